@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowjira "sigs.k8s.io/prow/pkg/jira"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/updateblockers"
+)
+
+const (
+	jqlAnnounced = "{{frag:ocpbugs}} AND labels in (UpdateRecommendationsBlocked)"
+
+	findingNoEdge          = "announced-without-edge"
+	findingResolvedBugs    = "edge-for-resolved-bugs"
+	findingClearedBugs     = "edge-for-cleared-bugs"
+	findingOpenISR         = "open-isr-for-declared-risk"
+	findingInvalidEdgeURL  = "edge-url-not-jira-card"
+	findingMissingEdgeCard = "edge-card-not-found"
+)
+
+type options struct {
+	graphRepositoryPath string
+
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository")
+
+	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.graphRepositoryPath == "" {
+		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
+	}
+
+	return o.jira.Validate()
+}
+
+// finding is a single inconsistency between the Jira labels and the graph data
+type finding struct {
+	kind    string
+	subject string
+	detail  string
+}
+
+// declaredRisks returns the names of the risks declared in the graph repository, keyed by the URL
+// their blocked edges reference
+func declaredRisks(index *graph.Index) map[string]sets.Set[string] {
+	byURL := map[string]sets.Set[string]{}
+	_ = index.Walk(graph.Filter{}, func(_ string, edge graph.ConditionallyBlockedEdge) error {
+		if _, ok := byURL[edge.URL]; !ok {
+			byURL[edge.URL] = sets.New[string]()
+		}
+		byURL[edge.URL].Insert(edge.Name)
+		return nil
+	})
+	return byURL
+}
+
+// auditAnnounced reports bugs announced as known issues whose impact statement request cards are not
+// referenced by any blocked edge
+func auditAnnounced(bugs []jira.Issue, declared map[string]sets.Set[string], bugProjects []string) []finding {
+	var findings []finding
+	for _, bug := range bugs {
+		var isrs []string
+		for _, link := range bug.Fields.IssueLinks {
+			for _, linked := range []*jira.Issue{link.OutwardIssue, link.InwardIssue} {
+				if blockerflow.IsImpactStatementRequestCandidate(linked, bugProjects) {
+					isrs = append(isrs, linked.Key)
+				}
+			}
+		}
+		var found bool
+		for _, isr := range isrs {
+			if _, ok := declared[graph.JiraBrowsePrefix+isr]; ok {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		detail := "no linked impact statement request card"
+		if len(isrs) > 0 {
+			detail = fmt.Sprintf("no blocked edge references %s", strings.Join(isrs, ","))
+		}
+		findings = append(findings, finding{kind: findingNoEdge, subject: bug.Key, detail: detail})
+	}
+	return findings
+}
+
+// auditRisk reports problems of the impact statement card referenced by the blocked edges of a risk
+// and of the bugs linked to it
+func auditRisk(client jirautil.Client, bugProjects flagutil.BugProjectsOptions, url string, risks []string) ([]finding, error) {
+	subject := strings.Join(risks, ",")
+	if !strings.HasPrefix(url, graph.JiraBrowsePrefix) {
+		return []finding{{kind: findingInvalidEdgeURL, subject: subject, detail: url}}, nil
+	}
+	key := strings.TrimPrefix(url, graph.JiraBrowsePrefix)
+
+	logrus.Infof("%s: Obtaining impact statement card %s", subject, key)
+	card, err := client.GetIssue(key)
+	if err != nil {
+		if prowjira.IsNotFound(err) {
+			return []finding{{kind: findingMissingEdgeCard, subject: subject, detail: fmt.Sprintf("%s: %v", url, err)}}, nil
+		}
+		return nil, err
+	}
+
+	var findings []finding
+	if status := card.Fields.Status; status != nil && status.StatusCategory.Key != jira.StatusCategoryComplete {
+		findings = append(findings, finding{kind: findingOpenISR, subject: subject, detail: fmt.Sprintf("%s is %s", key, status.Name)})
+	}
+
+	var bugs, resolved, announced []string
+	for _, link := range card.Fields.IssueLinks {
+		for _, linked := range []*jira.Issue{link.OutwardIssue, link.InwardIssue} {
+			if linked == nil || !bugProjects.IsBug(linked.Key) {
+				continue
+			}
+			bug, err := client.GetIssue(linked.Key)
+			if err != nil {
+				return nil, err
+			}
+			bugs = append(bugs, bug.Key)
+			if status := bug.Fields.Status; status != nil && (strings.EqualFold(status.Name, "VERIFIED") || strings.EqualFold(status.Name, "Closed")) {
+				resolved = append(resolved, bug.Key)
+			}
+			if sets.New(bug.Fields.Labels...).Has(updateblockers.LabelKnownIssueAnnounced) {
+				announced = append(announced, bug.Key)
+			}
+		}
+	}
+	if len(bugs) > 0 && len(resolved) == len(bugs) {
+		findings = append(findings, finding{kind: findingResolvedBugs, subject: subject, detail: fmt.Sprintf("all bugs linked to %s are VERIFIED or Closed (%s)", key, strings.Join(bugs, ","))})
+	}
+	if len(bugs) > 0 && len(announced) == 0 {
+		findings = append(findings, finding{kind: findingClearedBugs, subject: subject, detail: fmt.Sprintf("no bug linked to %s is labeled %s (%s)", key, updateblockers.LabelKnownIssueAnnounced, strings.Join(bugs, ","))})
+	}
+	return findings, nil
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota audit
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	index, err := graph.LoadIndex(o.graphRepositoryPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read graph repository")
+	}
+	declared := declaredRisks(index)
+
+	jiraClient, err := o.jira.Client()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create Jira client")
+	}
+
+	fragments, err := config.LoadJQLFragments()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load JQL fragments")
+	}
+	query, err := fragments.Expand(jqlAnnounced)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot expand JQL fragments")
+	}
+
+	logrus.Infof("Obtaining issues matching '%s'", query)
+	announced, err := jirautil.SearchAll(ctx, jiraClient, query)
+	if err != nil {
+		exitcode.JiraFatal(err, "Failed to query JIRA")
+	}
+
+	findings := auditAnnounced(announced, declared, o.bugProjects.Projects())
+
+	urls := sets.List(sets.KeySet(declared))
+	for _, url := range urls {
+		if err := ctx.Err(); err != nil {
+			exitcode.Fatal(exitcode.Interrupted, err, "cannot audit impact statement cards")
+		}
+		riskFindings, err := auditRisk(jiraClient, o.bugProjects, url, sets.List(declared[url]))
+		if err != nil {
+			exitcode.JiraFatal(err, "cannot get issue")
+		}
+		findings = append(findings, riskFindings...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].kind < findings[j].kind })
+	logrus.Infof("Audited %d announced bugs and %d impact statement cards, found %d inconsistencies", len(announced), len(urls), len(findings))
+	if len(findings) == 0 {
+		return
+	}
+
+	tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	_, _ = tabw.Write([]byte("FINDING\tSUBJECT\tDETAIL\n"))
+	for _, f := range findings {
+		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\n", f.kind, f.subject, f.detail)))
+	}
+	_ = tabw.Flush()
+	os.Exit(exitcode.ValidationFailed)
+}
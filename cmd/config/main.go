@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+	prowjira "sigs.k8s.io/prow/pkg/jira"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/jirautil"
+)
+
+const (
+	actionList  = "list"
+	actionGet   = "get"
+	actionSet   = "set"
+	actionUnset = "unset"
+	actionLogin = "login"
+)
+
+type options struct {
+	action string
+	args   []string
+
+	command      string
+	jiraEndpoint string
+	tokenFile    string
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s [flags] list | get FLAG | set FLAG VALUE... | unset FLAG | login\n\n", fs.Name())
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&o.command, "command", "", "Manage the flag defaults of this command (e.g. monitor-jira-dashboard) instead of the defaults for all commands")
+	fs.StringVar(&o.jiraEndpoint, "jira-endpoint", flagutil.DefaultJiraEndpoint, fmt.Sprintf("The Jira endpoint to verify the token against with %s", actionLogin))
+	fs.StringVar(&o.tokenFile, "jira-bearer-token-file", flagutil.DefaultTokenPath(), fmt.Sprintf("The file to write the Jira token into with %s", actionLogin))
+
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.FollowConfigDir(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply --config-dir")
+	}
+	if o.tokenFile == "" {
+		// without a user config directory, there is no default until --config-dir sets one
+		o.tokenFile = flagutil.DefaultTokenPath()
+	}
+
+	if fs.NArg() > 0 {
+		o.action = fs.Arg(0)
+		o.args = fs.Args()[1:]
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	var expected string
+	switch o.action {
+	case actionList, actionLogin:
+		if len(o.args) != 0 {
+			expected = "no arguments"
+		}
+	case actionGet, actionUnset:
+		if len(o.args) != 1 {
+			expected = "a single flag name"
+		}
+	case actionSet:
+		if len(o.args) < 2 {
+			expected = "a flag name and at least one value"
+		}
+	default:
+		return fmt.Errorf("an action must be one of %s, %s, %s, %s, %s", actionList, actionGet, actionSet, actionUnset, actionLogin)
+	}
+	if expected != "" {
+		return fmt.Errorf("%s expects %s", o.action, expected)
+	}
+
+	if o.action != actionLogin {
+		return nil
+	}
+	if o.jiraEndpoint == "" {
+		return fmt.Errorf("--jira-endpoint must be specified and nonempty")
+	}
+	if o.tokenFile == "" {
+		return fmt.Errorf("--jira-bearer-token-file must be specified and nonempty")
+	}
+	return nil
+}
+
+// flagName accepts flag names with or without the leading dashes
+func flagName(name string) string {
+	return strings.TrimLeft(name, "-")
+}
+
+// readToken prompts for the token without echoing it when stdin is a terminal, or reads the first
+// line of stdin otherwise
+func readToken() (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		_, _ = fmt.Fprint(os.Stderr, "Jira personal access token: ")
+		raw, err := term.ReadPassword(int(os.Stdin.Fd()))
+		_, _ = fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(raw)), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if line = strings.TrimSpace(line); line != "" {
+		return line, nil
+	}
+	return "", err
+}
+
+func login(o options) error {
+	token, err := readToken()
+	if err != nil {
+		return fmt.Errorf("cannot read the token: %w", err)
+	}
+	if token == "" {
+		return fmt.Errorf("the token must not be empty")
+	}
+
+	client, err := prowjira.NewClient(o.jiraEndpoint, prowjira.WithBearerAuth(func() string { return token }))
+	if err != nil {
+		return fmt.Errorf("cannot create Jira client: %w", err)
+	}
+	logrus.Infof("Verifying the token against %s", o.jiraEndpoint)
+	self, err := jirautil.Extend(client).GetSelf()
+	if err != nil {
+		return fmt.Errorf("cannot verify the token against %s: %w", o.jiraEndpoint, err)
+	}
+	logrus.Infof("Authenticated as %s (%s)", self.DisplayName, self.Name)
+
+	if err := os.MkdirAll(filepath.Dir(o.tokenFile), 0700); err != nil {
+		return fmt.Errorf("cannot create the token directory: %w", err)
+	}
+	if err := os.WriteFile(o.tokenFile, []byte(token), 0600); err != nil {
+		return fmt.Errorf("cannot write the token: %w", err)
+	}
+	// WriteFile does not change the permissions of an existing file
+	if err := os.Chmod(o.tokenFile, 0600); err != nil {
+		return fmt.Errorf("cannot restrict the token file permissions: %w", err)
+	}
+	logrus.Infof("Token written to %s", o.tokenFile)
+	return nil
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota config
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+
+	if o.action == actionLogin {
+		if err := login(o); err != nil {
+			exitcode.JiraFatal(err, "cannot log in")
+		}
+		return
+	}
+
+	defaults, err := config.LoadFlagDefaults()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load flag defaults")
+	}
+
+	switch o.action {
+	case actionList:
+		tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		_, _ = tabw.Write([]byte("COMMAND\tFLAG\tVALUE\n"))
+		for _, d := range defaults.List() {
+			command := d.Command
+			if command == "" {
+				command = "(all)"
+			}
+			_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\n", command, d.Name, strings.Join(d.Values, ", "))))
+		}
+		_ = tabw.Flush()
+	case actionGet:
+		values, ok := defaults.Get(o.command, flagName(o.args[0]))
+		if !ok {
+			exitcode.Fatal(exitcode.Error, fmt.Errorf("no default for --%s", flagName(o.args[0])), "cannot get flag default")
+		}
+		for _, value := range values {
+			fmt.Println(value)
+		}
+	case actionSet, actionUnset:
+		if o.action == actionSet {
+			defaults.Set(o.command, flagName(o.args[0]), o.args[1:])
+		} else {
+			defaults.Unset(o.command, flagName(o.args[0]))
+		}
+		if err := defaults.Save(); err != nil {
+			exitcode.Fatal(exitcode.Config, err, "cannot save flag defaults")
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/petr-muller/ota/internal/cincinnati"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/github"
+	"github.com/petr-muller/ota/internal/gitutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/promql"
+	"github.com/petr-muller/ota/internal/updateblockers"
+)
+
+type options struct {
+	graphRepositoryPath string
+	risk                string
+
+	osusURL       string
+	channelPrefix string
+	arch          string
+
+	docsProject string
+
+	github      flagutil.GitHubOptions
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository")
+	fs.StringVar(&o.risk, "risk", "", "The identifier of the risk to check")
+	fs.StringVar(&o.osusURL, "osus-url", cincinnati.DefaultURL, "The OSUS graph endpoint used to check whether the risk is served")
+	fs.StringVar(&o.channelPrefix, "channel-prefix", "candidate", "The channel group (candidate, fast, stable...) used to check whether the risk is served")
+	fs.StringVar(&o.arch, "arch", "amd64", "The architecture used to check whether the risk is served")
+	fs.StringVar(&o.docsProject, "docs-project", "OSDOCS", "The Jira project of the known issue docs snippet cards, linked from the impact statement card or the bugs")
+
+	o.github.AddFlags(fs)
+	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.graphRepositoryPath == "" {
+		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
+	}
+
+	if o.risk == "" {
+		return fmt.Errorf("--risk must be specified and nonempty")
+	}
+
+	if o.docsProject == "" {
+		return fmt.Errorf("--docs-project must be specified and nonempty")
+	}
+
+	return o.jira.Validate()
+}
+
+type result string
+
+const (
+	pass result = "PASS"
+	fail result = "FAIL"
+	skip result = "SKIP"
+)
+
+type checkItem struct {
+	name   string
+	result result
+	detail string
+}
+
+type checklist []checkItem
+
+func (c *checklist) add(name string, passed bool, detail string) {
+	r := fail
+	if passed {
+		r = pass
+	}
+	*c = append(*c, checkItem{name: name, result: r, detail: detail})
+}
+
+func (c *checklist) skip(name string, detail string) {
+	*c = append(*c, checkItem{name: name, result: skip, detail: detail})
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph checklist
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	var items checklist
+
+	paths, err := filepath.Glob(graph.EdgePath(o.graphRepositoryPath, "*", o.risk))
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot list blocked edges")
+	}
+	items.add("Blocked edge files present", len(paths) > 0, fmt.Sprintf("%d files", len(paths)))
+
+	var edges []graph.ConditionallyBlockedEdge
+	var problems []string
+	minors := sets.New[string]()
+	for _, path := range paths {
+		edge, err := graph.LoadEdge(path)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		// The same problems graph-lint fails on without --strict
+		for _, diagnostic := range promql.CheckRules(edge.MatchingRules) {
+			problems = append(problems, fmt.Sprintf("%s: %s", filepath.Base(path), diagnostic))
+		}
+		for _, problem := range graph.MessageErrors(graph.CheckMessage(edge.Message)) {
+			problems = append(problems, fmt.Sprintf("%s: %s", filepath.Base(path), problem))
+		}
+		if problem := graph.CheckFileName(path, edge); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", filepath.Base(path), problem))
+		}
+		if to, err := version.ParseGeneric(edge.To); err == nil {
+			minors.Insert(fmt.Sprintf("%d.%d", to.Major(), to.Minor()))
+		} else {
+			logrus.WithError(err).Warnf("%s: Not checking OSUS for unparseable 'to' version %q", filepath.Base(path), edge.To)
+		}
+		edges = append(edges, edge)
+	}
+	items.add("Blocked edge files are lint-clean", len(paths) > 0 && len(problems) == 0, strings.Join(problems, "; "))
+
+	if len(paths) > 0 {
+		args := append([]string{"-C", o.graphRepositoryPath, "status", "--porcelain", "--"}, paths...)
+		out, err := exec.Command("git", args...).Output()
+		switch {
+		case err != nil:
+			items.skip("Blocked edge files committed", fmt.Sprintf("cannot run git: %v", err))
+		default:
+			items.add("Blocked edge files committed", len(out) == 0, strings.TrimSpace(string(out)))
+		}
+
+		repository := gitutil.Repository{Path: o.graphRepositoryPath}
+		commit, err := repository.LastCommit(paths...)
+		switch {
+		case err != nil:
+			items.skip("PR merged", fmt.Sprintf("cannot run git: %v", err))
+		case commit == "":
+			items.add("PR merged", false, "blocked edge files were never committed")
+		default:
+			client, err := o.github.Client()
+			if err != nil {
+				exitcode.Fatal(exitcode.Config, err, "cannot create GitHub client")
+			}
+			pulls, err := client.CommitPullRequests(ctx, o.github.Repository(), commit)
+			if errors.Is(err, context.Canceled) {
+				exitcode.Fatal(exitcode.Interrupted, err, "cannot query GitHub")
+			}
+			if err != nil {
+				items.skip("PR merged", err.Error())
+				break
+			}
+			merged, detail := pullRequestMerged(pulls, commit)
+			items.add("PR merged", merged, detail)
+		}
+	}
+
+	osus := cincinnati.NewClient(o.osusURL, o.arch)
+	for _, minor := range sets.List(minors) {
+		channel := fmt.Sprintf("%s-%s", o.channelPrefix, minor)
+		served, err := osus.Graph(ctx, channel)
+		if errors.Is(err, context.Canceled) {
+			exitcode.Fatal(exitcode.Interrupted, err, "cannot query OSUS")
+		}
+		if err != nil {
+			items.skip(fmt.Sprintf("OSUS serving in %s", channel), err.Error())
+			continue
+		}
+		items.add(fmt.Sprintf("OSUS serving in %s", channel), served.RiskNames().Has(o.risk), "")
+	}
+
+	if len(edges) > 0 && strings.HasPrefix(edges[0].URL, graph.JiraBrowsePrefix) {
+		jiraClient, err := o.jira.Client()
+		if err != nil {
+			logrus.WithError(err).Fatal("cannot create Jira client")
+		}
+
+		impactStatementCard := strings.TrimPrefix(edges[0].URL, graph.JiraBrowsePrefix)
+		isr, err := jiraClient.GetIssue(impactStatementCard)
+		if err != nil {
+			logrus.WithError(err).Fatalf("cannot get issue %s", impactStatementCard)
+		}
+		if isr.Fields == nil || isr.Fields.Status == nil {
+			items.skip("Impact statement card closed", fmt.Sprintf("%s has no status", isr.Key))
+		} else {
+			items.add("Impact statement card closed", strings.EqualFold(isr.Fields.Status.Name, "Closed"), fmt.Sprintf("%s is %s", isr.Key, isr.Fields.Status.Name))
+		}
+
+		keys := linkedKeys(isr, o.bugProjects.IsBug)
+		bugs, err := jirautil.DefaultFetcher.GetIssues(ctx, jiraClient, keys)
+		if err != nil {
+			exitcode.JiraFatal(err, "cannot get bugs linked to the impact statement card")
+		}
+		if len(bugs) == 0 {
+			items.add("Bug linked to impact statement card", false, isr.Key)
+		}
+
+		isDocs := func(key string) bool { return jirautil.InProjects(key, []string{o.docsProject}) }
+		docs := sets.New[string](linkedKeys(isr, isDocs)...)
+		for _, bug := range bugs {
+			docs.Insert(linkedKeys(bug, isDocs)...)
+		}
+		if docs.Len() > 0 {
+			items.add("Docs snippet filed", true, strings.Join(sets.List(docs), ","))
+		} else {
+			items.add("Docs snippet filed", false, fmt.Sprintf("no %s card linked to %s or its bugs", o.docsProject, isr.Key))
+		}
+
+		for _, bug := range bugs {
+			labels := sets.New[string](bug.Fields.Labels...)
+			items.add(fmt.Sprintf("%s labeled %s", bug.Key, updateblockers.LabelKnownIssueAnnounced), labels.Has(updateblockers.LabelKnownIssueAnnounced), "")
+
+			commented := false
+			if bug.Fields.Comments != nil {
+				for _, comment := range bug.Fields.Comments.Comments {
+					if strings.Contains(comment.Body, o.risk) {
+						commented = true
+						break
+					}
+				}
+			}
+			items.add(fmt.Sprintf("%s has a comment about the risk", bug.Key), commented, "")
+		}
+	} else {
+		items.skip("Docs snippet filed", "no impact statement card to find the linked docs snippet card from")
+	}
+
+	tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	_, _ = tabw.Write([]byte("RESULT\tITEM\tDETAIL\n"))
+	for _, item := range items {
+		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\n", item.result, item.name, item.detail)))
+	}
+	_ = tabw.Flush()
+
+	for _, item := range items {
+		if item.result == fail {
+			os.Exit(exitcode.ValidationFailed)
+		}
+	}
+}
+
+// linkedKeys returns the keys of the cards linked to the card that match the filter
+func linkedKeys(card *jira.Issue, filter func(key string) bool) []string {
+	if card.Fields == nil {
+		return nil
+	}
+	var keys []string
+	for _, link := range card.Fields.IssueLinks {
+		for _, linked := range []*jira.Issue{link.OutwardIssue, link.InwardIssue} {
+			if linked != nil && filter(linked.Key) {
+				keys = append(keys, linked.Key)
+			}
+		}
+	}
+	return keys
+}
+
+// pullRequestMerged returns whether any of the pull requests containing the commit was merged, and
+// the URLs of the merged pull requests (or of all of them when none was merged)
+func pullRequestMerged(pulls []github.PullRequestState, commit string) (bool, string) {
+	if len(pulls) == 0 {
+		return false, fmt.Sprintf("no pull request contains commit %s", commit)
+	}
+	var merged, other []string
+	for _, pull := range pulls {
+		if pull.Merged() {
+			merged = append(merged, pull.HTMLURL)
+		} else {
+			other = append(other, fmt.Sprintf("%s (%s)", pull.HTMLURL, pull.State))
+		}
+	}
+	if len(merged) > 0 {
+		return true, strings.Join(merged, ", ")
+	}
+	return false, strings.Join(other, ", ")
+}
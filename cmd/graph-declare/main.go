@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jiratui"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/promql"
+	"github.com/petr-muller/ota/internal/updateblockers"
+)
+
+type options struct {
+	graphRepositoryPath string
+
+	risk    string
+	from    string
+	to      string
+	url     string
+	message string
+	promql  prowflagutil.Strings
+
+	skipJira bool
+
+	jira        flagutil.JiraOptions
+	git         flagutil.GraphGitOptions
+	pullRequest flagutil.PullRequestOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	o := options{promql: prowflagutil.NewStrings()}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository")
+	fs.StringVar(&o.risk, "risk", "", "The identifier (name) of the new risk")
+	fs.StringVar(&o.from, "from", "", "Regular expression matching the versions from which the update is exposed to the risk")
+	fs.StringVar(&o.to, "to", "", "The version to which the update is exposed to the risk")
+	fs.StringVar(&o.url, "url", "", "The URL of the Jira card describing the risk (usually the impact statement request)")
+	fs.StringVar(&o.message, "message", "", "The message describing the risk to cluster administrators; edited in $EDITOR when omitted in a terminal")
+	fs.Var(&o.promql, "promql", "PromQL expression matching the exposed clusters (can be passed multiple times)")
+	fs.BoolVar(&o.skipJira, "skip-jira", false, "Skip checking the referenced Jira card")
+
+	o.jira.AddFlags(fs)
+	o.git.AddFlags(fs)
+	o.pullRequest.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+var preReleaseRegexp = regexp.MustCompile(`^-[0-9A-Za-z.-]+$`)
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.graphRepositoryPath == "" {
+		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
+	}
+
+	if !graph.ValidRiskName(o.risk) {
+		return fmt.Errorf("--risk must be a CamelCase identifier")
+	}
+
+	if o.from == "" {
+		return fmt.Errorf("--from must be specified and nonempty")
+	}
+	if _, err := regexp.Compile(o.from); err != nil {
+		return fmt.Errorf("--from must be a valid regular expression: %w", err)
+	}
+
+	if o.to == "" {
+		return fmt.Errorf("--to must be specified and nonempty")
+	}
+	// ParseGeneric ignores anything after the version components, so the rest must be a pre-release
+	// suffix like in 4.16.0-rc.1
+	to, err := version.ParseGeneric(o.to)
+	if err != nil {
+		return fmt.Errorf("--to must be a release version: %w", err)
+	}
+	if rest, ok := strings.CutPrefix(o.to, to.String()); !ok || len(to.Components()) != 3 || (rest != "" && !preReleaseRegexp.MatchString(rest)) {
+		return fmt.Errorf("--to must be a release version like 4.16.1 or 4.16.0-rc.1, got %q", o.to)
+	}
+
+	if !strings.HasPrefix(o.url, graph.JiraBrowsePrefix) {
+		return fmt.Errorf("--url must be a Jira card URL starting with %s", graph.JiraBrowsePrefix)
+	}
+
+	if o.message == "" && !jiratui.Interactive() {
+		return fmt.Errorf("--message must be specified and nonempty when not running in a terminal")
+	}
+	if o.message != "" {
+		if err := (graph.EditableRisk{Name: o.risk, Message: o.message}).Validate(); err != nil {
+			return fmt.Errorf("--message is invalid: %w", err)
+		}
+	}
+
+	if len(o.promql.Strings()) == 0 {
+		return fmt.Errorf("--promql must be specified at least once")
+	}
+	for i, expr := range o.promql.Strings() {
+		if diagnostics := promql.Check(expr); len(diagnostics) > 0 {
+			return fmt.Errorf("--promql #%d is invalid: %s", i+1, diagnostics[0])
+		}
+	}
+
+	if err := o.pullRequest.Validate(); err != nil {
+		return err
+	}
+
+	if o.skipJira {
+		if o.pullRequest.CommentOnCard() {
+			return fmt.Errorf("--pr-comment cannot be combined with --skip-jira")
+		}
+		return nil
+	}
+
+	return o.jira.Validate()
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph declare
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	if o.message == "" {
+		var err error
+		header := []string{fmt.Sprintf("Declaring the risk for updates from %s to %s", o.from, o.to), "Impact statement: " + o.url}
+		if o.risk, o.message, err = graph.EditNameAndMessage(o.risk, "", header...); err != nil {
+			logrus.WithError(err).Fatal("cannot edit risk name and message")
+		}
+	}
+
+	destinationPath := graph.EdgePath(o.graphRepositoryPath, o.to, o.risk)
+	if _, err := os.Stat(destinationPath); err == nil {
+		logrus.Fatalf("Blocked edge %s already exists, use graph-extend-or-fix or graph-spread-edge-changes to modify it", destinationPath)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		logrus.WithError(err).Fatal("cannot check blocked edge file")
+	}
+
+	var jiraClient jirautil.Client
+	var card *jira.Issue
+	if !o.skipJira {
+		var err error
+		if jiraClient, err = o.jira.Client(); err != nil {
+			logrus.WithError(err).Fatal("cannot create Jira client")
+		}
+
+		cardKey := strings.TrimPrefix(o.url, graph.JiraBrowsePrefix)
+		logrus.Infof("Obtaining the referenced card %s", cardKey)
+		if card, err = jiraClient.GetIssue(cardKey); err != nil {
+			exitcode.JiraFatal(err, "cannot get issue")
+		}
+		if !sets.New[string](card.Fields.Labels...).Has(updateblockers.LabelBlocker) {
+			logrus.Fatalf("%s does not have the %s label", card.Key, updateblockers.LabelBlocker)
+		}
+	}
+
+	branch, err := o.git.Prepare(o.graphRepositoryPath, o.risk, []string{destinationPath})
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot write into the graph repository")
+	}
+
+	edge := graph.ConditionallyBlockedEdge{
+		To:      o.to,
+		From:    o.from,
+		URL:     o.url,
+		Name:    o.risk,
+		Message: o.message,
+	}
+	for _, expr := range o.promql.Strings() {
+		edge.MatchingRules = append(edge.MatchingRules, graph.PromQLRule{Type: "PromQL", PromQL: graph.PromQLQuery{Query: expr}})
+	}
+
+	logrus.Infof("Declaring risk %s in %s", o.risk, destinationPath)
+	if err := graph.SaveEdge(destinationPath, edge); err != nil {
+		logrus.WithError(err).Fatal("cannot write blocked edge")
+	}
+	o.git.ShowDiff(o.graphRepositoryPath, []string{destinationPath})
+
+	if !o.pullRequest.Enabled() {
+		return
+	}
+	if branch == "" {
+		branch = fmt.Sprintf("declare-%s-%s", o.risk, o.to)
+	}
+	title := fmt.Sprintf("%s: Declare %s", graph.EdgeFileName(o.to, o.risk), o.risk)
+	body := fmt.Sprintf("Declare the %s risk for updates to %s.\n\nImpact statement: %s", o.risk, o.to, o.url)
+	url, err := o.pullRequest.Open(ctx, o.graphRepositoryPath, branch, []string{destinationPath}, title, body)
+	if err != nil {
+		exitcode.Fatal(exitcode.Error, err, "cannot open pull request")
+	}
+	fmt.Println(url)
+
+	if o.pullRequest.CommentOnCard() {
+		flow, err := blockerflow.NewFlow(jiraClient)
+		if err != nil {
+			exitcode.Fatal(exitcode.Config, err, "cannot initialize blocker workflow")
+		}
+		flow.Visibility = o.jira.CommentVisibility(flow.Visibility)
+		if err := flow.AnnouncePullRequest(card, url); err != nil {
+			logrus.WithError(err).Fatal("cannot comment on the impact statement card")
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/cincinnati"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+
+	// osusPrefix marks a source that is the live graph served by OSUS in a channel, like osus:candidate-4.16
+	osusPrefix = "osus:"
+)
+
+type options struct {
+	graphRepositoryPath string
+	osusURL             string
+	arch                string
+	output              string
+
+	old string
+	new string
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s [options] OLD NEW\n\n", os.Args[0])
+		_, _ = fmt.Fprintf(fs.Output(), "OLD and NEW are each a graph repository checkout (directory), a git ref in --graph-repository-path,\n")
+		_, _ = fmt.Fprintf(fs.Output(), "a saved OSUS graph (JSON file) or %sCHANNEL for the graph currently served by OSUS.\n\n", osusPrefix)
+		fs.PrintDefaults()
+	}
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository where git refs are resolved")
+	fs.StringVar(&o.osusURL, "osus-url", cincinnati.DefaultURL, "The OSUS graph endpoint for osus:CHANNEL sources")
+	fs.StringVar(&o.arch, "osus-arch", "amd64", "The architecture of the graph served by OSUS for osus:CHANNEL sources")
+	fs.StringVar(&o.output, "output", outputText, fmt.Sprintf("Output format: %s or %s", outputText, outputJSON))
+
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	if fs.NArg() == 2 {
+		o.old, o.new = fs.Arg(0), fs.Arg(1)
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.old == "" || o.new == "" {
+		return fmt.Errorf("exactly two nonempty arguments (OLD and NEW) must be passed")
+	}
+
+	if o.output != outputText && o.output != outputJSON {
+		return fmt.Errorf("--output must be one of %s, %s", outputText, outputJSON)
+	}
+
+	return nil
+}
+
+// edges reads the blocked edges of the source, which is either the live OSUS graph in a channel, a
+// saved OSUS graph, a graph repository checkout or a git ref in the graph repository
+func (o *options) edges(ctx context.Context, source string) ([]graph.ConditionallyBlockedEdge, error) {
+	if channel, ok := strings.CutPrefix(source, osusPrefix); ok {
+		return cincinnati.NewClient(o.osusURL, o.arch).BlockedEdges(ctx, []string{channel}, func(cincinnati.Risk) bool { return true })
+	}
+
+	if info, err := os.Stat(source); err == nil {
+		if !info.IsDir() {
+			g, err := cincinnati.LoadGraph(source)
+			if err != nil {
+				return nil, err
+			}
+			return g.BlockedEdges(func(cincinnati.Risk) bool { return true }), nil
+		}
+		index, err := graph.LoadIndex(source)
+		if err != nil {
+			return nil, err
+		}
+		var edges []graph.ConditionallyBlockedEdge
+		_ = index.Walk(graph.Filter{}, func(_ string, edge graph.ConditionallyBlockedEdge) error {
+			edges = append(edges, edge)
+			return nil
+		})
+		return edges, nil
+	}
+
+	if o.graphRepositoryPath == "" {
+		return nil, fmt.Errorf("%s is not a file or a directory, --graph-repository-path must be specified to resolve it as a git ref", source)
+	}
+	return graph.EdgesAt(o.graphRepositoryPath, source)
+}
+
+// printChanges prints the changes in a form similar to a unified diff
+func printChanges(out io.Writer, changes []graph.RiskChange) {
+	markers := map[string]string{graph.RiskAdded: "+", graph.RiskRemoved: "-", graph.RiskEdited: "~"}
+	for _, change := range changes {
+		_, _ = fmt.Fprintf(out, "%s %s (%s)\n", markers[change.Change], change.Name, change.Change)
+		if len(change.Added) > 0 {
+			_, _ = fmt.Fprintf(out, "    + edges to %s\n", strings.Join(change.Added, ", "))
+		}
+		if len(change.Removed) > 0 {
+			_, _ = fmt.Fprintf(out, "    - edges to %s\n", strings.Join(change.Removed, ", "))
+		}
+		for _, edited := range change.Edited {
+			for _, field := range edited.Fields {
+				if !strings.Contains(field.Old+field.New, "\n") {
+					_, _ = fmt.Fprintf(out, "    ~ %s %s: %q -> %q\n", edited.To, field.Field, field.Old, field.New)
+					continue
+				}
+				_, _ = fmt.Fprintf(out, "    ~ %s %s:\n", edited.To, field.Field)
+				for _, line := range strings.Split(field.Old, "\n") {
+					_, _ = fmt.Fprintf(out, "        - %s\n", line)
+				}
+				for _, line := range strings.Split(field.New, "\n") {
+					_, _ = fmt.Fprintf(out, "        + %s\n", line)
+				}
+			}
+		}
+	}
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph diff
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	old, err := o.edges(ctx, o.old)
+	if err != nil {
+		logrus.WithError(err).Fatalf("cannot read blocked edges of %s", o.old)
+	}
+	new, err := o.edges(ctx, o.new)
+	if err != nil {
+		logrus.WithError(err).Fatalf("cannot read blocked edges of %s", o.new)
+	}
+	logrus.Debugf("Comparing %d blocked edges of %s with %d blocked edges of %s", len(old), o.old, len(new), o.new)
+
+	changes := graph.Diff(old, new)
+	if o.output == outputJSON {
+		if changes == nil {
+			changes = []graph.RiskChange{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(changes); err != nil {
+			logrus.WithError(err).Fatal("cannot encode changes")
+		}
+		return
+	}
+	if len(changes) == 0 {
+		logrus.Infof("No risks differ between %s and %s", o.old, o.new)
+		return
+	}
+	printChanges(os.Stdout, changes)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/jiratui"
+	"github.com/petr-muller/ota/internal/promql"
+)
+
+const (
+	fieldURL = iota
+	fieldMessage
+	fieldRules
+	fieldCount
+)
+
+// form edits the URL, message and matchingRules of a blocked edge. The risk name is shown but cannot
+// be edited because the blocked edge files are named after it.
+type form struct {
+	edge graph.ConditionallyBlockedEdge
+	path string
+
+	url     textinput.Model
+	message textarea.Model
+	rules   textarea.Model
+	// focused is the field that receives keyboard input
+	focused int
+
+	// problems are shown when the edited edge cannot be saved
+	problems []string
+
+	saved     bool
+	cancelled bool
+}
+
+func marshalRules(rules []graph.PromQLRule) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(rules); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func newForm(path string, edge graph.ConditionallyBlockedEdge) (form, error) {
+	rules, err := marshalRules(edge.MatchingRules)
+	if err != nil {
+		return form{}, fmt.Errorf("cannot marshal matchingRules: %w", err)
+	}
+
+	f := form{edge: edge, path: path, url: textinput.New(), message: textarea.New(), rules: textarea.New()}
+	f.url.SetValue(edge.URL)
+	f.url.Width = 100
+	f.message.SetValue(edge.Message)
+	f.message.SetWidth(100)
+	f.message.SetHeight(6)
+	f.message.CharLimit = 0
+	f.rules.SetValue(rules)
+	f.rules.SetWidth(100)
+	f.rules.SetHeight(10)
+	f.rules.CharLimit = 0
+	return f.focus(fieldURL), nil
+}
+
+func (f form) focus(field int) form {
+	f.focused = field
+	f.url.Blur()
+	f.message.Blur()
+	f.rules.Blur()
+	switch field {
+	case fieldURL:
+		f.url.Focus()
+	case fieldMessage:
+		f.message.Focus()
+	case fieldRules:
+		f.rules.Focus()
+	}
+	return f
+}
+
+// edited returns the blocked edge with the values from the form, or the problems that prevent saving it
+func (f form) edited() (graph.ConditionallyBlockedEdge, []string) {
+	edge := f.edge
+	edge.URL = strings.TrimSpace(f.url.Value())
+	edge.Message = f.message.Value()
+
+	var problems []string
+	if edge.URL == "" {
+		problems = append(problems, "url must not be empty")
+	}
+	if strings.TrimSpace(edge.Message) == "" {
+		problems = append(problems, "message must not be empty")
+	}
+
+	var rules []graph.PromQLRule
+	if err := yaml.Unmarshal([]byte(f.rules.Value()), &rules); err != nil {
+		return edge, append(problems, fmt.Sprintf("matchingRules: %v", err))
+	}
+	if len(rules) == 0 {
+		problems = append(problems, "matchingRules must not be empty")
+	}
+	for _, diagnostic := range promql.CheckRules(rules) {
+		problems = append(problems, diagnostic.String())
+	}
+	edge.MatchingRules = rules
+	return edge, problems
+}
+
+func (f form) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (f form) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+s":
+			if _, f.problems = f.edited(); len(f.problems) == 0 {
+				f.saved = true
+				return f, tea.Quit
+			}
+			return f, nil
+		case "esc", "ctrl+c":
+			f.cancelled = true
+			return f, tea.Quit
+		case "tab":
+			return f.focus((f.focused + 1) % fieldCount), nil
+		case "shift+tab":
+			return f.focus((f.focused + fieldCount - 1) % fieldCount), nil
+		}
+	}
+
+	var cmd tea.Cmd
+	switch f.focused {
+	case fieldURL:
+		f.url, cmd = f.url.Update(msg)
+	case fieldMessage:
+		f.message, cmd = f.message.Update(msg)
+	case fieldRules:
+		f.rules, cmd = f.rules.Update(msg)
+	}
+	return f, cmd
+}
+
+func (f form) View() string {
+	if f.saved || f.cancelled {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Editing %s\n\n", f.path)
+	fmt.Fprintf(&b, "Name: %s\n\n", f.edge.Name)
+	fmt.Fprintf(&b, "URL:\n%s\n\n", f.url.View())
+	fmt.Fprintf(&b, "Message:\n%s\n\n", f.message.View())
+	fmt.Fprintf(&b, "matchingRules:\n%s\n\n", f.rules.View())
+	for _, problem := range f.problems {
+		fmt.Fprintf(&b, "! %s\n", problem)
+	}
+	b.WriteString("(tab/shift+tab to switch fields, ctrl+s to save, esc to cancel)\n")
+	return b.String()
+}
+
+// edit shows the form for the blocked edge and returns the edited edge, or false when the user cancelled
+func edit(path string, edge graph.ConditionallyBlockedEdge) (graph.ConditionallyBlockedEdge, bool, error) {
+	f, err := newForm(path, edge)
+	if err != nil {
+		return edge, false, err
+	}
+	final, err := jiratui.Run(f)
+	if err != nil {
+		return edge, false, err
+	}
+	f = final.(form)
+	if !f.saved {
+		return edge, false, nil
+	}
+	edited, _ := f.edited()
+	return edited, true, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/jiratui"
+)
+
+type options struct {
+	graphRepositoryPath string
+
+	risk    string
+	version string
+	spread  bool
+
+	git flagutil.GraphGitOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository")
+
+	fs.StringVar(&o.risk, "risk", "", "The identifier of the risk to edit")
+	fs.StringVar(&o.version, "version", "", "The version whose blocked edge should be edited (default: the newest blocked version of the risk)")
+	fs.BoolVar(&o.spread, "spread", false, "Spread the changes to all blocked edges of the risk without asking")
+
+	o.git.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.graphRepositoryPath == "" {
+		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
+	}
+
+	if o.risk == "" {
+		return fmt.Errorf("--risk must be specified and nonempty")
+	}
+
+	if !jiratui.Interactive() {
+		return jiratui.ErrNotInteractive
+	}
+
+	return nil
+}
+
+// newest returns the path of the blocked edge with the highest 'to' version
+func newest(index *graph.Index, risk string) string {
+	var newestPath string
+	var newestVersion *version.Version
+	_ = index.Walk(graph.Filter{Risk: risk}, func(path string, edge graph.ConditionallyBlockedEdge) error {
+		to, err := version.ParseGeneric(edge.To)
+		if err != nil {
+			logrus.WithError(err).Warnf("Skipping file %s with unparseable 'to' version %q", path, edge.To)
+			return nil
+		}
+		if newestVersion == nil || newestVersion.LessThan(to) {
+			newestPath, newestVersion = path, to
+		}
+		return nil
+	})
+	return newestPath
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph edit
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+
+	index, err := graph.LoadIndex(o.graphRepositoryPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read graph repository")
+	}
+
+	paths := index.Paths(graph.Filter{Risk: o.risk})
+	if len(paths) == 0 {
+		exitcode.Fatal(exitcode.InvalidOptions, fmt.Errorf("no blocked edges for risk %s", o.risk), "invalid options")
+	}
+
+	sourcePath := newest(index, o.risk)
+	if o.version != "" {
+		sourcePath = graph.EdgePath(o.graphRepositoryPath, o.version, o.risk)
+	}
+	var others []string
+	for _, path := range paths {
+		if path != sourcePath {
+			others = append(others, path)
+		}
+	}
+	if len(others) == len(paths) {
+		exitcode.Fatal(exitcode.InvalidOptions, fmt.Errorf("risk %s does not block edges to %s", o.risk, o.version), "invalid options")
+	}
+
+	source, err := graph.LoadEdge(sourcePath)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read blocked edge")
+	}
+
+	edited, saved, err := edit(sourcePath, source)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot edit blocked edge")
+	}
+	if !saved {
+		logrus.Info("Cancelled, nothing was changed")
+		return
+	}
+	if reflect.DeepEqual(edited, source) {
+		logrus.Info("Blocked edge was not changed")
+		return
+	}
+	for _, problem := range graph.CheckMessage(edited.Message) {
+		logrus.Warn(problem.String())
+	}
+
+	spread := o.spread
+	if !spread && len(others) > 0 {
+		answer, err := jiratui.Ask(fmt.Sprintf("Spread the changes to the other %d blocked edges of %s? (y/n)", len(others), o.risk), "y")
+		if errors.Is(err, jiratui.ErrCancelled) {
+			logrus.Info("Cancelled, nothing was changed")
+			return
+		} else if err != nil {
+			logrus.WithError(err).Fatal("cannot ask whether to spread the changes")
+		}
+		spread = strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "y")
+	}
+	if !spread {
+		others = nil
+	}
+
+	written := append([]string{sourcePath}, others...)
+	if _, err := o.git.Prepare(o.graphRepositoryPath, o.risk, written); err != nil {
+		logrus.WithError(err).Fatal("cannot write into the graph repository")
+	}
+
+	logrus.Infof("Writing %s", sourcePath)
+	if err := graph.SaveEdge(sourcePath, edited); err != nil {
+		logrus.WithError(err).Fatal("cannot write blocked edge")
+	}
+	if len(others) > 0 {
+		logrus.Infof("Spreading the changes to %d blocked edges", len(others))
+		if err := index.Spread(edited, others); err != nil {
+			logrus.WithError(err).Fatal("cannot spread the changes")
+		}
+	}
+	o.git.ShowDiff(o.graphRepositoryPath, written)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/hooks"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/promql"
+)
+
+type options struct {
+	graphRepositoryPath string
+
+	risk       string
+	first      string
+	last       string
+	introduced string
+	from       string
+
+	git flagutil.GraphGitOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository")
+	fs.StringVar(&o.risk, "risk", "", "The identifier (name) of the declared risk whose blocked edges to generate or update")
+	fs.StringVar(&o.first, "first", "", "The oldest version of the range to block, like 4.16.0")
+	fs.StringVar(&o.last, "last", "", "The newest version of the range to block, like 4.16.20 (must be in the same minor as --first)")
+	fs.StringVar(&o.introduced, "introduced", "", "The version that introduced the risk, used to compute the from regular expression (default: --first)")
+	fs.StringVar(&o.from, "from", "", "Regular expression matching the versions from which the update is exposed to the risk (default: computed from --introduced)")
+
+	o.git.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+// parseRelease parses a z-stream release version like 4.16.1
+func parseRelease(flagName, value string) (*version.Version, error) {
+	v, err := version.ParseSemantic(value)
+	if err != nil {
+		return nil, fmt.Errorf("--%s must be a release version: %w", flagName, err)
+	}
+	if v.PreRelease() != "" || v.BuildMetadata() != "" {
+		return nil, fmt.Errorf("--%s must be a z-stream release version like 4.16.1, got %q", flagName, value)
+	}
+	return v, nil
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.graphRepositoryPath == "" {
+		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
+	}
+
+	if !graph.ValidRiskName(o.risk) {
+		return fmt.Errorf("--risk must be a CamelCase identifier")
+	}
+
+	first, err := parseRelease("first", o.first)
+	if err != nil {
+		return err
+	}
+	last, err := parseRelease("last", o.last)
+	if err != nil {
+		return err
+	}
+	if _, err := graph.ZStreams(first, last); err != nil {
+		return fmt.Errorf("--first and --last must be a range: %w", err)
+	}
+
+	if o.introduced == "" {
+		o.introduced = o.first
+	}
+	introduced, err := parseRelease("introduced", o.introduced)
+	if err != nil {
+		return err
+	}
+	if introduced.Major() != first.Major() || introduced.Minor() != first.Minor() || first.LessThan(introduced) {
+		return fmt.Errorf("--introduced must be in the minor of the range and not newer than --first")
+	}
+
+	if o.from != "" {
+		if _, err := regexp.Compile(o.from); err != nil {
+			return fmt.Errorf("--from must be a valid regular expression: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// template returns the newest blocked edge of the risk, whose url, message and matchingRules are copied
+// into the generated edges. Ranges reaching a version where the risk was declared fixed are refused.
+func template(index *graph.Index, risk string, last *version.Version) (graph.ConditionallyBlockedEdge, error) {
+	var newest graph.ConditionallyBlockedEdge
+	var newestVersion *version.Version
+	err := index.Walk(graph.Filter{Risk: risk}, func(path string, edge graph.ConditionallyBlockedEdge) error {
+		if edge.FixedIn != "" {
+			if fixedIn, err := version.ParseGeneric(edge.FixedIn); err == nil && fixedIn.Major() == last.Major() && fixedIn.Minor() == last.Minor() && !last.LessThan(fixedIn) {
+				return fmt.Errorf("%s declares %s fixed in %s, the range must end before it", path, risk, edge.FixedIn)
+			}
+		}
+		to, err := version.ParseGeneric(edge.To)
+		if err != nil {
+			logrus.WithError(err).Warnf("Skipping file %s with unparseable 'to' version %q", path, edge.To)
+			return nil
+		}
+		if newestVersion == nil || newestVersion.LessThan(to) {
+			newest, newestVersion = edge, to
+		}
+		return nil
+	})
+	if err != nil {
+		return graph.ConditionallyBlockedEdge{}, err
+	}
+	if newestVersion == nil {
+		return graph.ConditionallyBlockedEdge{}, fmt.Errorf("risk %s has no blocked edges, declare it with graph-declare first", risk)
+	}
+	return newest, nil
+}
+
+// released filters the versions to those in the candidate channel of their minor in the graph
+// repository, so that no edges are generated for versions that were never released. All versions are
+// kept when the channel cannot be read.
+func released(repositoryPath string, minor *version.Version, versions []string) []string {
+	channel := fmt.Sprintf("candidate-%d.%d", minor.Major(), minor.Minor())
+	inChannel, err := graph.ChannelVersions(repositoryPath, channel)
+	if err != nil {
+		logrus.WithError(err).Warnf("Cannot read the %s channel, generating blocked edges for all versions in the range", channel)
+		return versions
+	}
+	var kept []string
+	for _, v := range versions {
+		if !inChannel.Has(v) {
+			logrus.Infof("Skipping %s, it is not in the %s channel", v, channel)
+			continue
+		}
+		kept = append(kept, v)
+	}
+	return kept
+}
+
+// change is a blocked edge to write
+type change struct {
+	path    string
+	edge    graph.ConditionallyBlockedEdge
+	created bool
+}
+
+// plan returns the blocked edges of the versions that must be created or whose from differs
+func plan(o options, index *graph.Index, source graph.ConditionallyBlockedEdge, versions []string, from string) []change {
+	var changes []change
+	for _, v := range versions {
+		path := graph.EdgePath(o.graphRepositoryPath, v, o.risk)
+		var existing *graph.ConditionallyBlockedEdge
+		_ = index.Walk(graph.Filter{Risk: o.risk, Version: v}, func(_ string, edge graph.ConditionallyBlockedEdge) error {
+			existing = &edge
+			return nil
+		})
+		switch {
+		case existing == nil:
+			edge := source
+			edge.To, edge.From, edge.FixedIn = v, from, ""
+			changes = append(changes, change{path: path, edge: edge, created: true})
+		case existing.From != from:
+			edge := *existing
+			edge.From = from
+			changes = append(changes, change{path: path, edge: edge})
+		default:
+			logrus.Debugf("%s: up to date", path)
+		}
+	}
+	return changes
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph expand-range
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	first, last := version.MustParseSemantic(o.first), version.MustParseSemantic(o.last)
+	from := o.from
+	if from == "" {
+		from = graph.FromRegexp(version.MustParseSemantic(o.introduced))
+	}
+
+	index, err := graph.LoadIndex(o.graphRepositoryPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read graph repository")
+	}
+	source, err := template(index, o.risk, last)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot find the blocked edges of the risk")
+	}
+	if diagnostics := promql.CheckRules(source.MatchingRules); len(diagnostics) > 0 {
+		for _, diagnostic := range diagnostics {
+			logrus.Errorf("%s: %s", source.To, diagnostic)
+		}
+		logrus.Fatal("The newest blocked edge of the risk has invalid matchingRules, fix them first")
+	}
+
+	versions, _ := graph.ZStreams(first, last)
+	changes := plan(o, index, source, released(o.graphRepositoryPath, first, versions), from)
+	if len(changes) == 0 {
+		logrus.Infof("All blocked edges of %s from %s to %s are up to date", o.risk, o.first, o.last)
+		return
+	}
+
+	var written []string
+	for _, c := range changes {
+		written = append(written, c.path)
+	}
+	if _, err := o.git.Prepare(o.graphRepositoryPath, o.risk, written); err != nil {
+		logrus.WithError(err).Fatal("cannot write into the graph repository")
+	}
+
+	hookRunner, err := hooks.Load()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load hooks config")
+	}
+
+	var failed bool
+	for i, c := range changes {
+		if ctx.Err() != nil {
+			logrus.Warnf("Interrupted, not writing the remaining %d blocked edges", len(changes)-i)
+			failed = true
+			break
+		}
+		hookData := map[string]string{"risk": o.risk, "last": source.To, "new": c.edge.To, "path": c.path}
+		if c.created {
+			if err := hookRunner.Pre(hooks.EventRiskExtended, hookData); err != nil {
+				logrus.WithError(err).Errorf("%s: pre-action hook failed, skipping", c.path)
+				failed = true
+				continue
+			}
+		}
+		action := "updating the from regular expression in"
+		if c.created {
+			action = "creating"
+		}
+		logrus.Infof("%s: %s %s", o.risk, action, c.path)
+		if err := graph.SaveEdge(c.path, c.edge); err != nil {
+			logrus.WithError(err).Errorf("%s: cannot write blocked edge", c.path)
+			failed = true
+			continue
+		}
+		if c.created {
+			hookRunner.Post(hooks.EventRiskExtended, hookData)
+		}
+	}
+	o.git.ShowDiff(o.graphRepositoryPath, written)
+
+	if failed {
+		os.Exit(exitcode.PartialFailure)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/hooks"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jiratui"
+	"github.com/petr-muller/ota/internal/promql"
+	"github.com/petr-muller/ota/internal/riskinspect"
+)
+
+const (
+	actionExtend = "extend"
+	actionFix    = "fix"
+	actionSkip   = "skip"
+)
+
+type options struct {
+	graphRepositoryPath string
+	newVersion          string
+	plan                string
+	writePlan           string
+	skipInspect         bool
+
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+	git         flagutil.GraphGitOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository")
+	fs.StringVar(&o.newVersion, "new", "", "New version where all risks blocking older releases of the same minor should either be extended or declared fixed")
+	fs.StringVar(&o.plan, "plan", "", "Path to a YAML file mapping risk names to 'extend', 'fix' or 'skip', used instead of asking for each risk; risks not in the plan are skipped")
+	fs.StringVar(&o.writePlan, "write-plan", "", "Write a plan with all risks to extend or fix (set to 'skip') into this file and exit without changing anything")
+	fs.BoolVar(&o.skipInspect, "skip-inspect", false, "Skip inspecting the bug state of each risk")
+
+	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.git.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.graphRepositoryPath == "" {
+		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
+	}
+
+	if o.newVersion == "" {
+		return fmt.Errorf("--new must be specified and nonempty")
+	}
+	if _, err := version.ParseGeneric(o.newVersion); err != nil {
+		return fmt.Errorf("--new must be a release version: %w", err)
+	}
+
+	if o.plan != "" && o.writePlan != "" {
+		return fmt.Errorf("--plan and --write-plan are mutually exclusive")
+	}
+	if o.plan == "" && o.writePlan == "" && !jiratui.Interactive() {
+		return fmt.Errorf("either --plan or --write-plan must be specified when not running in a terminal")
+	}
+
+	if o.skipInspect {
+		return nil
+	}
+
+	return o.jira.Validate()
+}
+
+// candidate is a risk that blocks an older release of the minor of the new version
+type candidate struct {
+	risk string
+	// last is the newest blocked edge of the risk in the minor
+	last     graph.ConditionallyBlockedEdge
+	lastPath string
+
+	action string
+}
+
+// findCandidates returns the risks whose newest blocked edge in the minor of newVersion blocks an
+// older release and that were not declared fixed in that minor yet, sorted by name
+func findCandidates(index *graph.Index, newVersion *version.Version) []*candidate {
+	byRisk := map[string]*candidate{}
+	fixed := map[string]bool{}
+	_ = index.Walk(graph.Filter{}, func(path string, edge graph.ConditionallyBlockedEdge) error {
+		to, err := version.ParseGeneric(edge.To)
+		if err != nil {
+			logrus.WithError(err).Warnf("Skipping file %s with unparseable 'to' version %q", path, edge.To)
+			return nil
+		}
+		if to.Major() != newVersion.Major() || to.Minor() != newVersion.Minor() {
+			return nil
+		}
+		if edge.FixedIn != "" {
+			fixed[edge.Name] = true
+		}
+		if current, ok := byRisk[edge.Name]; ok && !version.MustParseGeneric(current.last.To).LessThan(to) {
+			return nil
+		}
+		byRisk[edge.Name] = &candidate{risk: edge.Name, last: edge, lastPath: path}
+		return nil
+	})
+
+	var candidates []*candidate
+	for name, c := range byRisk {
+		if fixed[name] || !version.MustParseGeneric(c.last.To).LessThan(newVersion) {
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].risk < candidates[j].risk })
+	return candidates
+}
+
+func loadPlan(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read plan: %w", err)
+	}
+	var plan map[string]string
+	if err := yaml.Unmarshal(raw, &plan); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal plan: %w", err)
+	}
+	for risk, action := range plan {
+		switch action {
+		case actionExtend, actionFix, actionSkip:
+		default:
+			return nil, fmt.Errorf("plan: risk %s has invalid action %q, must be one of %s, %s, %s", risk, action, actionExtend, actionFix, actionSkip)
+		}
+	}
+	return plan, nil
+}
+
+func writePlan(path string, candidates []*candidate) error {
+	plan := map[string]string{}
+	for _, c := range candidates {
+		plan[c.risk] = actionSkip
+	}
+	raw, err := yaml.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf("# Set each risk to %q, %q or %q and pass this file with --plan\n", actionExtend, actionFix, actionSkip)
+	return os.WriteFile(path, append([]byte(header), raw...), 0644)
+}
+
+// ask asks for the action for the candidate until a valid one is given
+func ask(c *candidate, newVersion string) (string, error) {
+	for {
+		question := fmt.Sprintf("%s (newest blocked %s): %s, %s or %s in %s?", c.risk, c.last.To, actionExtend, actionFix, actionSkip, newVersion)
+		answer, err := jiratui.Ask(question, actionSkip)
+		if err != nil {
+			return "", err
+		}
+		switch answer = strings.TrimSpace(answer); answer {
+		case actionExtend, actionFix, actionSkip:
+			return answer, nil
+		}
+		logrus.Warnf("Invalid action %q", answer)
+	}
+}
+
+// update returns the blocked edge of the candidate extended to or declared fixed in the new version,
+// together with the path it should be written to and the corresponding hook event
+func update(o options, c *candidate) (graph.ConditionallyBlockedEdge, string, hooks.Event) {
+	edge := c.last
+	if c.action == actionExtend {
+		edge.To = o.newVersion
+		return edge, graph.EdgePath(o.graphRepositoryPath, o.newVersion, c.risk), hooks.EventRiskExtended
+	}
+	edge.FixedIn = o.newVersion
+	return edge, c.lastPath, hooks.EventRiskFixed
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph extend-all
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	newVersion := version.MustParseGeneric(o.newVersion)
+	index, err := graph.LoadIndex(o.graphRepositoryPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read graph repository")
+	}
+
+	candidates := findCandidates(index, newVersion)
+	logrus.Infof("Found %d risks blocking older %d.%d releases", len(candidates), newVersion.Major(), newVersion.Minor())
+	if len(candidates) == 0 {
+		return
+	}
+
+	if o.writePlan != "" {
+		if err := writePlan(o.writePlan, candidates); err != nil {
+			logrus.WithError(err).Fatal("cannot write plan")
+		}
+		logrus.Infof("Plan written to %s", o.writePlan)
+		return
+	}
+
+	var plan map[string]string
+	if o.plan != "" {
+		if plan, err = loadPlan(o.plan); err != nil {
+			exitcode.Fatal(exitcode.InvalidOptions, err, "invalid plan")
+		}
+	}
+
+	inspect := func(*candidate) {}
+	if !o.skipInspect {
+		jiraClient, err := o.jira.Client()
+		if err != nil {
+			logrus.WithError(err).Fatal("cannot create Jira client")
+		}
+		inspect = func(c *candidate) {
+			if !strings.HasPrefix(c.last.URL, graph.JiraBrowsePrefix) {
+				logrus.Warnf("%s: blocked edge reference URL %s is not a Jira card", c.risk, c.last.URL)
+				return
+			}
+			inspection, err := riskinspect.Inspect(ctx, jiraClient, o.bugProjects.IsBug, strings.TrimPrefix(c.last.URL, graph.JiraBrowsePrefix))
+			if err != nil {
+				exitcode.JiraFatal(err, "cannot get issue")
+			}
+			fmt.Printf("\n=== %s: %s ===\n\n", c.risk, inspection.Summary())
+			inspection.Print(os.Stdout)
+		}
+	}
+
+	var selected []*candidate
+	var written []string
+	for _, c := range candidates {
+		if plan != nil {
+			c.action = plan[c.risk]
+		} else {
+			inspect(c)
+			if c.action, err = ask(c, o.newVersion); errors.Is(err, jiratui.ErrCancelled) {
+				logrus.Info("Cancelled, nothing was changed")
+				return
+			} else if err != nil {
+				logrus.WithError(err).Fatal("cannot ask for the action")
+			}
+		}
+		if c.action == "" || c.action == actionSkip {
+			continue
+		}
+		selected = append(selected, c)
+		_, path, _ := update(o, c)
+		written = append(written, path)
+	}
+	if len(selected) == 0 {
+		logrus.Info("No risks selected to extend or fix")
+		return
+	}
+
+	if _, err := o.git.Prepare(o.graphRepositoryPath, fmt.Sprintf("extend-all-%s", o.newVersion), written); err != nil {
+		logrus.WithError(err).Fatal("cannot write into the graph repository")
+	}
+
+	hookRunner, err := hooks.Load()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load hooks config")
+	}
+
+	var failed bool
+	for i, c := range selected {
+		if ctx.Err() != nil {
+			logrus.Warnf("Interrupted, not writing the remaining %d risks", len(selected)-i)
+			failed = true
+			break
+		}
+		edge, path, event := update(o, c)
+		hookData := map[string]string{"risk": c.risk, "last": c.last.To, "new": o.newVersion, "path": path}
+		if err := hookRunner.Pre(event, hookData); err != nil {
+			logrus.WithError(err).Errorf("%s: pre-action hook failed, skipping", c.risk)
+			failed = true
+			continue
+		}
+		if diagnostics := promql.CheckRules(edge.MatchingRules); len(diagnostics) > 0 {
+			for _, diagnostic := range diagnostics {
+				logrus.Errorf("%s: %s", path, diagnostic)
+			}
+			failed = true
+			continue
+		}
+		logrus.Infof("%s: %s in %s (%s)", c.risk, c.action, o.newVersion, path)
+		if err := graph.SaveEdge(path, edge); err != nil {
+			logrus.WithError(err).Errorf("%s: cannot write blocked edge", c.risk)
+			failed = true
+			continue
+		}
+		hookRunner.Post(event, hookData)
+	}
+	o.git.ShowDiff(o.graphRepositoryPath, written)
+
+	if failed {
+		os.Exit(exitcode.PartialFailure)
+	}
+}
@@ -1,19 +1,25 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
-	"github.com/andygrunwald/go-jira"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
-	"k8s.io/apimachinery/pkg/util/sets"
 
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
 	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/hooks"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jiratui"
+	"github.com/petr-muller/ota/internal/promql"
+	"github.com/petr-muller/ota/internal/riskinspect"
 )
 
 type options struct {
@@ -25,31 +31,50 @@ type options struct {
 
 	action      string
 	skipInspect bool
+	editMessage bool
 
-	jira flagutil.JiraOptions
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+	git         flagutil.GraphGitOptions
+	pullRequest flagutil.PullRequestOptions
+
+	log flagutil.LogOptions
 }
 
 func gatherOptions() options {
 	var o options
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
 
 	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository")
 	fs.StringVar(&o.risk, "risk", "", "The identifier of the risk to extend or declare fixed")
 	fs.StringVar(&o.lastVersion, "last", "", "Most recent version where the risk still exists")
 	fs.StringVar(&o.newVersion, "new", "", "New version where the risk should either be extended or declared fixed")
-	fs.StringVar(&o.action, "do", "", "Action to perform: 'extend' or declare 'fix'. Default is to do nothing")
+	fs.StringVar(&o.action, "do", "", "Action to perform: 'extend' or declare 'fix'. Default is to explore the linked bugs and accept an action in a terminal UI, or to do nothing when not running in a terminal")
 	fs.BoolVar(&o.skipInspect, "skip-inspect", false, "Skip inspecting the bug state and just perform the action")
+	fs.BoolVar(&o.editMessage, "edit-message", false, "Open the risk name and message in $EDITOR before writing the extended blocked edge")
 
 	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.git.AddFlags(fs)
+	o.pullRequest.AddFlags(fs)
+	o.log.AddFlags(fs)
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
 	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
 
 	return o
 }
 
 func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
 	if o.graphRepositoryPath == "" {
 		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
 	}
@@ -71,224 +96,177 @@ func (o *options) validate() error {
 
 	}
 
-	return o.jira.Validate()
-}
-
-type PromQLQuery struct {
-	Query string `yaml:"promql"`
-}
-
-type PromQLRule struct {
-	Type   string      `yaml:"type"`
-	PromQL PromQLQuery `yaml:"promql"`
-}
+	if err := o.pullRequest.Validate(); err != nil {
+		return err
+	}
+	if o.pullRequest.Enabled() && o.action == "" {
+		return fmt.Errorf("--open-pr requires --do")
+	}
 
-type ConditionallyBlockedEdge struct {
-	To            string       `yaml:"to"`
-	From          string       `yaml:"from"`
-	FixedIn       string       `yaml:"fixedIn,omitempty"`
-	URL           string       `yaml:"url"`
-	Name          string       `yaml:"name"`
-	Message       string       `yaml:"message"`
-	MatchingRules []PromQLRule `yaml:"matchingRules"`
+	return o.jira.Validate()
 }
 
 func main() {
 	// TODO(muller): Cobrify as ota graph ...
 	o := gatherOptions()
 	if err := o.validate(); err != nil {
-		logrus.WithError(err).Fatal("invalid options")
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
 	}
+	o.log.Apply()
+	ctx := interrupt.Context()
 
-	edgesDirectory := filepath.Join(o.graphRepositoryPath, "blocked-edges")
-	lastVersionBlockPath := filepath.Join(edgesDirectory, fmt.Sprintf("%s-%s.yaml", o.lastVersion, o.risk))
-	updatedEdgeRaw, err := os.ReadFile(lastVersionBlockPath)
+	lastVersionBlockPath := graph.EdgePath(o.graphRepositoryPath, o.lastVersion, o.risk)
+	lastVersionBlock, err := graph.LoadEdge(lastVersionBlockPath)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot read source file")
 	}
 
-	var lastVersionBlock ConditionallyBlockedEdge
-	if err := yaml.Unmarshal(updatedEdgeRaw, &lastVersionBlock); err != nil {
-		logrus.WithError(err).Fatal("cannot unmarshal source file")
-	}
-
+	var impactStatementSummary string
 	if !o.skipInspect {
 		impactStatementCard := lastVersionBlock.URL
-		if !strings.HasPrefix(impactStatementCard, "https://issues.redhat.com/browse/") {
+		if !strings.HasPrefix(impactStatementCard, graph.JiraBrowsePrefix) {
 			logrus.Warnf("Blocked edge reference URL %s is not a Jira card", impactStatementCard)
 			return
 		}
-		impactStatementCard = strings.TrimPrefix(impactStatementCard, "https://issues.redhat.com/browse/")
+		impactStatementCard = strings.TrimPrefix(impactStatementCard, graph.JiraBrowsePrefix)
 
 		jiraClient, err := o.jira.Client()
 		if err != nil {
 			logrus.WithError(err).Fatal("cannot create Jira client")
 		}
 
-		logrus.Infof("Obtaining (likely) impact statement card %s and process its linked bugs", impactStatementCard)
-		blockerCandidate, err := jiraClient.GetIssue(impactStatementCard)
+		inspection, err := riskinspect.Inspect(ctx, jiraClient, o.bugProjects.IsBug, impactStatementCard)
 		if err != nil {
-			logrus.WithError(err).Fatal("cannot get issue")
-		}
-		seen := sets.New[string]()
-		bugs := map[string]*jira.Issue{}
-		worklist := map[string]*jira.Issue{impactStatementCard: blockerCandidate}
-		directBlocks := sets.New[string]()
-
-		for len(worklist) > 0 {
-			var key string
-			var card *jira.Issue
-			for k, v := range worklist {
-				key = k
-				card = v
-				delete(worklist, key)
-				break
-			}
-
-			if seen.Has(key) {
-				logrus.Tracef("%s: Skipping already seen card", key)
-				continue
-			}
-			seen.Insert(key)
-
-			if card == nil {
-				// Should not happen
-				continue
-			}
-
-			fmt.Printf("%s ", key)
-			if strings.HasPrefix(key, "OCPBUGS-") {
-				logrus.Tracef("%s: Found a bug card", key)
-				bugs[key] = card
-			}
-
-			for _, link := range card.Fields.IssueLinks {
-				if outward := link.OutwardIssue; outward != nil {
-					if strings.HasPrefix(outward.Key, "OCPBUGS-") {
-						linkedIssue, err := jiraClient.GetIssue(outward.Key)
-						if err != nil {
-							logrus.WithError(err).Fatal("cannot get issue")
-						}
-						worklist[outward.Key] = linkedIssue
-						if key == blockerCandidate.Key && link.Type.Outward == "blocks" {
-							directBlocks.Insert(outward.Key)
-						}
-					} else {
-						logrus.Tracef("%s: not following a non-bug link '%s %s'", key, link.Type.Outward, outward.Key)
-					}
-				}
-				if inward := link.InwardIssue; inward != nil {
-					if strings.HasPrefix(inward.Key, "OCPBUGS-") {
-						linkedIssue, err := jiraClient.GetIssue(inward.Key)
-						if err != nil {
-							logrus.WithError(err).Fatal("cannot get issue")
-						}
-						worklist[inward.Key] = linkedIssue
-						if key == blockerCandidate.Key && link.Type.Inward == "blocks" {
-							directBlocks.Insert(inward.Key)
-						}
-					} else {
-						logrus.Tracef("%s: not following a non-bug link '%s %s'", key, link.Type.Inward, inward.Key)
-					}
-				}
-			}
+			exitcode.JiraFatal(err, "cannot get issue")
 		}
-		fmt.Printf("\n")
-
-		logrus.Infof("Found %d bug cards", len(bugs))
-		for key, bug := range bugs {
-			targetVersion := ""
-			if items, err := getIssueTargetVersion(bug); err == nil && len(items) > 0 {
-				targetVersion = items[0].Name
-				if len(items) > 1 {
-					logrus.Warningf("%s: Found multiple target versions: %v", key, items)
-				}
+		impactStatementSummary = inspection.Summary()
+		if o.action == "" && jiratui.Interactive() {
+			o.action, err = jiratui.ExploreBugs(inspection, jiraClient.JiraURL(), o.newVersion)
+			if errors.Is(err, jiratui.ErrCancelled) {
+				logrus.Info("Cancelled, nothing was changed")
+				return
+			} else if err != nil {
+				logrus.WithError(err).Fatal("cannot explore the linked bugs")
 			}
-
-			direct := ""
-			if directBlocks.Has(key) {
-				direct = "x"
-			}
-			// TODO(muller): Tabulate better, sort etc
-			fmt.Printf("%s\t%-2s\t%s\t%-12s\t%s\n", key, direct, targetVersion, bug.Fields.Status.Name, bug.Fields.Summary)
+		} else {
+			inspection.Print(os.Stdout)
+			action, reason := inspection.Recommend(o.newVersion)
+			fmt.Printf("\nRecommendation: %s in %s (%s)\n", action, o.newVersion, reason)
 		}
 	}
 
-	// TODO(muller): Infer whether the bug is likely fixed or not
-	// Likely only follow direct block links from the impact statement card and their clones
-	// Unfixed (up to MODIFIED?) bugs in higher or or equal versions are likely unfixed
-	// No unfixed (up to MODIFIED) bugs in higher or equal versions are likely fixed
-	// ON_QA and VERIFIED are hard to reason about: maybe check them in release controller diffs?
+	hookRunner, err := hooks.Load()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load hooks config")
+	}
 
-	var destinationPath string
+	var destinationPath, title string
+	var hookEvent hooks.Event
 	updatedEdge := lastVersionBlock
 	switch o.action {
 	case "":
 		logrus.Infof("No action specified, doing nothing")
 		return
 	case "extend":
-		logrus.Infof("Extending `%s` risk to %s", o.risk, o.newVersion)
+		if o.editMessage {
+			var header []string
+			if impactStatementSummary != "" {
+				header = append(header, "Impact statement: "+impactStatementSummary)
+			}
+			if updatedEdge.Name, updatedEdge.Message, err = graph.EditNameAndMessage(updatedEdge.Name, updatedEdge.Message, header...); err != nil {
+				logrus.WithError(err).Fatal("cannot edit risk name and message")
+			}
+			if updatedEdge.Name != o.risk {
+				logrus.Infof("Renaming the risk %s to %s in %s; the blocked edges of older versions keep the old name", o.risk, updatedEdge.Name, o.newVersion)
+			}
+		}
+		logrus.Infof("Extending `%s` risk to %s", updatedEdge.Name, o.newVersion)
+		hookEvent = hooks.EventRiskExtended
 		updatedEdge.To = o.newVersion
-		destinationPath = filepath.Join(edgesDirectory, fmt.Sprintf("%s-%s.yaml", o.newVersion, o.risk))
+		destinationPath = graph.EdgePath(o.graphRepositoryPath, o.newVersion, updatedEdge.Name)
+		title = fmt.Sprintf("%s: Extend %s to %s", graph.EdgeFileName(o.newVersion, updatedEdge.Name), updatedEdge.Name, o.newVersion)
 	case "fix":
 		logrus.Infof("Declaring the risk %s fixed in %s", o.risk, o.newVersion)
+		hookEvent = hooks.EventRiskFixed
 		updatedEdge.FixedIn = o.newVersion
 		destinationPath = lastVersionBlockPath
+		title = fmt.Sprintf("%s: Declare %s fixed in %s", graph.EdgeFileName(o.lastVersion, o.risk), o.risk, o.newVersion)
+	}
+	// the edited name is used for everything written from now on, so that the file name, the branch
+	// and the pull request match the content of the written blocked edge
+	risk := updatedEdge.Name
+
+	branch, err := o.git.Prepare(o.graphRepositoryPath, risk, []string{destinationPath})
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot write into the graph repository")
+	}
+
+	hookData := map[string]string{"risk": risk, "last": o.lastVersion, "new": o.newVersion, "path": destinationPath}
+	if err := hookRunner.Pre(hookEvent, hookData); err != nil {
+		logrus.WithError(err).Fatal("pre-action hook failed")
 	}
 
-	updatedEdgeRaw, err = yaml.Marshal(updatedEdge)
+	updatedEdgeRaw, err := graph.MarshalEdge(updatedEdge)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot marshal blocked edge")
 	}
+	diagnostics, err := promql.CheckEdge(updatedEdgeRaw)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot validate blocked edge")
+	}
+	for _, diagnostic := range diagnostics {
+		logrus.Errorf("%s: %s", destinationPath, diagnostic)
+	}
+	if len(diagnostics) > 0 {
+		logrus.Fatal("blocked edge has invalid PromQL in matchingRules")
+	}
 	if err := os.WriteFile(destinationPath, updatedEdgeRaw, 0644); err != nil {
 		logrus.WithError(err).Fatal("cannot write blocked edge")
 	}
 
+	o.git.ShowDiff(o.graphRepositoryPath, []string{destinationPath})
+
+	hookRunner.Post(hookEvent, hookData)
+
+	if o.pullRequest.Enabled() {
+		if branch == "" {
+			branch = fmt.Sprintf("%s-%s-%s", o.action, risk, o.newVersion)
+		}
+		openPullRequest(ctx, o, updatedEdge, branch, destinationPath, title)
+	}
 }
 
-// Stolen from openshift-eng/jira-lifecycle-plugin
-const (
-	TargetVersionField    = "customfield_12319940"
-	TargetVersionFieldOld = "customfield_12323140"
-)
+// openPullRequest proposes the written blocked edge in a pull request and comments on the impact
+// statement card about it when requested
+func openPullRequest(ctx context.Context, o options, edge graph.ConditionallyBlockedEdge, branch, path, title string) {
+	body := fmt.Sprintf("%s\n\nImpact statement: %s", title, edge.URL)
+	url, err := o.pullRequest.Open(ctx, o.graphRepositoryPath, branch, []string{path}, title, body)
+	if err != nil {
+		exitcode.Fatal(exitcode.Error, err, "cannot open pull request")
+	}
+	fmt.Println(url)
 
-// getUnknownField will attempt to get the specified field from the Unknowns struct and unmarshal
-// the value into the provided function. If the field is not set, the first return value of this
-// function will return false.
-func getUnknownField(field string, issue *jira.Issue, fn func() interface{}) (bool, error) {
-	obj := fn()
-	if issue.Fields == nil || issue.Fields.Unknowns == nil {
-		return false, nil
+	if !o.pullRequest.CommentOnCard() {
+		return
 	}
-	unknownField, ok := issue.Fields.Unknowns[field]
-	if !ok {
-		return false, nil
+	if !strings.HasPrefix(edge.URL, graph.JiraBrowsePrefix) {
+		logrus.Warnf("Blocked edge reference URL %s is not a Jira card, not commenting about the pull request", edge.URL)
+		return
 	}
-	bytes, err := json.Marshal(unknownField)
+	jiraClient, err := o.jira.Client()
 	if err != nil {
-		return true, fmt.Errorf("failed to process the custom field %s. Error : %v", field, err)
+		logrus.WithError(err).Fatal("cannot create Jira client")
 	}
-	if err := json.Unmarshal(bytes, obj); err != nil {
-		return true, fmt.Errorf("failed to unmarshal the json to struct for %s. Error: %v", field, err)
+	card, err := jiraClient.GetIssue(strings.TrimPrefix(edge.URL, graph.JiraBrowsePrefix))
+	if err != nil {
+		exitcode.JiraFatal(err, "cannot get issue")
 	}
-	return true, nil
-}
-
-func getIssueTargetVersion(issue *jira.Issue) ([]*jira.Version, error) {
-	var obj *[]*jira.Version
-	isSet, err := getUnknownField(TargetVersionField, issue, func() interface{} {
-		obj = &[]*jira.Version{{}}
-		return obj
-	})
-	if isSet && obj != nil && *obj != nil {
-		return *obj, err
+	flow, err := blockerflow.NewFlow(jiraClient)
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot initialize blocker workflow")
 	}
-	isSet, err = getUnknownField(TargetVersionFieldOld, issue, func() interface{} {
-		obj = &[]*jira.Version{{}}
-		return obj
-	})
-	if !isSet {
-		return nil, err
+	flow.Visibility = o.jira.CommentVisibility(flow.Visibility)
+	if err := flow.AnnouncePullRequest(card, url); err != nil {
+		logrus.WithError(err).Fatal("cannot comment on the impact statement card")
 	}
-	return *obj, err
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/promql"
+)
+
+type options struct {
+	graphRepositoryPath string
+	strict              bool
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository")
+	fs.BoolVar(&o.strict, "strict", false, "Also fail on message style warnings, which are guessed by heuristics and may be wrong about valid messages")
+
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.graphRepositoryPath == "" {
+		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
+	}
+
+	return nil
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph lint
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+
+	index, err := graph.LoadIndex(o.graphRepositoryPath)
+	var edgeErrors graph.EdgeErrors
+	if err != nil && !errors.As(err, &edgeErrors) {
+		logrus.WithError(err).Fatal("cannot read graph repository")
+	}
+
+	files, failed := len(edgeErrors), len(edgeErrors)
+	for _, path := range slices.Sorted(maps.Keys(edgeErrors)) {
+		// LoadEdge errors already name the file
+		cause := edgeErrors[path]
+		if unwrapped := errors.Unwrap(cause); unwrapped != nil {
+			cause = unwrapped
+		}
+		fmt.Printf("%s: cannot parse YAML: %v\n", path, cause)
+	}
+
+	if err := index.Walk(graph.Filter{}, func(path string, edge graph.ConditionallyBlockedEdge) error {
+		files++
+		diagnostics := promql.CheckRules(edge.MatchingRules)
+		for _, diagnostic := range diagnostics {
+			fmt.Printf("%s: %s\n", path, diagnostic)
+		}
+
+		problems := graph.CheckMessage(edge.Message)
+		for _, problem := range problems {
+			fmt.Printf("%s: %s\n", path, problem)
+		}
+		if !o.strict {
+			problems = graph.MessageErrors(problems)
+		}
+
+		nameProblem := graph.CheckFileName(path, edge)
+		if nameProblem != "" {
+			fmt.Printf("%s: %s\n", path, nameProblem)
+		}
+
+		if len(diagnostics) > 0 || len(problems) > 0 || nameProblem != "" {
+			failed++
+		}
+		return nil
+	}); err != nil {
+		logrus.WithError(err).Fatal("cannot walk graph repository")
+	}
+
+	logrus.Infof("Checked %d files, %d have problems", files, failed)
+	if failed > 0 {
+		os.Exit(exitcode.ValidationFailed)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jirautil"
+)
+
+const (
+	outputTable    = "table"
+	outputJSON     = "json"
+	outputMarkdown = "markdown"
+)
+
+// resolvedStatuses are the bug statuses that count as resolved in the inventory
+var resolvedStatuses = sets.New("VERIFIED", "Closed")
+
+type options struct {
+	output   string
+	skipJira bool
+
+	risks       flagutil.RiskSourceOptions
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.output, "output", outputTable, fmt.Sprintf("Output format: %s, %s or %s", outputTable, outputJSON, outputMarkdown))
+	fs.BoolVar(&o.skipJira, "skip-jira", false, "Do not query Jira for the status of the impact statement cards and their bugs")
+
+	o.risks.AddFlags(fs)
+	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	switch o.output {
+	case outputTable, outputJSON, outputMarkdown:
+	default:
+		return fmt.Errorf("--output must be one of %s, %s, %s", outputTable, outputJSON, outputMarkdown)
+	}
+
+	if err := o.risks.Validate(); err != nil {
+		return err
+	}
+
+	if o.skipJira {
+		return nil
+	}
+
+	return o.jira.Validate()
+}
+
+// bug is a bug linked to the impact statement card of a risk
+type bug struct {
+	Key    string `json:"key"`
+	Status string `json:"status"`
+}
+
+// risk is the inventory entry of all blocked edges that share a risk name
+type risk struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+
+	// From and To summarize the versions of the blocked edges, see versionRanges
+	From    []string `json:"from"`
+	To      []string `json:"to"`
+	FixedIn []string `json:"fixedIn,omitempty"`
+
+	ImpactStatement       string `json:"impactStatement,omitempty"`
+	ImpactStatementStatus string `json:"impactStatementStatus,omitempty"`
+	Bugs                  []bug  `json:"bugs,omitempty"`
+
+	from, to sets.Set[string]
+}
+
+// resolved returns how many of the linked bugs are VERIFIED or Closed
+func (r risk) resolved() int {
+	var resolved int
+	for _, b := range r.Bugs {
+		if resolvedStatuses.Has(b.Status) {
+			resolved++
+		}
+	}
+	return resolved
+}
+
+func (r risk) bugsColumn() string {
+	if len(r.Bugs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d resolved", r.resolved(), len(r.Bugs))
+}
+
+// versionRanges summarizes versions as one "lowest - highest" range per minor. Values that are not
+// versions (the from regular expressions in the graph repository) are returned as they are.
+func versionRanges(values sets.Set[string]) []string {
+	type bounds struct{ lowest, highest *version.Version }
+	byMinor := map[string]*bounds{}
+	var ranges []string
+	for _, value := range sets.List(values) {
+		v, err := version.ParseSemantic(value)
+		if err != nil {
+			ranges = append(ranges, value)
+			continue
+		}
+		minor := fmt.Sprintf("%d.%d", v.Major(), v.Minor())
+		b, ok := byMinor[minor]
+		if !ok {
+			byMinor[minor] = &bounds{lowest: v, highest: v}
+			continue
+		}
+		if v.LessThan(b.lowest) {
+			b.lowest = v
+		}
+		if b.highest.LessThan(v) {
+			b.highest = v
+		}
+	}
+	for _, b := range byMinor {
+		if b.lowest.String() == b.highest.String() {
+			ranges = append(ranges, b.lowest.String())
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%s - %s", b.lowest, b.highest))
+		}
+	}
+	sort.Strings(ranges)
+	return ranges
+}
+
+func inventory(edges []graph.ConditionallyBlockedEdge) []*risk {
+	byName := map[string]*risk{}
+	fixedIn := map[string]sets.Set[string]{}
+	for _, edge := range edges {
+		r, ok := byName[edge.Name]
+		if !ok {
+			r = &risk{Name: edge.Name, URL: edge.URL, from: sets.New[string](), to: sets.New[string]()}
+			byName[edge.Name] = r
+			fixedIn[edge.Name] = sets.New[string]()
+		}
+		r.from.Insert(edge.From)
+		r.to.Insert(edge.To)
+		if edge.FixedIn != "" {
+			fixedIn[edge.Name].Insert(edge.FixedIn)
+		}
+	}
+
+	var risks []*risk
+	for name, r := range byName {
+		r.From = versionRanges(r.from)
+		r.To = versionRanges(r.to)
+		r.FixedIn = sets.List(fixedIn[name])
+		if strings.HasPrefix(r.URL, graph.JiraBrowsePrefix) {
+			r.ImpactStatement = strings.TrimPrefix(r.URL, graph.JiraBrowsePrefix)
+		}
+		risks = append(risks, r)
+	}
+	sort.Slice(risks, func(i, j int) bool { return risks[i].Name < risks[j].Name })
+	return risks
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph risks
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	edges, err := o.risks.Edges(ctx)
+	if err != nil {
+		exitcode.Fatal(exitcode.Error, err, "cannot look up conditional risks")
+	}
+	risks := inventory(edges)
+
+	if !o.skipJira {
+		jiraClient, err := o.jira.Client()
+		if err != nil {
+			logrus.WithError(err).Fatal("cannot create Jira client")
+		}
+		var withCards []*risk
+		var keys []string
+		for _, r := range risks {
+			if r.ImpactStatement != "" {
+				withCards = append(withCards, r)
+				keys = append(keys, r.ImpactStatement)
+			}
+		}
+		logrus.Infof("Obtaining %d impact statement cards", len(keys))
+		cards, err := jirautil.DefaultFetcher.GetIssues(ctx, jiraClient, keys)
+		if err != nil {
+			exitcode.JiraFatal(err, "cannot get issue")
+		}
+		for i, r := range withCards {
+			card := cards[i]
+			if card.Fields.Status != nil {
+				r.ImpactStatementStatus = card.Fields.Status.Name
+			}
+			for _, link := range card.Fields.IssueLinks {
+				for _, linked := range []*jira.Issue{link.OutwardIssue, link.InwardIssue} {
+					if linked == nil || !o.bugProjects.IsBug(linked.Key) || linked.Fields == nil || linked.Fields.Status == nil {
+						continue
+					}
+					r.Bugs = append(r.Bugs, bug{Key: linked.Key, Status: linked.Fields.Status.Name})
+				}
+			}
+		}
+	}
+
+	switch o.output {
+	case outputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(risks); err != nil {
+			logrus.WithError(err).Fatal("cannot encode risks")
+		}
+	case outputMarkdown:
+		fmt.Println("| Risk | From | To | Fixed in | Impact statement | Status | Bugs |")
+		fmt.Println("| --- | --- | --- | --- | --- | --- | --- |")
+		for _, r := range risks {
+			card := r.URL
+			if r.ImpactStatement != "" {
+				card = fmt.Sprintf("[%s](%s)", r.ImpactStatement, r.URL)
+			}
+			fmt.Printf("| %s | %s | %s | %s | %s | %s | %s |\n", r.Name, strings.Join(r.From, "<br>"), strings.Join(r.To, "<br>"), strings.Join(r.FixedIn, ", "), card, r.ImpactStatementStatus, r.bugsColumn())
+		}
+	default:
+		tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		_, _ = tabw.Write([]byte("RISK\tFROM\tTO\tFIXED IN\tISR\tSTATUS\tBUGS\n"))
+		for _, r := range risks {
+			_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Name, strings.Join(r.From, ", "), strings.Join(r.To, ", "), strings.Join(r.FixedIn, ", "), r.ImpactStatement, r.ImpactStatementStatus, r.bugsColumn())))
+		}
+		_ = tabw.Flush()
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/promql"
+)
+
+type options struct {
+	edge     string
+	profiles prowflagutil.Strings
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	o := options{profiles: prowflagutil.NewStrings()}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.edge, "edge", "", "The blocked edge file whose matchingRules to simulate")
+	fs.Var(&o.profiles, "cluster-profile", "A cluster profile to evaluate the matchingRules against: a YAML file with the name and the metrics of the cluster in the Prometheus text format, or an Insights Operator archive (.tar.gz) (can be passed multiple times)")
+
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.edge == "" {
+		return fmt.Errorf("--edge must be specified and nonempty")
+	}
+
+	if len(o.profiles.Strings()) == 0 {
+		return fmt.Errorf("--cluster-profile must be specified at least once")
+	}
+
+	return nil
+}
+
+// verdict is the outcome of evaluating the matchingRules for a single cluster profile
+type verdict struct {
+	// rule is the index of the rule that decided, or -1 when no rule could be evaluated
+	rule    int
+	matches bool
+	// problems hold the reasons why the rules before the deciding one could not be evaluated
+	problems []string
+}
+
+// simulate evaluates the rules like the cluster-version operator does: in order, until the first rule
+// that evaluates successfully, which decides whether the cluster is exposed to the risk
+func simulate(ctx context.Context, rules []graph.PromQLRule, profile *promql.Profile) verdict {
+	v := verdict{rule: -1}
+	for i, rule := range rules {
+		switch rule.Type {
+		case "Always":
+			v.rule, v.matches = i, true
+			return v
+		case "PromQL":
+		default:
+			v.problems = append(v.problems, fmt.Sprintf("matchingRules[%d]: unknown type %s", i, rule.Type))
+			continue
+		}
+
+		result, err := profile.Evaluate(ctx, rule.PromQL.Query)
+		if err != nil {
+			v.problems = append(v.problems, fmt.Sprintf("matchingRules[%d]: evaluation failed: %v", i, err))
+			continue
+		}
+		if problems := promql.CheckResult(result, nil); len(problems) > 0 {
+			for _, problem := range problems {
+				v.problems = append(v.problems, fmt.Sprintf("matchingRules[%d]: %s", i, problem))
+			}
+			continue
+		}
+		v.rule, v.matches = i, result.Samples[0].Value == "1"
+		return v
+	}
+	return v
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph simulate
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	edge, err := graph.LoadEdge(o.edge)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read blocked edge")
+	}
+
+	var profiles []*promql.Profile
+	for _, path := range o.profiles.Strings() {
+		profile, err := promql.LoadProfile(path)
+		if err != nil {
+			logrus.WithError(err).Fatal("cannot load cluster profile")
+		}
+		profiles = append(profiles, profile)
+	}
+
+	failed := false
+	for _, profile := range profiles {
+		v := simulate(ctx, edge.MatchingRules, profile)
+		if ctx.Err() != nil {
+			exitcode.Fatal(exitcode.Error, ctx.Err(), "interrupted")
+		}
+		switch {
+		case v.rule < 0:
+			fmt.Printf("%s: cannot evaluate the risk %s, the cluster would report the evaluation failure\n", profile.Name, edge.Name)
+			failed = true
+		case v.matches:
+			fmt.Printf("%s: exposed to %s (matchingRules[%d])\n", profile.Name, edge.Name, v.rule)
+		default:
+			fmt.Printf("%s: not exposed to %s (matchingRules[%d])\n", profile.Name, edge.Name, v.rule)
+		}
+		for _, problem := range v.problems {
+			fmt.Printf("  %s\n", problem)
+		}
+	}
+
+	if failed {
+		os.Exit(exitcode.ValidationFailed)
+	}
+}
@@ -5,9 +5,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/promql"
 )
 
 type options struct {
@@ -15,25 +26,46 @@ type options struct {
 
 	risk        string
 	fromVersion string
+
+	onlyMinors       prowflagutil.Strings
+	excludedVersions prowflagutil.Strings
+
+	git flagutil.GraphGitOptions
+
+	log flagutil.LogOptions
 }
 
 func gatherOptions() options {
 	var o options
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
 
 	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository")
 
 	fs.StringVar(&o.risk, "risk", "", "The identifier of the risk to be updates")
 	fs.StringVar(&o.fromVersion, "from", "", "The version where the risk was updated manually and its changes should propagate everywhere")
 
+	fs.Var(&o.onlyMinors, "only-minor", "Only spread the changes to blocked edges to versions of this minor, e.g. 4.16 (can be passed multiple times)")
+	fs.Var(&o.excludedVersions, "exclude-version", "Do not spread the changes to the blocked edge to this version (can be passed multiple times)")
+
+	o.git.AddFlags(fs)
+	o.log.AddFlags(fs)
+
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
 	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
 
 	return o
 }
 
 func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
 	if o.graphRepositoryPath == "" {
 		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
 	}
@@ -46,95 +78,113 @@ func (o *options) validate() error {
 		return fmt.Errorf("--from must be specified and nonempty")
 	}
 
-	return nil
-}
+	for _, minor := range o.onlyMinors.Strings() {
+		if v, err := version.ParseGeneric(minor); err != nil || minor != fmt.Sprintf("%d.%d", v.Major(), v.Minor()) {
+			return fmt.Errorf("--only-minor must be a minor version like 4.16, got %q", minor)
+		}
+	}
 
-type PromQLQuery struct {
-	Query string `yaml:"promql"`
+	return nil
 }
 
-type PromQLRule struct {
-	Type   string      `yaml:"type"`
-	PromQL PromQLQuery `yaml:"promql"`
+// selected returns true when the changes should be spread to the blocked edge
+func (o *options) selected(edge graph.ConditionallyBlockedEdge) bool {
+	if sets.New(o.excludedVersions.Strings()...).Has(edge.To) {
+		return false
+	}
+	if len(o.onlyMinors.Strings()) == 0 {
+		return true
+	}
+	to, err := version.ParseGeneric(edge.To)
+	if err != nil {
+		return false
+	}
+	return sets.New(o.onlyMinors.Strings()...).Has(fmt.Sprintf("%d.%d", to.Major(), to.Minor()))
 }
 
-type ConditionallyBlockedEdge struct {
-	To            string       `yaml:"to"`
-	From          string       `yaml:"from"`
-	FixedIn       string       `yaml:"fixedIn,omitempty"`
-	URL           string       `yaml:"url"`
-	Name          string       `yaml:"name"`
-	Message       string       `yaml:"message"`
-	MatchingRules []PromQLRule `yaml:"matchingRules"`
+// changes returns the names of the fields the spreading changes in the target edge
+func changes(source, target graph.ConditionallyBlockedEdge) []string {
+	var changed []string
+	if source.Message != target.Message {
+		changed = append(changed, "message")
+	}
+	if source.URL != target.URL {
+		changed = append(changed, "url")
+	}
+	if !reflect.DeepEqual(source.MatchingRules, target.MatchingRules) {
+		changed = append(changed, "matchingRules")
+	}
+	return changed
 }
 
 func main() {
 	// TODO(muller): Cobrify as ota graph spread-edge-changes
 	o := gatherOptions()
 	if err := o.validate(); err != nil {
-		logrus.WithError(err).Fatal("invalid options")
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
 	}
+	o.log.Apply()
 
-	edgesDirectory := filepath.Join(o.graphRepositoryPath, "blocked-edges")
-	sourcePath := filepath.Join(edgesDirectory, fmt.Sprintf("%s-%s.yaml", o.fromVersion, o.risk))
+	sourcePath := graph.EdgePath(o.graphRepositoryPath, o.fromVersion, o.risk)
 	sourceRaw, err := os.ReadFile(sourcePath)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot read source file")
 	}
 
-	var source ConditionallyBlockedEdge
-	if err := yaml.Unmarshal(sourceRaw, &source); err != nil {
+	source, err := graph.UnmarshalEdge(sourceRaw)
+	if err != nil {
 		logrus.WithError(err).Fatal("cannot unmarshal source file")
 	}
 
-	if err := filepath.WalkDir(edgesDirectory, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			logrus.WithError(err).Errorf("Failure when walking items in graph repository directory %s", edgesDirectory)
-			return err
-		}
+	diagnostics, err := promql.CheckEdge(sourceRaw)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot validate source file")
+	}
+	for _, diagnostic := range diagnostics {
+		logrus.Errorf("%s: %s", sourcePath, diagnostic)
+	}
+	if len(diagnostics) > 0 {
+		logrus.Fatal("source file has invalid PromQL in matchingRules, refusing to spread it")
+	}
 
-		if d.IsDir() {
-			logrus.Tracef("Skipping (unexpected) directory %s", path)
-			return nil
-		}
+	index, err := graph.LoadIndex(o.graphRepositoryPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read graph repository")
+	}
 
-		targetRaw, err := os.ReadFile(path)
-		if err != nil {
-			logrus.WithError(err).Errorf("Cannot read target file %s", path)
-			return err
+	// The source file is usually edited manually and not committed yet
+	var targets []string
+	summary := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	_, _ = summary.Write([]byte("FILE\tCHANGES\n"))
+	_ = index.Walk(graph.Filter{Risk: o.risk}, func(path string, target graph.ConditionallyBlockedEdge) error {
+		if path == sourcePath {
+			return nil
 		}
-
-		var target ConditionallyBlockedEdge
-		if err := yaml.Unmarshal(targetRaw, &target); err != nil {
-			logrus.WithError(err).Errorf("Cannot unmarshal target file %s", path)
-			return err
+		if !o.selected(target) {
+			logrus.Debugf("Skipping %s, not selected by --only-minor or --exclude-version", path)
+			return nil
 		}
-
-		if target.Name != o.risk {
-			logrus.Tracef("Skipping target file %s because it does not match the risk %s", path, o.risk)
+		changed := changes(source, target)
+		if len(changed) == 0 {
+			logrus.Debugf("Skipping %s, already up to date", path)
 			return nil
 		}
+		targets = append(targets, path)
+		_, _ = summary.Write([]byte(fmt.Sprintf("%s\t%s\n", filepath.Base(path), strings.Join(changed, ", "))))
+		return nil
+	})
+	if len(targets) == 0 {
+		logrus.Info("No blocked edges to spread the changes to")
+		return
+	}
+	if _, err := o.git.Prepare(o.graphRepositoryPath, o.risk, targets, sourcePath); err != nil {
+		logrus.WithError(err).Fatal("cannot write into the graph repository")
+	}
 
-		target.Message = source.Message
-		target.URL = source.URL
-		target.MatchingRules = source.MatchingRules
-		// TODO(muller): Handle `from` field, will be likely identical within minor
-
-		targetFile, err := os.Create(path)
-		if err != nil {
-			logrus.WithError(err).Errorf("Cannot open target file %s", path)
-		}
-		defer func(targetFile *os.File) {
-			_ = targetFile.Close()
-		}(targetFile)
-
-		encoder := yaml.NewEncoder(targetFile)
-		encoder.SetIndent(1)
-		if err := encoder.Encode(target); err != nil {
-			logrus.WithError(err).Errorf("Cannot marshal updated edge into target file %s", path)
-		}
-		return err
-	}); err != nil {
-		logrus.WithError(err).Fatal("cannot walk graph repository")
+	if err := index.Spread(source, targets); err != nil {
+		logrus.WithError(err).Fatal("cannot spread the changes")
 	}
+	logrus.Infof("Spread the changes to %d blocked edges", len(targets))
+	_ = summary.Flush()
+	o.git.ShowDiff(o.graphRepositoryPath, targets)
 }
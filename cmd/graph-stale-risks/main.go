@@ -0,0 +1,271 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/timefmt"
+)
+
+type options struct {
+	graphRepositoryPath string
+
+	releases    int
+	isrInactive time.Duration
+	skipJira    bool
+
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository")
+	fs.IntVar(&o.releases, "releases", 3, "Report risks whose newest blocked version is at least this many z-stream releases behind the newest release in the same minor (from the candidate channels of the graph repository)")
+	fs.DurationVar(&o.isrInactive, "isr-inactive", 90*24*time.Hour, "Report risks whose impact statement card had no activity for at least this long")
+	fs.BoolVar(&o.skipJira, "skip-jira", false, "Skip the checks that need to query Jira")
+
+	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.graphRepositoryPath == "" {
+		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
+	}
+
+	if o.releases < 1 {
+		return fmt.Errorf("--releases must be positive")
+	}
+
+	if o.skipJira {
+		return nil
+	}
+
+	return o.jira.Validate()
+}
+
+// risk aggregates all blocked edges that share a risk name
+type risk struct {
+	name string
+	url  string
+
+	// newest holds the newest blocked version for each minor (keyed by "X.Y") the risk affects
+	newest map[string]*version.Version
+	// fixed holds the minors (keyed by "X.Y") where the risk was declared fixed
+	fixed sets.Set[string]
+
+	reasons []string
+}
+
+func majorMinor(v *version.Version) string {
+	return fmt.Sprintf("%d.%d", v.Major(), v.Minor())
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph stale-risks
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	risks := map[string]*risk{}
+
+	index, err := graph.LoadIndex(o.graphRepositoryPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read graph repository")
+	}
+
+	if err := index.Walk(graph.Filter{}, func(path string, edge graph.ConditionallyBlockedEdge) error {
+		to, err := version.ParseGeneric(edge.To)
+		if err != nil {
+			logrus.WithError(err).Warnf("Skipping file %s with unparseable 'to' version %q", path, edge.To)
+			return nil
+		}
+		minor := majorMinor(to)
+
+		r, ok := risks[edge.Name]
+		if !ok {
+			r = &risk{name: edge.Name, newest: map[string]*version.Version{}, fixed: sets.New[string]()}
+			risks[edge.Name] = r
+		}
+		r.url = edge.URL
+		if newest, ok := r.newest[minor]; !ok || to.GreaterThan(newest) {
+			r.newest[minor] = to
+		}
+		if edge.FixedIn != "" {
+			r.fixed.Insert(minor)
+		}
+
+		return nil
+	}); err != nil {
+		logrus.WithError(err).Fatal("cannot walk graph repository")
+	}
+
+	// releases caches the released versions per minor, nil when they cannot be read
+	releases := map[string][]*version.Version{}
+	for _, r := range risks {
+		for _, minor := range sets.List(sets.KeySet(r.newest)) {
+			if r.fixed.Has(minor) {
+				continue
+			}
+			shipped, ok := releases[minor]
+			if !ok {
+				shipped = released(o.graphRepositoryPath, minor)
+				releases[minor] = shipped
+			}
+			var newer int
+			for _, release := range shipped {
+				if release.GreaterThan(r.newest[minor]) {
+					newer++
+				}
+			}
+			if newer >= o.releases {
+				r.reasons = append(r.reasons, fmt.Sprintf("newest blocked %s is %d releases behind", r.newest[minor], newer))
+			}
+		}
+	}
+
+	if !o.skipJira {
+		jiraClient, err := o.jira.Client()
+		if err != nil {
+			logrus.WithError(err).Fatal("cannot create Jira client")
+		}
+
+		now := time.Now()
+		for _, r := range risks {
+			if err := ctx.Err(); err != nil {
+				exitcode.Fatal(exitcode.Interrupted, err, "cannot inspect impact statement cards")
+			}
+			if !strings.HasPrefix(r.url, graph.JiraBrowsePrefix) {
+				logrus.Warnf("%s: Blocked edge reference URL %s is not a Jira card", r.name, r.url)
+				continue
+			}
+			impactStatementCard := strings.TrimPrefix(r.url, graph.JiraBrowsePrefix)
+
+			logrus.Infof("%s: Obtaining impact statement card %s", r.name, impactStatementCard)
+			card, err := jiraClient.GetIssue(impactStatementCard)
+			if err != nil {
+				logrus.WithError(err).Errorf("%s: Cannot get issue %s", r.name, impactStatementCard)
+				continue
+			}
+
+			if inactive := now.Sub(time.Time(card.Fields.Updated)); inactive >= o.isrInactive {
+				r.reasons = append(r.reasons, fmt.Sprintf("%s has no activity for %s", card.Key, timefmt.Duration(inactive)))
+			}
+
+			if bugs := linkedBugs(card, o.bugProjects); len(bugs) > 0 && allResolved(bugs) {
+				r.reasons = append(r.reasons, fmt.Sprintf("all referenced bugs are VERIFIED or Closed (%s)", strings.Join(issueKeys(bugs), ",")))
+			}
+		}
+	}
+
+	var stale []*risk
+	for _, r := range risks {
+		if len(r.reasons) > 0 {
+			stale = append(stale, r)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].name < stale[j].name })
+
+	logrus.Infof("Found %d stale risk candidates out of %d risks", len(stale), len(risks))
+	tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	_, _ = tabw.Write([]byte("RISK\tURL\tREASONS\n"))
+	for _, r := range stale {
+		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\n", r.name, r.url, strings.Join(r.reasons, "; "))))
+	}
+	_ = tabw.Flush()
+}
+
+// released returns the versions of the minor (like "4.16") in its candidate channel in the graph
+// repository, which also lists versions of the previous minor. It returns nil when the channel cannot
+// be read, so that no risk of the minor is reported as behind.
+func released(repositoryPath, minor string) []*version.Version {
+	channel := "candidate-" + minor
+	inChannel, err := graph.ChannelVersions(repositoryPath, channel)
+	if err != nil {
+		logrus.WithError(err).Warnf("Cannot read the %s channel, not checking how far behind the %s risks are", channel, minor)
+		return nil
+	}
+	var versions []*version.Version
+	for _, v := range sets.List(inChannel) {
+		parsed, err := version.ParseGeneric(v)
+		if err != nil {
+			logrus.WithError(err).Warnf("Skipping unparseable version %q in the %s channel", v, channel)
+			continue
+		}
+		if majorMinor(parsed) == minor {
+			versions = append(versions, parsed)
+		}
+	}
+	return versions
+}
+
+// linkedBugs returns the bug cards directly linked to the given card
+func linkedBugs(card *jira.Issue, bugProjects flagutil.BugProjectsOptions) []*jira.Issue {
+	var bugs []*jira.Issue
+	for _, link := range card.Fields.IssueLinks {
+		for _, linked := range []*jira.Issue{link.OutwardIssue, link.InwardIssue} {
+			if linked != nil && bugProjects.IsBug(linked.Key) {
+				bugs = append(bugs, linked)
+			}
+		}
+	}
+	return bugs
+}
+
+func allResolved(bugs []*jira.Issue) bool {
+	for _, bug := range bugs {
+		if bug.Fields == nil || bug.Fields.Status == nil {
+			return false
+		}
+		if status := strings.ToUpper(bug.Fields.Status.Name); status != "VERIFIED" && status != "CLOSED" {
+			return false
+		}
+	}
+	return true
+}
+
+func issueKeys(issues []*jira.Issue) []string {
+	var keys []string
+	for _, issue := range issues {
+		keys = append(keys, issue.Key)
+	}
+	return keys
+}
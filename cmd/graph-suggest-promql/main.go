@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/promql"
+)
+
+// placeholder fills the fields of the draft that were not passed
+const placeholder = "TODO"
+
+type options struct {
+	impactStatementRequestCard string
+
+	risk    string
+	from    string
+	to      string
+	message string
+	output  string
+
+	jira flagutil.JiraOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.impactStatementRequestCard, "impact-statement-card", "", "Full JIRA ID of the answered impact statement request card")
+	fs.StringVar(&o.risk, "risk", "", "The identifier (name) of the risk in the draft (default: a placeholder)")
+	fs.StringVar(&o.from, "from", "", "Regular expression matching the versions from which the update is exposed to the risk, in the draft (default: a placeholder)")
+	fs.StringVar(&o.to, "to", "", "The version to which the update is exposed to the risk, in the draft (default: a placeholder)")
+	fs.StringVar(&o.message, "message", "", "The message describing the risk to cluster administrators, in the draft (default: a placeholder)")
+	fs.StringVar(&o.output, "output", "", "Write the draft blocked edge into this file instead of the standard output")
+
+	o.jira.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.impactStatementRequestCard == "" {
+		return fmt.Errorf("--impact-statement-card must be specified and nonempty")
+	}
+
+	if o.risk != "" && !graph.ValidRiskName(o.risk) {
+		return fmt.Errorf("--risk must be a CamelCase identifier")
+	}
+
+	return o.jira.Validate()
+}
+
+// orPlaceholder returns the value or the placeholder when it is empty
+func orPlaceholder(value string) string {
+	if value == "" {
+		return placeholder
+	}
+	return value
+}
+
+// draft renders the blocked edge with a header listing where its PromQL comes from
+func draft(edge graph.ConditionallyBlockedEdge, answered []string, suggestions []promql.Suggestion) ([]byte, error) {
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "# Draft blocked edge proposed from the impact statement in %s; review it before declaring the risk.\n", edge.URL)
+	_, _ = fmt.Fprintf(&buf, "# The PromQL matches the clusters matching ANY of the following; combine them with \"and\" where the risk needs all of them:\n")
+	for _, query := range answered {
+		_, _ = fmt.Fprintf(&buf, "#  - quoted in the impact statement: %s\n", strings.Join(strings.Fields(query), " "))
+	}
+	for _, suggestion := range suggestions {
+		_, _ = fmt.Fprintf(&buf, "#  - %s, mentioned in: %q\n", suggestion.Snippet.Name, suggestion.Evidence)
+	}
+	if edge.Name == placeholder || edge.From == placeholder || edge.To == placeholder || edge.Message == placeholder {
+		_, _ = fmt.Fprintf(&buf, "# Fill in the %s fields.\n", placeholder)
+	}
+
+	raw, err := graph.MarshalEdge(edge)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal blocked edge: %w", err)
+	}
+	buf.Write(raw)
+	return buf.Bytes(), nil
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph suggest-promql
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+
+	library, err := promql.LoadLibrary()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load PromQL library")
+	}
+
+	jiraClient, err := o.jira.Client()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create Jira client")
+	}
+
+	logrus.Infof("Obtaining the impact statement request card %s", o.impactStatementRequestCard)
+	isr, err := jiraClient.GetIssue(o.impactStatementRequestCard)
+	if err != nil {
+		exitcode.JiraFatal(err, "cannot get issue")
+	}
+
+	statement := blockerflow.ImpactStatement(isr)
+	answered := promql.Queries(statement)
+	suggestions := library.Suggest(statement)
+	if len(answered) == 0 && len(suggestions) == 0 {
+		logrus.Fatalf("Found neither PromQL nor any cluster property from the PromQL library in %s, the matchingRules must be written manually", isr.Key)
+	}
+
+	queries := append([]string{}, answered...)
+	for _, suggestion := range suggestions {
+		queries = append(queries, suggestion.Snippet.PromQL)
+	}
+
+	edge := graph.ConditionallyBlockedEdge{
+		To:            orPlaceholder(o.to),
+		From:          orPlaceholder(o.from),
+		URL:           graph.JiraBrowsePrefix + isr.Key,
+		Name:          orPlaceholder(o.risk),
+		Message:       orPlaceholder(o.message),
+		MatchingRules: []graph.PromQLRule{{Type: "PromQL", PromQL: graph.PromQLQuery{Query: promql.Combine(queries)}}},
+	}
+	raw, err := draft(edge, answered, suggestions)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot render the draft")
+	}
+
+	if o.output == "" {
+		_, _ = os.Stdout.Write(raw)
+		return
+	}
+	if err := os.WriteFile(o.output, raw, 0644); err != nil {
+		logrus.WithError(err).Fatal("cannot write the draft")
+	}
+	logrus.Infof("Draft blocked edge written to %s", o.output)
+}
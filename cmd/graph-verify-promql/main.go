@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/promql"
+)
+
+type options struct {
+	edge string
+
+	endpoint       string
+	tokenFile      string
+	clusterID      string
+	expectedLabels prowflagutil.Strings
+	samples        int
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	o := options{expectedLabels: prowflagutil.NewStrings()}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.edge, "edge", "", "The blocked edge file whose matchingRules to verify")
+	fs.StringVar(&o.endpoint, "endpoint", "", "The Prometheus-compatible endpoint to evaluate the PromQL against, like a Telemetry proxy or the Thanos Querier route of a test cluster")
+	fs.StringVar(&o.tokenFile, "token-file", "", "File holding a bearer token for the endpoint (optional)")
+	fs.StringVar(&o.clusterID, "cluster-id", "", "Evaluate the PromQL for the cluster with this ID by replacing the _id=\"\" matchers (needed for Telemetry, where every series carries the _id of its cluster)")
+	fs.Var(&o.expectedLabels, "expect-label", "A label every sample of the result must carry (can be passed multiple times)")
+	fs.IntVar(&o.samples, "samples", 10, "The maximum number of result samples to print for each rule")
+
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.edge == "" {
+		return fmt.Errorf("--edge must be specified and nonempty")
+	}
+
+	if o.endpoint == "" {
+		return fmt.Errorf("--endpoint must be specified and nonempty")
+	}
+
+	if o.samples < 0 {
+		return fmt.Errorf("--samples must not be negative")
+	}
+
+	return nil
+}
+
+// token returns the bearer token from --token-file, or an empty string when it is not set
+func (o *options) token() (string, error) {
+	if o.tokenFile == "" {
+		return "", nil
+	}
+	raw, err := os.ReadFile(o.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("cannot read token file: %w", err)
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota graph verify-promql
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	edge, err := graph.LoadEdge(o.edge)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read blocked edge")
+	}
+
+	if diagnostics := promql.CheckRules(edge.MatchingRules); len(diagnostics) > 0 {
+		for _, diagnostic := range diagnostics {
+			fmt.Printf("%s: %s\n", o.edge, diagnostic)
+		}
+		os.Exit(exitcode.ValidationFailed)
+	}
+
+	token, err := o.token()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load endpoint token")
+	}
+	client := promql.NewClient(o.endpoint, token)
+
+	failed := false
+	for i, rule := range edge.MatchingRules {
+		if rule.Type != "PromQL" {
+			logrus.Infof("Skipping matchingRules[%d] of type %s", i, rule.Type)
+			continue
+		}
+		query := rule.PromQL.Query
+		if o.clusterID != "" {
+			query = promql.ForCluster(query, o.clusterID)
+		}
+
+		fmt.Printf("matchingRules[%d]:\n", i)
+		result, err := client.Query(ctx, query)
+		if err != nil {
+			if ctx.Err() != nil {
+				exitcode.Fatal(exitcode.Error, ctx.Err(), "interrupted")
+			}
+			fmt.Printf("  evaluation failed: %v\n", err)
+			failed = true
+			continue
+		}
+		for _, warning := range result.Warnings {
+			fmt.Printf("  warning: %s\n", warning)
+		}
+		for j, sample := range result.Samples {
+			if j == o.samples {
+				fmt.Printf("  ... and %d more samples\n", len(result.Samples)-o.samples)
+				break
+			}
+			fmt.Printf("  %s\n", sample)
+		}
+		problems := promql.CheckResult(result, o.expectedLabels.Strings())
+		for _, problem := range problems {
+			fmt.Printf("  problem: %s\n", problem)
+		}
+		if len(problems) == 0 {
+			fmt.Printf("  ok\n")
+		}
+		failed = failed || len(problems) > 0
+	}
+
+	if failed {
+		os.Exit(exitcode.ValidationFailed)
+	}
+}
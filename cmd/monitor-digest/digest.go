@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/timefmt"
+)
+
+// change is a single card that entered, changed in or left a dashboard section since the previous snapshot
+type change struct {
+	Marker  string
+	Key     string
+	Summary string
+	Detail  string
+}
+
+// changes lists the changes in the section, the cards that left it last
+func changes(sec section) []change {
+	var all []change
+	for _, issue := range sec.Issues {
+		switch {
+		case issue.Change == "new":
+			all = append(all, change{Marker: "+", Key: issue.Key, Summary: issue.Summary, Detail: "new"})
+		case issue.Change != "" && issue.Previous != nil:
+			var details []string
+			if issue.Previous.Status != issue.Status {
+				details = append(details, fmt.Sprintf("status %s → %s", issue.Previous.Status, issue.Status))
+			}
+			if issue.Previous.Assignee != issue.Assignee {
+				details = append(details, fmt.Sprintf("assignee %s → %s", orNobody(issue.Previous.Assignee), orNobody(issue.Assignee)))
+			}
+			all = append(all, change{Marker: "*", Key: issue.Key, Summary: issue.Summary, Detail: strings.Join(details, ", ")})
+		}
+	}
+	for _, issue := range sec.Removed {
+		all = append(all, change{Marker: "-", Key: issue.Key, Summary: issue.Summary, Detail: "left the section"})
+	}
+	return all
+}
+
+func orNobody(assignee string) string {
+	if assignee == "" {
+		return "nobody"
+	}
+	return assignee
+}
+
+// funcs are the template functions shared by both digest formats
+func funcs(r report, timestamps timefmt.Formatter) map[string]any {
+	return map[string]any{
+		"browse":  func(key string) string { return graph.JiraBrowsePrefix + key },
+		"since":   func(t time.Time) string { return timestamps.Since(t, r.Now) },
+		"local":   func(t time.Time) string { return t.Local().Format(time.DateTime) },
+		"changes": changes,
+		"outstanding": func(requested *time.Time) string {
+			if requested == nil {
+				return ""
+			}
+			return blockerflow.FormatOutstanding(*requested, r.Now)
+		},
+		"cell": func(s string) string { return strings.ReplaceAll(s, "|", `\|`) },
+		"join": strings.Join,
+		"list": func(items ...string) []string { return items },
+	}
+}
+
+// writeDigest renders the report in the given format
+func writeDigest(w io.Writer, format string, r report, timestamps timefmt.Formatter) error {
+	switch format {
+	case formatMarkdown:
+		tmpl, err := template.New(format).Funcs(funcs(r, timestamps)).Parse(markdownTemplate)
+		if err != nil {
+			return fmt.Errorf("cannot parse digest template: %w", err)
+		}
+		return tmpl.Execute(w, r)
+	case formatHTML:
+		tmpl, err := htmltemplate.New(format).Funcs(funcs(r, timestamps)).Parse(htmlTemplate)
+		if err != nil {
+			return fmt.Errorf("cannot parse digest template: %w", err)
+		}
+		return tmpl.Execute(w, r)
+	}
+	return fmt.Errorf("unknown digest format %q", format)
+}
+
+const markdownTemplate = `# Upgrade blocker digest
+
+Generated from the dashboard snapshot taken {{ local .Now }}.
+
+## Recent changes
+{{ with .Previous }}
+Cards that entered, changed in or left the sections since the snapshot taken {{ local . }}:
+{{ range $.Sections }}{{ $title := .Title }}{{ with changes . }}
+**{{ $title }}**
+{{ range . }}
+- [{{ .Key }}]({{ browse .Key }}) {{ .Summary }} ({{ .Detail }})
+{{- end }}
+{{ end }}{{ end }}{{ else }}
+There is no previous snapshot to compare against.
+{{ end }}
+{{- range .Sections }}
+## {{ .Title }} ({{ .Count }})
+{{ if .Issues }}
+| ID | Summary | Component | Status | Assignee | Modified | Outstanding |
+| --- | --- | --- | --- | --- | --- | --- |
+{{- range .Issues }}
+| [{{ .Key }}]({{ browse .Key }}) | {{ cell .Summary }} | {{ cell .Component }} | {{ .Status }} | {{ .Assignee }} | {{ since .Updated }} | {{ outstanding .Requested }} |
+{{- end }}
+{{ else }}
+No JIRAs.
+{{ end }}{{ end }}
+## Unanswered impact statement requests by team
+{{ if .UnansweredByTeam }}
+| Team | Count | Oldest | Cards |
+| --- | --- | --- | --- |
+{{- range .UnansweredByTeam }}
+{{- $cards := .ImpactStatementRequests }}{{ if not $cards }}{{ $cards = .Bugs }}{{ end }}
+| {{ .Team }}{{ with .Name }} ({{ . }}){{ end }} | {{ .Count }} | {{ since .Oldest }} | {{ join $cards ", " }} |
+{{- end }}
+{{ else }}
+No unanswered requests.
+{{ end }}`
+
+// htmlTemplate is an email-ready page: styles are inlined because mail clients drop style sheets
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Upgrade blocker digest</title></head>
+<body style="font-family: sans-serif;">
+<h1>Upgrade blocker digest</h1>
+<p>Generated from the dashboard snapshot taken {{ local .Now }}.</p>
+<h2>Recent changes</h2>
+{{- with .Previous }}
+<p>Changes since the snapshot taken {{ local . }}: + (new), * (status or assignee changed), - (left the section).</p>
+{{- range $.Sections }}{{ $title := .Title }}{{ with changes . }}
+<h3>{{ $title }}</h3>
+<ul>
+{{- range . }}
+<li>{{ .Marker }} <a href="{{ browse .Key }}">{{ .Key }}</a> {{ .Summary }} ({{ .Detail }})</li>
+{{- end }}
+</ul>
+{{- end }}{{ end }}
+{{- else }}
+<p>There is no previous snapshot to compare against.</p>
+{{- end }}
+{{- range .Sections }}
+<h2>{{ .Title }} ({{ .Count }})</h2>
+{{- if .Issues }}
+<table style="border-collapse: collapse;">
+<tr>{{ range $title := list "ID" "Summary" "Component" "Status" "Assignee" "Modified" "Outstanding" }}<th style="border: 1px solid #ccc; padding: 4px 8px; background: #eee; text-align: left;">{{ $title }}</th>{{ end }}</tr>
+{{- range .Issues }}
+<tr>
+<td style="border: 1px solid #ccc; padding: 4px 8px;"><a href="{{ browse .Key }}">{{ .Key }}</a></td>
+<td style="border: 1px solid #ccc; padding: 4px 8px;">{{ .Summary }}</td>
+<td style="border: 1px solid #ccc; padding: 4px 8px;">{{ .Component }}</td>
+<td style="border: 1px solid #ccc; padding: 4px 8px;">{{ .Status }}</td>
+<td style="border: 1px solid #ccc; padding: 4px 8px;">{{ .Assignee }}</td>
+<td style="border: 1px solid #ccc; padding: 4px 8px;">{{ since .Updated }}</td>
+<td style="border: 1px solid #ccc; padding: 4px 8px;{{ if .Overdue }} color: #c00; font-weight: bold;{{ end }}">{{ outstanding .Requested }}</td>
+</tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No JIRAs.</p>
+{{- end }}
+{{- end }}
+<h2>Unanswered impact statement requests by team</h2>
+{{- if .UnansweredByTeam }}
+<table style="border-collapse: collapse;">
+<tr>{{ range $title := list "Team" "Count" "Oldest" "Cards" }}<th style="border: 1px solid #ccc; padding: 4px 8px; background: #eee; text-align: left;">{{ $title }}</th>{{ end }}</tr>
+{{- range .UnansweredByTeam }}
+<tr>
+<td style="border: 1px solid #ccc; padding: 4px 8px;">{{ .Team }}{{ with .Name }} ({{ . }}){{ end }}</td>
+<td style="border: 1px solid #ccc; padding: 4px 8px;">{{ .Count }}</td>
+<td style="border: 1px solid #ccc; padding: 4px 8px;">{{ since .Oldest }}</td>
+<td style="border: 1px solid #ccc; padding: 4px 8px;">
+{{- $cards := .ImpactStatementRequests }}{{ if not $cards }}{{ $cards = .Bugs }}{{ end }}
+{{- range $i, $card := $cards }}{{ if $i }}, {{ end }}<a href="{{ browse $card }}">{{ $card }}</a>{{ end -}}
+</td>
+</tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No unanswered requests.</p>
+{{- end }}
+</body>
+</html>
+`
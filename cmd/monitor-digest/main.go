@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/notify"
+	"github.com/petr-muller/ota/internal/timefmt"
+)
+
+const (
+	formatHTML     = "html"
+	formatMarkdown = "markdown"
+
+	// notifySource is the notification source of the digests; route it to email sinks in notify.yaml
+	notifySource = "digest"
+)
+
+type options struct {
+	format string
+	send   bool
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.format, "format", formatHTML, fmt.Sprintf("Digest format: %s or %s", formatHTML, formatMarkdown))
+	fs.BoolVar(&o.send, "send", false, fmt.Sprintf("Send the digest to the sinks that notify.yaml routes the %q source to (e.g. email sinks) instead of printing it", notifySource))
+
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.format != formatHTML && o.format != formatMarkdown {
+		return fmt.Errorf("--format must be either %s or %s", formatHTML, formatMarkdown)
+	}
+	return nil
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota monitor digest
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+
+	timestamps, err := timefmt.Load()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load display config")
+	}
+
+	r, err := latestReport()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot load the latest dashboard snapshot")
+	}
+	logrus.Infof("Rendering the dashboard stored with the snapshot taken %s", r.Now.Local().Format(time.DateTime))
+
+	var digest bytes.Buffer
+	if err := writeDigest(&digest, o.format, *r, timestamps); err != nil {
+		logrus.WithError(err).Fatal("cannot render the digest")
+	}
+
+	if !o.send {
+		_, _ = os.Stdout.Write(digest.Bytes())
+		return
+	}
+
+	notifier, err := notify.Load()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load notification config")
+	}
+	msg := notify.Message{
+		Source: notifySource,
+		Title:  fmt.Sprintf("Upgrade blocker digest %s", r.Now.Local().Format(time.DateOnly)),
+		Body:   digest.String(),
+		HTML:   o.format == formatHTML,
+	}
+	if err := notifier.Notify(msg); err != nil {
+		exitcode.Fatal(exitcode.Error, err, "cannot send the digest")
+	}
+	logrus.Info("Digest sent")
+}
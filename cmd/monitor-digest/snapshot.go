@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/petr-muller/ota/internal/config"
+)
+
+const (
+	// snapshotDirName is the directory in the ota data directory where monitor-jira-dashboard stores
+	// its snapshots
+	snapshotDirName   = "dashboard"
+	snapshotExtension = ".json"
+)
+
+// The types below decode the parts of the dashboard snapshots the digest renders; see the report
+// type of monitor-jira-dashboard
+
+type issue struct {
+	Key       string    `json:"key"`
+	Summary   string    `json:"summary"`
+	Component string    `json:"component"`
+	Status    string    `json:"status"`
+	Assignee  string    `json:"assignee"`
+	Updated   time.Time `json:"updated"`
+
+	ImpactStatementRequest string     `json:"impactStatementRequest,omitempty"`
+	Requested              *time.Time `json:"requested,omitempty"`
+	Overdue                bool       `json:"overdue,omitempty"`
+
+	Change   string        `json:"change,omitempty"`
+	Previous *removedIssue `json:"previous,omitempty"`
+}
+
+type removedIssue struct {
+	Key      string `json:"key"`
+	Summary  string `json:"summary"`
+	Status   string `json:"status"`
+	Assignee string `json:"assignee"`
+}
+
+type section struct {
+	Name    string         `json:"name"`
+	Title   string         `json:"title"`
+	Count   int            `json:"count"`
+	Issues  []issue        `json:"issues"`
+	Removed []removedIssue `json:"removed,omitempty"`
+}
+
+type teamSummary struct {
+	Team                    string    `json:"team"`
+	Name                    string    `json:"name,omitempty"`
+	Count                   int       `json:"count"`
+	Oldest                  time.Time `json:"oldest"`
+	Bugs                    []string  `json:"bugs"`
+	ImpactStatementRequests []string  `json:"impactStatementRequests"`
+}
+
+type report struct {
+	Now              time.Time     `json:"now"`
+	Sections         []section     `json:"sections"`
+	Previous         *time.Time    `json:"previous,omitempty"`
+	UnansweredByTeam []teamSummary `json:"unansweredByTeam"`
+}
+
+type snapshot struct {
+	Taken  time.Time `json:"taken"`
+	Report *report   `json:"report,omitempty"`
+}
+
+// latestReport returns the dashboard stored with the latest snapshot
+func latestReport() (*report, error) {
+	dataDir, err := config.OtaDataDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(dataDir, snapshotDirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("cannot read snapshot directory %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, snapshotExtension) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no dashboard snapshot is stored, run monitor-jira-dashboard first")
+	}
+	// the names sort chronologically
+	sort.Strings(names)
+	path := filepath.Join(dir, names[len(names)-1])
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s snapshot
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal snapshot %s: %w", path, err)
+	}
+	if s.Report == nil {
+		return nil, fmt.Errorf("the latest snapshot (taken %s) does not store the dashboard, run monitor-jira-dashboard first", s.Taken.Local().Format(time.DateTime))
+	}
+	return s.Report, nil
+}
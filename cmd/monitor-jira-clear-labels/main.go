@@ -4,38 +4,50 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"strings"
 
-	"github.com/andygrunwald/go-jira"
 	"github.com/sirupsen/logrus"
-	"k8s.io/apimachinery/pkg/util/sets"
 
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
 	"github.com/petr-muller/ota/internal/flagutil"
-	"github.com/petr-muller/ota/internal/updateblockers"
 )
 
 type options struct {
 	bugId int
 
-	jira flagutil.JiraOptions
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+
+	log flagutil.LogOptions
 }
 
 func gatherOptions() options {
 	var o options
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
 
-	fs.IntVar(&o.bugId, "bug", 0, "The numerical part of the OCPBUGS card to clear all UpgradeBlocker related labels from")
+	fs.IntVar(&o.bugId, "bug", 0, "The numerical part of the bug card (in the first --bug-project) to clear all UpgradeBlocker related labels from")
 
 	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
 	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
 
 	return o
 }
 
 func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
 	if o.bugId == 0 {
 		return fmt.Errorf("--bug must be specified and nonzero")
 	}
@@ -47,34 +59,29 @@ func main() {
 	// TODO(muller): Cobrify as ota monitor jira clear-upgradeblocker-labels
 	o := gatherOptions()
 	if err := o.validate(); err != nil {
-		logrus.WithError(err).Fatal("invalid options")
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
 	}
+	o.log.Apply()
 
 	jiraClient, err := o.jira.Client()
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot create Jira client")
 	}
 
-	ocpbugsId := fmt.Sprintf("OCPBUGS-%d", o.bugId)
-	logrus.Infof("Obtaining issue %s", ocpbugsId)
-
-	blockerCandidate, err := jiraClient.GetIssue(ocpbugsId)
+	flow, err := blockerflow.NewFlow(jiraClient)
 	if err != nil {
-		logrus.WithError(err).Fatal("cannot get issue")
+		exitcode.Fatal(exitcode.Config, err, "cannot initialize blocker workflow")
 	}
+	flow.Visibility = o.jira.CommentVisibility(flow.Visibility)
+	flow.BugProjects = o.bugProjects.Projects()
 
-	// logrus.Infof("Adding an informative comment to %s card", blockerCandidate.Key)
-	// TODO(muller): Actually add a comment
-
-	toRemove := sets.New[string](updateblockers.LabelBlocker, updateblockers.LabelImpactStatementRequested, updateblockers.LabelImpactStatementProposed, updateblockers.LabelKnownIssueAnnounced)
-
-	logrus.Infof("Clearing OTA labels (%s) from %s card", strings.Join(sets.List(toRemove), ","), blockerCandidate.Key)
-	labels := sets.New[string](blockerCandidate.Fields.Labels...).Difference(toRemove)
+	logrus.Infof("Obtaining issue %s", o.bugProjects.BugKey(o.bugId))
+	bug, err := jiraClient.GetIssue(o.bugProjects.BugKey(o.bugId))
+	if err != nil {
+		exitcode.JiraFatal(err, "cannot get issue")
+	}
 
-	if _, err := jiraClient.UpdateIssue(&jira.Issue{
-		Key:    blockerCandidate.Key,
-		Fields: &jira.IssueFields{Labels: sets.List(labels)},
-	}); err != nil {
-		logrus.WithError(err).Fatal("cannot update issue")
+	if err := flow.ClearLabels(&blockerflow.Candidate{Bug: bug}); err != nil {
+		logrus.WithError(err).Fatal("cannot clear labels")
 	}
 }
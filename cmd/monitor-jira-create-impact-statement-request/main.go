@@ -1,183 +1,356 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/andygrunwald/go-jira"
 	"github.com/sirupsen/logrus"
-	"k8s.io/apimachinery/pkg/util/sets"
+	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
 
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
 	"github.com/petr-muller/ota/internal/flagutil"
-	"github.com/petr-muller/ota/internal/updateblockers"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jiratui"
+	"github.com/petr-muller/ota/internal/jirautil"
 )
 
 type options struct {
-	bugId            int
-	componentProject string // TODO(muller): Infer automatically
-
-	jira flagutil.JiraOptions
+	bugs             prowflagutil.Strings
+	jql              string
+	filter           string
+	componentProject string
+	epic             string
+	template         string
+	force            bool
+	yes              bool
+
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+	notify      flagutil.NotifyOptions
+
+	log flagutil.LogOptions
 }
 
 func gatherOptions() options {
 	var o options
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
 
-	fs.IntVar(&o.bugId, "bug", 0, "The numerical part of the OCPBUGS card to create the impact statement request for")
-	fs.StringVar(&o.componentProject, "for", "", "The project of the component to create the impact statement request for")
+	fs.Var(&o.bugs, "bug", "The numerical part of the bug card (in the first --bug-project) to create the impact statement request for (can be passed multiple times)")
+	fs.StringVar(&o.jql, "jql", "", "Create impact statement requests for all bugs matching this JQL query (JQL fragments are expanded)")
+	fs.StringVar(&o.filter, "filter", "", "Create impact statement requests for all bugs matching this saved Jira filter (ID or name)")
+	fs.StringVar(&o.componentProject, "for", "", "The project of the component to create the impact statement request for (in batch mode, offered for each bug; when omitted, the project of the earlier requests for the bug's component is offered)")
+	fs.StringVar(&o.epic, "epic", "", "Link the impact statement requests to this epic, like the tracking epic of the component team (default: the epic of the project in epics.yaml in the config directory; remembered there for projects without one)")
+	fs.BoolVar(&o.force, "force", false, "Create the impact statement request even when the bug is not labeled for one or already has one")
+	fs.BoolVar(&o.yes, "yes", false, "Do not ask for confirmation before creating each impact statement request in batch mode and assume the --for project (for non-interactive use)")
+	fs.StringVar(&o.template, "template", "", "Path to a template of the impact statement request description (default: impact-statement-request.tmpl in the config directory, or the built-in one)")
 
 	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.notify.AddFlags(fs)
+	o.log.AddFlags(fs)
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
 	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
 
 	return o
 }
 
 func (o *options) validate() error {
-	if o.bugId == 0 {
-		return fmt.Errorf("--bug must be specified and nonzero")
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	sources := 0
+	for _, set := range []bool{len(o.bugs.Strings()) > 0, o.jql != "", o.filter != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one of --bug, --jql or --filter must be specified")
 	}
 
-	if o.componentProject == "" {
-		return fmt.Errorf("--for must be specified and nonempty")
+	for _, bug := range o.bugs.Strings() {
+		if id, err := strconv.Atoi(bug); err != nil || id <= 0 {
+			return fmt.Errorf("--bug must be a positive number, not %q", bug)
+		}
+	}
+
+	if o.componentProject == "" && !o.batch() && !jiratui.Interactive() {
+		return fmt.Errorf("--for must be specified and nonempty when not running in a terminal")
+	}
+	if o.componentProject == "" && o.yes {
+		return fmt.Errorf("--yes requires --for")
+	}
+	if o.batch() && !o.yes && !jiratui.Interactive() {
+		return fmt.Errorf("batch mode asks to confirm each bug and needs a terminal; pass --for and --yes to run it non-interactively")
 	}
 
 	return o.jira.Validate()
 }
 
-func main() {
-	// TODO(muller): Cobrify as ota monitor jira create-impact-statement-request
-	o := gatherOptions()
-	if err := o.validate(); err != nil {
-		logrus.WithError(err).Fatal("invalid options")
-	}
+// batch returns true when the impact statement requests may be created for more than one bug, in
+// which case the user is asked to confirm each of them
+func (o *options) batch() bool {
+	return len(o.bugs.Strings()) > 1 || o.jql != "" || o.filter != ""
+}
 
-	jiraClient, err := o.jira.Client()
+// query returns the JQL selecting the bugs for --jql or --filter
+func (o *options) query() (string, error) {
+	if o.filter != "" {
+		return fmt.Sprintf("filter = %q", o.filter), nil
+	}
+	fragments, err := config.LoadJQLFragments()
 	if err != nil {
-		logrus.WithError(err).Fatal("cannot create Jira client")
+		return "", fmt.Errorf("cannot load JQL fragments: %w", err)
 	}
+	return fragments.Expand(o.jql)
+}
 
-	ocpbugsId := fmt.Sprintf("OCPBUGS-%d", o.bugId)
-	logrus.Infof("Obtaining issue %s", ocpbugsId)
+// result is the outcome of requesting an impact statement for a single bug
+type result struct {
+	bug     string
+	outcome string
+	err     error
+}
 
-	blockerCandidate, err := jiraClient.GetIssue(ocpbugsId)
-	if err != nil {
-		logrus.WithError(err).Fatal("cannot get issue")
+// confirm asks the user whether to create the impact statement request for the bug and in which
+// project, offering the given one. It returns an empty project when the bug should be skipped.
+func confirm(bug *jira.Issue, project string) (string, error) {
+	var component string
+	if len(bug.Fields.Components) > 0 {
+		component = bug.Fields.Components[0].Name
 	}
+	question := fmt.Sprintf("%s %s (%s)\nProject to create the impact statement request in (empty to skip):", bug.Key, bug.Fields.Summary, component)
+	answer, err := jiratui.Ask(question, project)
+	return strings.TrimSpace(answer), err
+}
 
-	// TODO(muller): Validate whether it is a valid recipient for the impact statement request (labels, existence of impact statement, etc.)
-
-	assignee := blockerCandidate.Fields.Assignee
-	if assignee == nil {
-		logrus.Warnf("Issue %s has no assignee", ocpbugsId)
-	} else {
-		logrus.Infof("Issue %s is assigned to %s", ocpbugsId, assignee.Name)
+// request requests the impact statement for the candidate in the project. When the project does not
+// exist or does not accept the impact statement request card, the error lists what the project
+// accepts and, if prompt is set, the user is asked for another project or priority instead. It
+// returns the project the request was created in.
+func request(flow *blockerflow.Flow, candidate *blockerflow.Candidate, project string, force, prompt bool) (string, error) {
+	for {
+		err := flow.RequestImpactStatement(candidate, project, force)
+		var invalid *jirautil.InvalidValueError
+		switch {
+		case err == nil || !prompt:
+			return project, err
+		case errors.As(err, &invalid) && invalid.Field == "priority":
+			priority, askErr := jiratui.Ask(fmt.Sprintf("%v\nPriority of the impact statement request (empty to give up):", err), "")
+			if priority = strings.TrimSpace(priority); askErr != nil || priority == "" {
+				return project, err
+			}
+			flow.Priority = priority
+		case errors.As(err, &invalid) || errors.Is(err, jirautil.ErrNoSuchProject):
+			answer, askErr := jiratui.Ask(fmt.Sprintf("%v\nProject to create the impact statement request in (empty to give up):", err), project)
+			if answer = strings.TrimSpace(answer); askErr != nil || answer == "" {
+				return project, err
+			}
+			project = answer
+		default:
+			return project, err
+		}
 	}
+}
 
-	impactStatementRequest := jira.Issue{
-		Fields: &jira.IssueFields{
-			Type:        jira.IssueType{Name: "Spike"},
-			Project:     jira.Project{Key: o.componentProject},
-			Priority:    &jira.Priority{Name: "Critical"},
-			Labels:      []string{updateblockers.LabelBlocker},
-			Description: fmt.Sprintf(descriptionTemplate, ocpbugsId, ocpbugsId),
-			Summary:     fmt.Sprintf("Impact statement request for %s %s", ocpbugsId, blockerCandidate.Fields.Summary),
-		},
+// rememberEpic saves the --epic as the epic of the project when the project has no epic yet, so that
+// the later impact statement requests in the project are linked to it without --epic
+func rememberEpic(flow *blockerflow.Flow, project string) {
+	if flow.Epic == "" || flow.Epics.For(project) != "" {
+		return
+	}
+	if flow.Epics == nil {
+		flow.Epics = config.Epics{}
 	}
-	if assignee != nil {
-		impactStatementRequest.Fields.Assignee = assignee
+	flow.Epics[strings.ToUpper(project)] = flow.Epic
+	if err := flow.Epics.Save(); err != nil {
+		logrus.WithError(err).Warnf("Cannot remember epic %s for project %s", flow.Epic, project)
+		return
 	}
+	logrus.Infof("Remembered epic %s for project %s", flow.Epic, project)
+}
+
+// suggester offers the project to create the impact statement request in: --for when set, otherwise
+// the project inferred for the bug's component, remembered for the other bugs in the component
+type suggester struct {
+	project     string
+	client      jirautil.Searcher
+	bugProjects []string
 
-	logrus.Infof("Creating impact statement request Spike card in %s project", o.componentProject)
-	isrIssue, err := jiraClient.CreateIssue(&impactStatementRequest)
+	components map[string]string
+}
+
+func (s *suggester) suggest(ctx context.Context, bug *jira.Issue) string {
+	if s.project != "" || len(bug.Fields.Components) == 0 {
+		return s.project
+	}
+	component := bug.Fields.Components[0].Name
+	if project, ok := s.components[component]; ok {
+		return project
+	}
+	project, err := blockerflow.SuggestComponentProject(ctx, s.client, bug, s.bugProjects)
 	if err != nil {
-		logrus.WithError(err).Fatal("cannot create impact statement request")
+		logrus.WithError(err).Warnf("Cannot infer the project of the %s component", component)
 	}
+	s.components[component] = project
+	return project
+}
 
-	logrus.Infof("Creating a '%s blocks %s' link between the cards", isrIssue.Key, blockerCandidate.Key)
-	blockLink := jira.IssueLink{
-		OutwardIssue: &jira.Issue{ID: blockerCandidate.ID},
-		InwardIssue:  &jira.Issue{ID: isrIssue.ID},
-		Type: jira.IssueLinkType{
-			Name:    "Blocks",
-			Inward:  "is blocked by",
-			Outward: "blocks",
-		},
+func main() {
+	// TODO(muller): Cobrify as ota monitor jira create-impact-statement-request
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
 	}
+	o.log.Apply()
+	ctx := interrupt.Context()
 
-	if err := jiraClient.CreateIssueLink(&blockLink); err != nil {
-		logrus.WithError(err).Fatal("cannot create issue link")
+	jiraClient, err := o.jira.Client()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create Jira client")
 	}
 
-	logrus.Infof("Adding an informative comment to %s card", blockerCandidate.Key)
-	var assigneeComment string
-	if assignee != nil {
-		assigneeComment = fmt.Sprintf(" and assigned it to [~%s] (this card's assignee)", assignee.Name)
+	flow, err := blockerflow.NewFlow(jiraClient)
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot initialize blocker workflow")
 	}
-	commentBody := fmt.Sprintf(
-		"This card has been labeled as a potential upgrade risk with an {{UpgradeBlock}} label. We have created a card %s to help us understand the impact of the bug so that we can warn exposed cluster owners about it before they upgrade to an affected OCP version%s. The card simply asks for answers to several questions and should not require too much time to answer.",
-		isrIssue.Key, assigneeComment,
-	)
-
-	candidateBugComment := &jira.Comment{
-		Author: jira.User{
-			Name: "afri@afri.cz", // TODO(muller): Use the user associated with the Jira client
-		},
-		Body:       commentBody,
-		Visibility: jira.CommentVisibility{}, // TODO(muller): Use employee visibility
+	flow.Visibility = o.jira.CommentVisibility(flow.Visibility)
+	flow.BugProjects = o.bugProjects.Projects()
+	flow.Epic = o.epic
+	if flow.Notifier, err = o.notify.Notifier(); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load notification config")
 	}
-
-	if _, err := jiraClient.AddComment(blockerCandidate.ID, candidateBugComment); err != nil {
-		logrus.WithError(err).Fatal("cannot create comment")
+	if o.template != "" {
+		if flow.DescriptionTemplate, err = blockerflow.LoadDescriptionTemplate(o.template); err != nil {
+			exitcode.Fatal(exitcode.Config, err, "cannot load description template")
+		}
 	}
 
-	logrus.Infof("Adding the ImpactStatementRequested label to %s card", blockerCandidate.Key)
+	var bugs []jira.Issue
+	if len(o.bugs.Strings()) > 0 {
+		var keys []string
+		for _, id := range o.bugs.Strings() {
+			bugId, _ := strconv.Atoi(id)
+			keys = append(keys, o.bugProjects.BugKey(bugId))
+		}
+		logrus.Infof("Obtaining issues %s", strings.Join(keys, ", "))
+		issues, err := jirautil.DefaultFetcher.GetIssues(ctx, jiraClient, keys)
+		if err != nil {
+			exitcode.JiraFatal(err, "cannot get issue")
+		}
+		for _, bug := range issues {
+			bugs = append(bugs, *bug)
+		}
+	} else {
+		query, err := o.query()
+		if err != nil {
+			logrus.WithError(err).Fatal("cannot build the query")
+		}
+		logrus.Infof("Obtaining bugs matching %s", query)
+		if bugs, err = jirautil.SearchAll(ctx, jiraClient, query); err != nil {
+			exitcode.JiraFatal(err, "Failed to query JIRA")
+		}
+	}
 
-	labels := sets.New[string](blockerCandidate.Fields.Labels...)
-	labels.Insert(updateblockers.LabelImpactStatementRequested)
-	labels.Insert(updateblockers.LabelBlocker)
+	prompt := !o.yes && jiratui.Interactive()
+	projects := &suggester{project: o.componentProject, client: jiraClient, bugProjects: flow.BugProjects, components: map[string]string{}}
+
+	if !o.batch() {
+		project := o.componentProject
+		if project == "" {
+			if project, err = confirm(&bugs[0], projects.suggest(ctx, &bugs[0])); err != nil {
+				logrus.WithError(err).Fatal("cannot ask for the project to create the impact statement request in")
+			}
+			if project == "" {
+				logrus.Infof("Not creating an impact statement request for %s", bugs[0].Key)
+				return
+			}
+		}
+		if project, err = request(flow, &blockerflow.Candidate{Bug: &bugs[0]}, project, o.force, prompt); err != nil {
+			logrus.WithError(err).Fatal("cannot request impact statement")
+		}
+		rememberEpic(flow, project)
+		return
+	}
 
-	if _, err := jiraClient.UpdateIssue(&jira.Issue{
-		Key:    blockerCandidate.Key,
-		Fields: &jira.IssueFields{Labels: sets.List(labels)},
-	}); err != nil {
-		logrus.WithError(err).Fatal("cannot update issue")
+	var results []result
+	var created, failed int
+	var interrupted bool
+	for i := range bugs {
+		if err := ctx.Err(); err != nil {
+			for _, rest := range bugs[i:] {
+				results = append(results, result{bug: rest.Key, outcome: "skipped", err: err})
+			}
+			interrupted = true
+			break
+		}
+		bug := &bugs[i]
+		candidate := &blockerflow.Candidate{Bug: bug}
+		if err := flow.CheckImpactStatementRequest(candidate); err != nil && !o.force {
+			results = append(results, result{bug: bug.Key, outcome: "skipped", err: err})
+			continue
+		}
+
+		project := o.componentProject
+		if !o.yes {
+			if project, err = confirm(bug, projects.suggest(ctx, bug)); err != nil {
+				logrus.WithError(err).Warn("Not asking about the remaining bugs")
+				for _, rest := range bugs[i:] {
+					results = append(results, result{bug: rest.Key, outcome: "skipped"})
+				}
+				break
+			}
+			if project == "" {
+				results = append(results, result{bug: bug.Key, outcome: "skipped"})
+				continue
+			}
+		}
+
+		if project, err = request(flow, candidate, project, o.force, prompt); err != nil {
+			logrus.WithError(err).Errorf("%s: cannot request impact statement", bug.Key)
+			results = append(results, result{bug: bug.Key, outcome: "failed", err: err})
+			failed++
+			continue
+		}
+		rememberEpic(flow, project)
+		results = append(results, result{bug: bug.Key, outcome: "created " + candidate.ImpactStatementRequest.Key + " in " + project})
+		created++
 	}
 
+	fmt.Println()
+	tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	_, _ = tabw.Write([]byte("BUG\tRESULT\tDETAIL\n"))
+	for _, r := range results {
+		var detail string
+		if r.err != nil {
+			detail = r.err.Error()
+		}
+		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\n", r.bug, r.outcome, detail)))
+	}
+	_ = tabw.Flush()
+
+	switch {
+	case (failed > 0 || interrupted) && created > 0:
+		os.Exit(exitcode.PartialFailure)
+	case failed > 0:
+		os.Exit(exitcode.Error)
+	case interrupted:
+		os.Exit(exitcode.Interrupted)
+	}
 }
-
-var descriptionTemplate = `We're asking the following questions to evaluate whether or not %s warrants changing update recommendations from either the previous X.Y or X.Y.Z. The ultimate goal is to avoid recommending an update which introduces new risk or reduces cluster functionality in any way. In the absence of a declared update risk (the status quo), there is some risk that the existing fleet updates into the at-risk releases. Depending on the bug and estimated risk, leaving the update risk undeclared may be acceptable.
-
-Sample answers are provided to give more context and the {{ImpactStatementRequested}} label has been added to %s. When responding, please move this ticket to {{{}Code Review{}}}. The expectation is that the assignee answers these questions.
-
-h2. Which 4.y.z to 4.y'.z' updates increase vulnerability?
- * reasoning: This allows us to populate [{{from}} and {{to}} in conditional update recommendations|https://github.com/openshift/cincinnati-graph-data/tree/0335e56cde6b17230106f137382cbbd9aa5038ed#block-edges] for "the {{$SOURCE_RELEASE}} to {{$TARGET_RELEASE}} update is exposed.
- * example: Customers upgrading from any 4.y (or specific 4.y.z) to 4.(y+1).z'. Use {{oc adm upgrade}} to show your current cluster version.
-
-h2. Which types of clusters?
- * reasoning: This allows us to populate [{{matchingRules}} in conditional update recommendations|https://github.com/openshift/cincinnati-graph-data/tree/0335e56cde6b17230106f137382cbbd9aa5038ed#block-edges] for "clusters like {{{}$THIS{}}}".
- * example: GCP clusters with thousands of namespaces, approximately 5%% of the subscribed fleet. Check your vulnerability with {{oc ...}} or the following PromQL {{{}count (...) > 0{}}}.
-
-The two questions above are sufficient to declare an initial update risk, and we would like as much detail as possible on them as quickly as you can get it. Perfectly crisp responses are nice, but are not required. For example "it seems like these platforms are involved, because..." in a day 1 draft impact statement is helpful, even if you follow up with "actually, it was these other platforms" on day 3. In the absence of a response within 7 days, we may or may not declare a conditional update risk based on our current understanding of the issue.
-
-If you can, answers to the following questions will make the conditional risk declaration more actionable for customers.
-
-h2. What is the impact? Is it serious enough to warrant removing update recommendations?
- * reasoning: This allows us to populate [{{name}} and {{message}} in conditional update recommendations|https://github.com/openshift/cincinnati-graph-data/tree/0335e56cde6b17230106f137382cbbd9aa5038ed#block-edges] for "...because if you update, {{$THESE_CONDITIONS}} may cause {{{}$THESE_UNFORTUNATE_SYMPTOMS{}}}".
- * example: Around 2 minute disruption in edge routing for 10%% of clusters. Check with {{{}oc ...{}}}.
- * example: Up to 90 seconds of API downtime. Check with {{{}curl ...{}}}.
- * example: etcd loses quorum and you have to restore from backup. Check with {{{}ssh ...{}}}.
-
-h2. How involved is remediation?
- * reasoning: This allows administrators who are already vulnerable, or who chose to waive conditional-update risks, to recover their cluster. And even moderately serious impacts might be acceptable if they are easy to mitigate.
- * example: Issue resolves itself after five minutes.
- * example: Admin can run a single: {{{}oc ...{}}}.
- * example: Admin must SSH to hosts, restore from backups, or other non standard admin activities.
-
-h2. Is this a regression?
- * reasoning: Updating between two vulnerable releases may not increase exposure (unless rebooting during the update increases vulnerability, etc.). We only qualify update recommendations if the update increases exposure.
- * example: No, it has always been like this we just never noticed.
- * example: Yes, from 4.y.z to 4.y+1.z Or 4.y.z to 4.y.z+1.`
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/timefmt"
+)
+
+// htmlFileName is the page written into the --html-dir directory
+const htmlFileName = "index.html"
+
+// writeHTML renders the dashboard into a self-contained static page in dir, so that it can be published
+// on a web server for people who do not run the tools
+func writeHTML(r report, timestamps timefmt.Formatter, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory %s: %w", dir, err)
+	}
+
+	funcs := template.FuncMap{
+		"browse":      func(key string) string { return graph.JiraBrowsePrefix + key },
+		"since":       func(t time.Time) string { return timestamps.Since(t, r.Now) },
+		"marker":      changeMarker,
+		"join":        strings.Join,
+		"local":       func(t time.Time) string { return t.Local().Format(time.DateTime) },
+		"outstanding": func(issue sectionIssue) string { return issue.outstanding(r.Now) },
+	}
+	page, err := template.New(htmlFileName).Funcs(funcs).Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("cannot parse page template: %w", err)
+	}
+
+	path := filepath.Join(dir, htmlFileName)
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create %s: %w", path, err)
+	}
+	if err := page.Execute(f, r); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot render %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// htmlTemplate is the dashboard page; the tables are sorted by clicking their headers, without any
+// resources loaded from elsewhere
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Upgrade blocker dashboard</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #eee; cursor: pointer; }
+tr.new { background: #e6ffe6; }
+tr.changed { background: #fff8e0; }
+tr.removed { color: #888; text-decoration: line-through; }
+td.overdue { color: #c00; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Upgrade blocker dashboard</h1>
+<p>Generated {{ local .Now }}.{{ with .Previous }} Changes since the snapshot taken {{ local . }} are marked with + (new), * (status or assignee changed) and - (removed).{{ end }}</p>
+{{ range .Sections }}
+{{- $requests := eq .Name "needImpactStatement" }}
+<h2>{{ .Title }} ({{ .Count }})</h2>
+<table class="sortable">
+<thead><tr><th></th><th>ID</th><th>Summary</th><th>Component</th><th>Status</th><th>Assignee</th><th>Modified</th><th>Affects</th>{{ if $requests }}<th>ISR</th><th>Outstanding</th>{{ end }}</tr></thead>
+<tbody>
+{{- range .Issues }}
+<tr class="{{ .Change }}"><td>{{ marker . }}</td><td><a href="{{ browse .Key }}">{{ .Key }}</a></td><td>{{ .Summary }}</td><td>{{ .Component }}</td><td>{{ .Status }}</td><td>{{ .Assignee }}</td><td data-sort="{{ .Updated.Unix }}">{{ since .Updated }}</td><td>{{ join .Affects ", " }}</td>
+{{- if $requests }}<td>{{ with .ImpactStatementRequest }}<a href="{{ browse . }}">{{ . }}</a>{{ end }}</td><td{{ if .Overdue }} class="overdue"{{ end }} data-sort="{{ .DaysOutstanding }}">{{ outstanding . }}</td>{{ end }}</tr>
+{{- end }}
+{{- range .Removed }}
+<tr class="removed"><td>-</td><td><a href="{{ browse .Key }}">{{ .Key }}</a></td><td>{{ .Summary }}</td><td></td><td>{{ .Status }}</td><td>{{ .Assignee }}</td><td></td><td></td>{{ if $requests }}<td></td><td></td>{{ end }}</tr>
+{{- end }}
+</tbody>
+</table>
+{{ end }}
+<h2>Unanswered impact statement requests by team</h2>
+<table class="sortable">
+<thead><tr><th>Team</th><th>Count</th><th>Oldest</th><th>Cards</th></tr></thead>
+<tbody>
+{{- range .UnansweredByTeam }}
+<tr><td>{{ .Team }}{{ with .Name }} ({{ . }}){{ end }}</td><td>{{ .Count }}</td><td data-sort="{{ .Oldest.Unix }}">{{ since .Oldest }}</td><td>
+{{- $cards := .ImpactStatementRequests }}{{ if not $cards }}{{ $cards = .Bugs }}{{ end }}
+{{- range $i, $card := $cards }}{{ if $i }}, {{ end }}<a href="{{ browse $card }}">{{ $card }}</a>{{ end -}}
+</td></tr>
+{{- end }}
+</tbody>
+</table>
+<script>
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var body = th.closest("table").tBodies[0];
+    var column = th.cellIndex;
+    var ascending = th.dataset.order !== "asc";
+    th.dataset.order = ascending ? "asc" : "desc";
+    var value = function (row) {
+      var cell = row.cells[column];
+      return cell.dataset.sort !== undefined ? cell.dataset.sort : cell.textContent.trim();
+    };
+    Array.from(body.rows).sort(function (a, b) {
+      var x = value(a), y = value(b);
+      var order = (x !== "" && y !== "" && !isNaN(x) && !isNaN(y)) ? x - y : x.localeCompare(y);
+      return ascending ? order : -order;
+    }).forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`
@@ -1,52 +1,264 @@
 package main
 
 import (
-	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"text/tabwriter"
 	"time"
 
+	"github.com/andygrunwald/go-jira"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
 	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/notify"
+	"github.com/petr-muller/ota/internal/timefmt"
 )
 
 const (
-	jqlNeedImpactStatementRequest = "project = OCPBUGS AND labels in (UpgradeBlocker) AND labels not in (ImpactStatementRequested, ImpactStatementProposed, UpdateRecommendationsBlocked)"
-	jqlNeedImpactStatement        = "project = OCPBUGS AND labels in (UpgradeBlocker) AND labels in (ImpactStatementRequested)"
-	jqlHaveImpactStatement        = "project = OCPBUGS AND labels in (ImpactStatementProposed)"
+	jqlNeedImpactStatementRequest = "{{frag:upgrade_blockers}} AND labels not in (ImpactStatementRequested, ImpactStatementProposed, UpdateRecommendationsBlocked)"
+	jqlNeedImpactStatement        = "{{frag:upgrade_blockers}} AND labels in (ImpactStatementRequested)"
+	jqlHaveImpactStatement        = "{{frag:ocpbugs}} AND labels in (ImpactStatementProposed)"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputHTML  = "html"
 )
 
 type options struct {
-	jira flagutil.JiraOptions
+	output            string
+	htmlDir           string
+	since             string
+	snapshotRetention time.Duration
+	interactive       bool
+	notify            bool
+	exitCode          bool
+	offline           bool
+	overdueOnly       bool
+
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+
+	log flagutil.LogOptions
 }
 
 func gatherOptions() options {
 	var o options
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.output, "output", outputTable, fmt.Sprintf("Output format: %s, %s, %s or %s", outputTable, outputJSON, outputYAML, outputHTML))
+	fs.StringVar(&o.htmlDir, "html-dir", "", fmt.Sprintf("Directory to write the static page into with --output=%s", outputHTML))
+	fs.BoolVar(&o.interactive, "interactive", false, "Browse the sections in an interactive terminal UI")
+	fs.BoolVar(&o.exitCode, "exit-code", false, fmt.Sprintf("Exit with %d when any card entered or left a section since the previous snapshot", exitcode.ChangesDetected))
+	fs.BoolVar(&o.notify, "notify", false, "Send a notification about the changes since the previous snapshot to the sinks configured in notify.yaml")
+	fs.StringVar(&o.since, "since", "", "Highlight changes since this snapshot: a path to a stored snapshot or a duration selecting the latest snapshot at least that old (default: the previous run)")
+	fs.BoolVar(&o.offline, "offline", false, "Render the dashboard stored with the latest snapshot without querying Jira")
+	fs.BoolVar(&o.overdueOnly, "overdue-only", false, fmt.Sprintf("Only show the bugs whose impact statement request is waiting for an answer for more than %s", timefmt.Duration(blockerflow.ImpactStatementSLA)))
+	fs.DurationVar(&o.snapshotRetention, "snapshot-retention", 30*24*time.Hour, "Remove stored snapshots older than this after saving a new one (0 keeps all snapshots)")
 
 	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
 	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
 
 	return o
 }
 
 func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	switch o.output {
+	case outputTable, outputJSON, outputYAML, outputHTML:
+	default:
+		return fmt.Errorf("--output must be one of %s, %s, %s, %s", outputTable, outputJSON, outputYAML, outputHTML)
+	}
+
+	if (o.output == outputHTML) != (o.htmlDir != "") {
+		return fmt.Errorf("--html-dir must be set exactly when --output=%s", outputHTML)
+	}
+
+	if o.interactive && o.output != outputTable {
+		return fmt.Errorf("--interactive cannot be combined with --output=%s", o.output)
+	}
+
+	if o.interactive && o.notify {
+		return fmt.Errorf("--interactive cannot be combined with --notify")
+	}
+
+	if o.offline {
+		if o.interactive || o.notify || o.since != "" {
+			return fmt.Errorf("--offline cannot be combined with --interactive, --notify or --since")
+		}
+		return nil
+	}
+
 	return o.jira.Validate()
 }
 
+// section is a group of cards in the same stage of the workflow
+type section struct {
+	Name   string         `json:"name"`
+	Title  string         `json:"title"`
+	Query  string         `json:"query"`
+	Count  int            `json:"count"`
+	Issues []sectionIssue `json:"issues"`
+	// Removed holds the cards that were in the section in the previous snapshot but are no longer
+	Removed []snapshotIssue `json:"removed,omitempty"`
+
+	description string
+}
+
+type sectionIssue struct {
+	Key       string    `json:"key"`
+	Summary   string    `json:"summary"`
+	Component string    `json:"component"`
+	Status    string    `json:"status"`
+	Assignee  string    `json:"assignee"`
+	Updated   time.Time `json:"updated"`
+	Affects   []string  `json:"affects"`
+
+	// ImpactStatementRequest is the key of the linked ISR card of a bug waiting for an impact statement
+	ImpactStatementRequest string `json:"impactStatementRequest,omitempty"`
+	// Requested is when the ISR card was created; DaysOutstanding and Overdue are computed from it
+	Requested       *time.Time `json:"requested,omitempty"`
+	DaysOutstanding int        `json:"daysOutstanding,omitempty"`
+	Overdue         bool       `json:"overdue,omitempty"`
+
+	// Change is "new" or "changed" when the card differs from the previous snapshot
+	Change   string         `json:"change,omitempty"`
+	Previous *snapshotIssue `json:"previous,omitempty"`
+
+	Issue jira.Issue `json:"issue"`
+}
+
+// report is the machine-readable dashboard; "now" is the time the ages of the cards are computed against
+type report struct {
+	Now      time.Time `json:"now"`
+	Sections []section `json:"sections"`
+	// Previous is the time of the snapshot the changes are highlighted against
+	Previous *time.Time `json:"previous,omitempty"`
+
+	// UnansweredByTeam pivots the bugs waiting for an impact statement by the team asked to provide it
+	UnansweredByTeam []teamSummary `json:"unansweredByTeam"`
+}
+
+func (r report) section(name string) section {
+	for _, sec := range r.Sections {
+		if sec.Name == name {
+			return sec
+		}
+	}
+	return section{}
+}
+
+// overdue returns a copy of the report with only the bugs whose impact statement request is overdue;
+// sections without impact statement requests are left out
+func (r report) overdue() report {
+	filtered := r
+	filtered.Sections = nil
+	for _, sec := range r.Sections {
+		if !sec.tracksRequests() {
+			continue
+		}
+		issues := []sectionIssue{}
+		for _, issue := range sec.Issues {
+			if issue.Overdue {
+				issues = append(issues, issue)
+			}
+		}
+		sec.Issues, sec.Count, sec.Removed = issues, len(issues), nil
+		filtered.Sections = append(filtered.Sections, sec)
+	}
+	return filtered
+}
+
+// tracksRequests returns true for the section with the bugs waiting for an answer to their impact
+// statement requests
+func (sec section) tracksRequests() bool {
+	return sec.Name == "needImpactStatement"
+}
+
+// sections returns the dashboard sections with their (unexpanded) queries
+func sections() []section {
+	return []section{
+		{Name: "needImpactStatementRequest", Title: "JIRAs that need an impact statement request", Query: jqlNeedImpactStatementRequest, description: "need an impact statement request"},
+		// TODO(muller): Show impact statement card and whether it changed
+		{Name: "needImpactStatement", Title: "JIRAs that wait for a developer to provide an impact statement", Query: jqlNeedImpactStatement, description: "wait for an impact statement"},
+		{Name: "haveImpactStatement", Title: "JIRAs where a developer proposed an impact statement", Query: jqlHaveImpactStatement, description: "have a proposed impact statement"},
+	}
+}
+
+func newSectionIssue(issue jira.Issue) sectionIssue {
+	var affects []string
+	for _, version := range issue.Fields.AffectsVersions {
+		affects = append(affects, version.Name)
+	}
+	var component, status, assignee string
+	if len(issue.Fields.Components) > 0 {
+		component = issue.Fields.Components[0].Name
+	}
+	if issue.Fields.Status != nil {
+		status = issue.Fields.Status.Name
+	}
+	if issue.Fields.Assignee != nil {
+		assignee = issue.Fields.Assignee.Name
+	}
+	return sectionIssue{
+		Key:       issue.Key,
+		Summary:   issue.Fields.Summary,
+		Component: component,
+		Status:    status,
+		Assignee:  assignee,
+		Updated:   time.Time(issue.Fields.Updated),
+		Affects:   affects,
+		Issue:     issue,
+	}
+}
+
 func main() {
 	// TODO(muller): Cobrify as ota monitor dashboard
 
 	o := gatherOptions()
 	if err := o.validate(); err != nil {
-		logrus.WithError(err).Fatal("invalid options")
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	timestamps, err := timefmt.Load()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load display config")
+	}
+
+	if o.offline {
+		r, err := latestReport()
+		if err != nil {
+			logrus.WithError(err).Fatal("cannot load the latest dashboard snapshot")
+		}
+		logrus.Infof("Showing the dashboard stored with the snapshot taken %s", r.Now.Local().Format(time.DateTime))
+		render(o, *r, timestamps)
+		return
 	}
 
 	jiraClient, err := o.jira.Client()
@@ -54,75 +266,217 @@ func main() {
 		logrus.WithError(err).Fatal("cannot create Jira client")
 	}
 
-	now := time.Now()
+	fragments, err := config.LoadJQLFragments()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load JQL fragments")
+	}
+
+	r := report{Now: time.Now(), Sections: sections()}
+	for i := range r.Sections {
+		sec := &r.Sections[i]
+		if sec.Query, err = fragments.Expand(sec.Query); err != nil {
+			logrus.WithError(err).Fatal("cannot expand JQL fragments")
+		}
+	}
+
+	if o.interactive {
+		flow, err := blockerflow.NewFlow(jiraClient)
+		if err != nil {
+			exitcode.Fatal(exitcode.Config, err, "cannot initialize blocker workflow")
+		}
+		flow.Visibility = o.jira.CommentVisibility(flow.Visibility)
+		flow.BugProjects = o.bugProjects.Projects()
+
+		if err := runInteractive(jiraClient, flow, r.Sections, timestamps, o.overdueOnly); err != nil {
+			logrus.WithError(err).Fatal("interactive dashboard failed")
+		}
+		return
+	}
+
+	var queries []string
+	for _, sec := range r.Sections {
+		logrus.Infof("Obtaining JIRAs that %s", sec.description)
+		queries = append(queries, sec.Query)
+	}
+	results, err := jirautil.DefaultFetcher.SearchAll(ctx, jiraClient, queries)
+	if err != nil {
+		exitcode.JiraFatal(err, "Failed to query JIRA")
+	}
+	for i, issues := range results {
+		sec := &r.Sections[i]
+		sec.Issues = []sectionIssue{}
+		for _, issue := range issues {
+			sec.Issues = append(sec.Issues, newSectionIssue(issue))
+		}
+		sec.Count = len(sec.Issues)
+	}
 
-	logrus.Infof("Obtaining JIRAs that need an impact statement request")
-	needImpactStatementRequest, _, err := jiraClient.SearchWithContext(context.Background(), jqlNeedImpactStatementRequest, nil)
+	logrus.Infof("Obtaining impact statement request cards waiting for an answer")
+	waiting := r.section("needImpactStatement").Issues
+	isrs, err := linkImpactStatementRequests(ctx, jiraClient, waiting, o.bugProjects.Projects(), r.Now)
 	if err != nil {
-		logrus.WithError(err).Fatal("Failed to query JIRA")
+		exitcode.JiraFatal(err, "Failed to query JIRA")
 	}
+	r.UnansweredByTeam = unansweredByTeam(waiting, isrs)
 
-	logrus.Infof("Obtaining JIRAs that wait for an impact statement")
-	needImpactStatement, _, err := jiraClient.SearchWithContext(context.Background(), jqlNeedImpactStatement, nil)
+	previous, err := findSnapshot(o.since, r.Now)
 	if err != nil {
-		logrus.WithError(err).Fatal("Failed to query JIRA")
+		logrus.WithError(err).Fatal("cannot load previous dashboard snapshot")
+	}
+	if previous != nil {
+		r.compare(previous)
+		if o.notify {
+			notifyChanges(r)
+		}
+	}
+	if err := saveSnapshot(newSnapshot(r)); err != nil {
+		logrus.WithError(err).Warn("Cannot save dashboard snapshot")
+	}
+	if o.snapshotRetention > 0 {
+		if err := pruneSnapshots(r.Now.Add(-o.snapshotRetention)); err != nil {
+			logrus.WithError(err).Warn("Cannot remove old dashboard snapshots")
+		}
+	}
+
+	render(o, r, timestamps)
+}
+
+// render writes the dashboard in the requested output format
+func render(o options, r report, timestamps timefmt.Formatter) {
+	if o.exitCode && r.changed() {
+		defer os.Exit(exitcode.ChangesDetected)
+	}
+	if o.overdueOnly {
+		r = r.overdue()
 	}
 
-	logrus.Infof("Obtaining JIRAs that have a proposed impact statement")
-	haveImpactStatement, _, err := jiraClient.SearchWithContext(context.Background(), jqlHaveImpactStatement, nil)
+	switch o.output {
+	case outputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r); err != nil {
+			logrus.WithError(err).Fatal("cannot encode dashboard")
+		}
+	case outputYAML:
+		// Go through JSON so that the Jira types (with JSON-only serialization) are rendered the same way
+		raw, err := json.Marshal(r)
+		if err != nil {
+			logrus.WithError(err).Fatal("cannot encode dashboard")
+		}
+		var generic any
+		if err := json.Unmarshal(raw, &generic); err != nil {
+			logrus.WithError(err).Fatal("cannot encode dashboard")
+		}
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(generic); err != nil {
+			logrus.WithError(err).Fatal("cannot encode dashboard")
+		}
+		_ = encoder.Close()
+	case outputHTML:
+		if err := writeHTML(r, timestamps, o.htmlDir); err != nil {
+			logrus.WithError(err).Fatal("cannot write dashboard page")
+		}
+		logrus.Infof("Dashboard page written to %s", filepath.Join(o.htmlDir, htmlFileName))
+	default:
+		// TODO(muller): Maybe show activity since last run somehow
+		if r.Previous != nil {
+			fmt.Printf("\nChanges since the snapshot taken %s are marked with + (new), * (status or assignee changed) and - (removed)\n", r.Previous.Local().Format(time.DateTime))
+		}
+		for _, sec := range r.Sections {
+			fmt.Printf("\n=== %s ===\n\n", sec.Title)
+			tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			header := "  ID\tSUMMARY\tCOMPONENT\tMODIFIED\tAFFECTS"
+			if sec.tracksRequests() {
+				header += "\tISR\tOUTSTANDING"
+			}
+			_, _ = tabw.Write([]byte(header + "\n"))
+			for _, issue := range sec.Issues {
+				sinceUpdated := timestamps.Since(issue.Updated, r.Now)
+				row := fmt.Sprintf("%s %s\t%s\t%s\t%s\t%s", changeMarker(issue), issue.Key, issue.Summary, issue.Component, sinceUpdated, strings.Join(issue.Affects, "|"))
+				if sec.tracksRequests() {
+					row += fmt.Sprintf("\t%s\t%s", issue.ImpactStatementRequest, issue.outstanding(r.Now))
+				}
+				_, _ = tabw.Write([]byte(row + "\n"))
+			}
+			for _, issue := range sec.Removed {
+				_, _ = tabw.Write([]byte(fmt.Sprintf("- %s\t%s\t\t\t\n", issue.Key, issue.Summary)))
+			}
+			_ = tabw.Flush()
+		}
+
+		fmt.Printf("\n=== Unanswered impact statement requests by team ===\n\n")
+		tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		_, _ = tabw.Write([]byte("TEAM\tCOUNT\tOLDEST\tCARDS\n"))
+		for _, team := range r.UnansweredByTeam {
+			name := team.Team
+			if team.Name != "" {
+				name = fmt.Sprintf("%s (%s)", team.Team, team.Name)
+			}
+			cards := team.ImpactStatementRequests
+			if len(cards) == 0 {
+				cards = team.Bugs
+			}
+			_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%d\t%s\t%s\n", name, team.Count, timestamps.Since(team.Oldest, r.Now), strings.Join(cards, ","))))
+		}
+		_ = tabw.Flush()
+	}
+}
+
+// outstanding describes how long the impact statement request of the bug is waiting for an answer
+func (issue sectionIssue) outstanding(now time.Time) string {
+	if issue.Requested == nil {
+		return ""
+	}
+	return blockerflow.FormatOutstanding(*issue.Requested, now)
+}
+
+func changeMarker(issue sectionIssue) string {
+	switch issue.Change {
+	case changeNew:
+		return "+"
+	case changeUpdated:
+		return "*"
+	}
+	return " "
+}
+
+// notifyChanges sends a summary of the cards that entered or left the sections since the previous snapshot
+func notifyChanges(r report) {
+	var lines []string
+	for _, sec := range r.Sections {
+		var added, removed []string
+		for _, issue := range sec.Issues {
+			if issue.Change == changeNew {
+				added = append(added, issue.Key)
+			}
+		}
+		for _, issue := range sec.Removed {
+			removed = append(removed, issue.Key)
+		}
+		if len(added) > 0 {
+			lines = append(lines, fmt.Sprintf("New JIRAs that %s: %s", sec.description, strings.Join(added, ", ")))
+		}
+		if len(removed) > 0 {
+			lines = append(lines, fmt.Sprintf("JIRAs that no longer %s: %s", sec.description, strings.Join(removed, ", ")))
+		}
+	}
+	if len(lines) == 0 {
+		logrus.Info("No changes to notify about")
+		return
+	}
+
+	notifier, err := notify.Load()
 	if err != nil {
-		logrus.WithError(err).Fatal("Failed to query JIRA")
-	}
-
-	// TODO(muller): DRY the code
-	// TODO(muller): Cache the results and emphasize items that changed since the last run
-	// TODO(muller): Maybe show activity since last run somehow
-	fmt.Printf("\n=== JIRAs that need an impact statement request ===\n\n")
-	tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	_, _ = tabw.Write([]byte("ID\tSUMMARY\tCOMPONENT\tMODIFIED\tAFFECTS\n"))
-	for _, issue := range needImpactStatementRequest {
-		id := issue.Key
-		summary := issue.Fields.Summary
-		component := issue.Fields.Components[0].Name
-		sinceUpdated := now.Sub(time.Time(issue.Fields.Updated)).Truncate(time.Minute)
-		var affects []string
-		for _, version := range issue.Fields.AffectsVersions {
-			affects = append(affects, version.Name)
-		}
-		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\n", id, summary, component, sinceUpdated.String(), strings.Join(affects, "|"))))
-	}
-	_ = tabw.Flush()
-
-	// TODO(muller): Show impact statement card and whether it changed
-	fmt.Printf("\n=== JIRAs that wait for a developer to provide an impact statement ===\n\n")
-	tabw = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	_, _ = tabw.Write([]byte("ID\tSUMMARY\tCOMPONENT\tMODIFIED\tAFFECTS\n"))
-	for _, issue := range needImpactStatement {
-		id := issue.Key
-		summary := issue.Fields.Summary
-		component := issue.Fields.Components[0].Name
-		sinceUpdated := now.Sub(time.Time(issue.Fields.Updated)).Truncate(time.Minute)
-		var affects []string
-		for _, version := range issue.Fields.AffectsVersions {
-			affects = append(affects, version.Name)
-		}
-		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\n", id, summary, component, sinceUpdated.String(), strings.Join(affects, "|"))))
-	}
-	_ = tabw.Flush()
-
-	fmt.Printf("\n=== JIRAs where a developer proposed an impact statement ===\n\n")
-	tabw = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	_, _ = tabw.Write([]byte("ID\tSUMMARY\tCOMPONENT\tMODIFIED\tAFFECTS\n"))
-	for _, issue := range haveImpactStatement {
-		id := issue.Key
-		summary := issue.Fields.Summary
-		component := issue.Fields.Components[0].Name
-		sinceUpdated := now.Sub(time.Time(issue.Fields.Updated)).Truncate(time.Minute)
-		var affects []string
-		for _, version := range issue.Fields.AffectsVersions {
-			affects = append(affects, version.Name)
-		}
-		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\n", id, summary, component, sinceUpdated.String(), strings.Join(affects, "|"))))
-	}
-	_ = tabw.Flush()
+		logrus.WithError(err).Warn("Cannot load notification config")
+		return
+	}
+	msg := notify.Message{
+		Source: "dashboard",
+		Title:  "Upgrade blocker dashboard changed",
+		Body:   strings.Join(lines, "\n"),
+	}
+	if err := notifier.Notify(msg); err != nil {
+		logrus.WithError(err).Warn("Cannot send dashboard notification")
+	}
 }
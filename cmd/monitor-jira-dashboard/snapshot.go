@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+
+	"github.com/petr-muller/ota/internal/config"
+)
+
+const (
+	snapshotDirName    = "dashboard"
+	snapshotNameLayout = "2006-01-02T15-04-05"
+	snapshotExtension  = ".json"
+)
+
+const (
+	changeNew     = "new"
+	changeUpdated = "changed"
+)
+
+// snapshotIssue is the state of a card remembered between dashboard runs
+type snapshotIssue struct {
+	Key      string `json:"key"`
+	Summary  string `json:"summary"`
+	Status   string `json:"status"`
+	Assignee string `json:"assignee"`
+}
+
+// snapshot is the dashboard state persisted after each run, keyed by section name
+type snapshot struct {
+	Taken    time.Time                  `json:"taken"`
+	Sections map[string][]snapshotIssue `json:"sections"`
+
+	// Report is the whole dashboard without the raw Jira issues, rendered again with --offline
+	Report *report `json:"report,omitempty"`
+}
+
+func snapshotDir() (string, error) {
+	dataDir, err := config.OtaDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, snapshotDirName), nil
+}
+
+func newSnapshot(r report) snapshot {
+	s := snapshot{Taken: r.Now, Sections: map[string][]snapshotIssue{}}
+	for _, sec := range r.Sections {
+		issues := []snapshotIssue{}
+		for _, issue := range sec.Issues {
+			issues = append(issues, snapshotIssue{Key: issue.Key, Summary: issue.Summary, Status: issue.Status, Assignee: issue.Assignee})
+		}
+		s.Sections[sec.Name] = issues
+	}
+
+	stored := r
+	stored.Sections = make([]section, len(r.Sections))
+	for i, sec := range r.Sections {
+		sec.Issues = slices.Clone(sec.Issues)
+		for j := range sec.Issues {
+			sec.Issues[j].Issue = jira.Issue{}
+		}
+		stored.Sections[i] = sec
+	}
+	s.Report = &stored
+	return s
+}
+
+func saveSnapshot(s snapshot) error {
+	dir, err := snapshotDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create snapshot directory %s: %w", dir, err)
+	}
+
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("cannot marshal snapshot: %w", err)
+	}
+	path := filepath.Join(dir, s.Taken.UTC().Format(snapshotNameLayout)+snapshotExtension)
+	return os.WriteFile(path, raw, 0644)
+}
+
+func loadSnapshot(path string) (*snapshot, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s snapshot
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal snapshot %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// findSnapshot returns the snapshot to compare against. An empty since selects the latest stored
+// snapshot, a duration selects the latest snapshot at least that old and anything else is taken as a
+// path to a snapshot file. A nil snapshot is returned when there is no stored snapshot to compare to.
+func findSnapshot(since string, now time.Time) (*snapshot, error) {
+	var age time.Duration
+	if since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil {
+			return loadSnapshot(since)
+		}
+		age = d
+	}
+
+	dir, err := snapshotDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snapshot directory %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, snapshotExtension) {
+			names = append(names, name)
+		}
+	}
+	// the names sort chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, name := range names {
+		taken, err := time.Parse(snapshotNameLayout, strings.TrimSuffix(name, snapshotExtension))
+		if err != nil {
+			continue
+		}
+		if now.Sub(taken) >= age {
+			return loadSnapshot(filepath.Join(dir, name))
+		}
+	}
+	return nil, nil
+}
+
+// latestReport returns the dashboard stored with the latest snapshot
+func latestReport() (*report, error) {
+	latest, err := findSnapshot("", time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, errors.New("no dashboard snapshot is stored, run the dashboard online first")
+	}
+	if latest.Report == nil {
+		return nil, fmt.Errorf("the latest snapshot (taken %s) does not store the dashboard, run the dashboard online first", latest.Taken.Local().Format(time.DateTime))
+	}
+	return latest.Report, nil
+}
+
+// pruneSnapshots removes the stored snapshots taken before the given time
+func pruneSnapshots(before time.Time) error {
+	dir, err := snapshotDir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("cannot read snapshot directory %s: %w", dir, err)
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, snapshotExtension) {
+			continue
+		}
+		taken, err := time.Parse(snapshotNameLayout, strings.TrimSuffix(name, snapshotExtension))
+		if err != nil || !taken.Before(before) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compare marks the cards that are new or changed (status or assignee) since the previous snapshot
+// and records the cards that disappeared from each section
+func (r *report) compare(previous *snapshot) {
+	r.Previous = &previous.Taken
+	for i := range r.Sections {
+		sec := &r.Sections[i]
+		before := map[string]snapshotIssue{}
+		for _, issue := range previous.Sections[sec.Name] {
+			before[issue.Key] = issue
+		}
+
+		for j := range sec.Issues {
+			issue := &sec.Issues[j]
+			old, ok := before[issue.Key]
+			switch {
+			case !ok:
+				issue.Change = changeNew
+			case old.Status != issue.Status || old.Assignee != issue.Assignee:
+				issue.Change = changeUpdated
+				issue.Previous = &old
+			}
+			delete(before, issue.Key)
+		}
+
+		for _, issue := range previous.Sections[sec.Name] {
+			if _, removed := before[issue.Key]; removed {
+				sec.Removed = append(sec.Removed, issue)
+			}
+		}
+	}
+}
+
+// changed returns true when any card entered or left a section since the previous snapshot
+func (r report) changed() bool {
+	for _, sec := range r.Sections {
+		if len(sec.Removed) > 0 {
+			return true
+		}
+		for _, issue := range sec.Issues {
+			if issue.Change == changeNew {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/jirautil"
+)
+
+// noImpactStatementRequestTeam groups the bugs without a linked impact statement request card
+const noImpactStatementRequestTeam = "(no ISR card)"
+
+// teamSummary is the unanswered impact statement request queue of a single team (the project of
+// the ISR cards)
+type teamSummary struct {
+	Team                    string    `json:"team"`
+	Name                    string    `json:"name,omitempty"`
+	Count                   int       `json:"count"`
+	Oldest                  time.Time `json:"oldest"`
+	Bugs                    []string  `json:"bugs"`
+	ImpactStatementRequests []string  `json:"impactStatementRequests"`
+}
+
+// linkImpactStatementRequests fills in the impact statement request cards linked to the bugs waiting
+// for an impact statement (in place), and when they were requested. The ISR cards are fetched with a
+// single search to learn when they were created; they are returned keyed by their keys.
+func linkImpactStatementRequests(ctx context.Context, client jirautil.Searcher, waiting []sectionIssue, bugProjects []string, now time.Time) (map[string]jira.Issue, error) {
+	var keys []string
+	for i := range waiting {
+		if isr := blockerflow.LinkedImpactStatementRequest(&waiting[i].Issue, bugProjects); isr != "" {
+			waiting[i].ImpactStatementRequest = isr
+			keys = append(keys, isr)
+		}
+	}
+
+	isrs := map[string]jira.Issue{}
+	if len(keys) > 0 {
+		found, err := jirautil.SearchAll(ctx, client, fmt.Sprintf("key in (%s)", strings.Join(keys, ",")))
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch impact statement request cards: %w", err)
+		}
+		for _, isr := range found {
+			isrs[isr.Key] = isr
+		}
+	}
+
+	for i := range waiting {
+		isr, ok := isrs[waiting[i].ImpactStatementRequest]
+		if !ok {
+			continue
+		}
+		requested := time.Time(isr.Fields.Created)
+		waiting[i].Requested = &requested
+		waiting[i].DaysOutstanding = blockerflow.DaysOutstanding(requested, now)
+		waiting[i].Overdue = blockerflow.Overdue(requested, now)
+	}
+	return isrs, nil
+}
+
+// unansweredByTeam pivots the bugs waiting for an impact statement by the project of their ISR cards,
+// see linkImpactStatementRequests
+func unansweredByTeam(waiting []sectionIssue, isrs map[string]jira.Issue) []teamSummary {
+	teams := map[string]*teamSummary{}
+	for _, bug := range waiting {
+		team, name, requested := noImpactStatementRequestTeam, "", bug.Updated
+		if isrKey := bug.ImpactStatementRequest; isrKey != "" {
+			team = strings.SplitN(isrKey, "-", 2)[0]
+			if isr, ok := isrs[isrKey]; ok {
+				name = isr.Fields.Project.Name
+				requested = time.Time(isr.Fields.Created)
+			}
+		}
+
+		summary, ok := teams[team]
+		if !ok {
+			summary = &teamSummary{Team: team, Name: name, Oldest: requested}
+			teams[team] = summary
+		}
+		summary.Count++
+		summary.Bugs = append(summary.Bugs, bug.Key)
+		if bug.ImpactStatementRequest != "" {
+			summary.ImpactStatementRequests = append(summary.ImpactStatementRequests, bug.ImpactStatementRequest)
+		}
+		if requested.Before(summary.Oldest) {
+			summary.Oldest = requested
+		}
+	}
+
+	var summaries []teamSummary
+	for _, summary := range teams {
+		summaries = append(summaries, *summary)
+	}
+	// teams with the oldest requests need escalation first
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Oldest.Before(summaries[j].Oldest) })
+	return summaries
+}
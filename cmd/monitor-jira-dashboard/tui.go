@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	prowjira "sigs.k8s.io/prow/pkg/jira"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/jiratui"
+	"github.com/petr-muller/ota/internal/timefmt"
+)
+
+// interactiveModel shows the dashboard sections as tables that can be browsed and acted on
+type interactiveModel struct {
+	jira prowjira.Client
+
+	panels []jiratui.Panel
+	// focused is the index of the panel that receives keyboard input
+	focused int
+
+	actions jiratui.Actions
+}
+
+func runInteractive(client prowjira.Client, flow *blockerflow.Flow, sections []section, timestamps timefmt.Formatter, overdueOnly bool) error {
+	m := interactiveModel{
+		jira:    client,
+		actions: jiratui.NewActions(flow, jiratui.RequestImpactStatement, jiratui.ProposeImpactStatement, jiratui.ClearLabels),
+	}
+	for _, sec := range sections {
+		if overdueOnly && !sec.tracksRequests() {
+			continue
+		}
+		panel := jiratui.NewPanel(sec.Name, sec.Title, sec.Query, sec.tracksRequests()).WithTimestamps(timestamps).WithBugProjects(flow.BugProjects)
+		if overdueOnly {
+			panel = panel.WithOverdueOnly()
+		}
+		m.panels = append(m.panels, panel)
+	}
+	m.panels[0] = m.panels[0].Focus()
+
+	_, err := jiratui.Run(m)
+	return err
+}
+
+func (m interactiveModel) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	for i := range m.panels {
+		var cmd tea.Cmd
+		m.panels[i], cmd = m.panels[i].Refresh(m.jira)
+		cmds = append(cmds, cmd)
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m interactiveModel) refresh(i int) (interactiveModel, tea.Cmd) {
+	var cmd tea.Cmd
+	m.panels[i], cmd = m.panels[i].Refresh(m.jira)
+	return m, cmd
+}
+
+func (m interactiveModel) refreshAll() (interactiveModel, tea.Cmd) {
+	var cmds []tea.Cmd
+	for i := range m.panels {
+		var cmd tea.Cmd
+		m, cmd = m.refresh(i)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m interactiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, isKey := msg.(tea.KeyMsg); isKey && m.actions.Active() {
+		var cmd tea.Cmd
+		m.actions, cmd = m.actions.Update(msg)
+		return m, cmd
+	}
+
+	switch msg := msg.(type) {
+	case jiratui.ActionDoneMsg:
+		m.actions, _ = m.actions.Update(msg)
+		if msg.Err != nil {
+			return m, nil
+		}
+		// the bug moves between the sections
+		return m.refreshAll()
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "tab":
+			m.panels[m.focused] = m.panels[m.focused].Blur()
+			m.focused = (m.focused + 1) % len(m.panels)
+			m.panels[m.focused] = m.panels[m.focused].Focus()
+			return m, nil
+		case "enter":
+			return m, m.panels[m.focused].OpenSelected(m.jira)
+		case "r":
+			return m.refresh(m.focused)
+		case "R":
+			return m.refreshAll()
+		}
+		if selected := m.panels[m.focused].Selected(); selected != nil {
+			var cmd tea.Cmd
+			var started bool
+			if m.actions, cmd, started = m.actions.Start(msg.String(), selected.Key); started {
+				return m, cmd
+			}
+		}
+	}
+
+	var cmds []tea.Cmd
+	for i := range m.panels {
+		var cmd tea.Cmd
+		m.panels[i], cmd = m.panels[i].Update(msg, m.jira)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m interactiveModel) View() string {
+	var views []string
+	for _, panel := range m.panels {
+		views = append(views, panel.View())
+	}
+	view := strings.Join(views, "\n\n") + "\n\n"
+
+	if status := m.actions.View(); status != "" {
+		view += status + "\n"
+	}
+	if !m.actions.Active() {
+		view += "Press 'tab' to switch tables, 'enter' to open the selected issue, " + m.actions.Help() + ", 'r'/'R' to refresh the table/all tables, 'q' to quit"
+	}
+	return view
+}
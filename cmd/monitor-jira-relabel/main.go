@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jirautil"
+)
+
+type options struct {
+	fromLabel string
+	toLabel   string
+	jql       string
+
+	dryRun bool
+	pace   time.Duration
+
+	jira flagutil.JiraOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.fromLabel, "from-label", "", "The label to be replaced")
+	fs.StringVar(&o.toLabel, "to-label", "", "The label to replace --from-label with")
+	fs.StringVar(&o.jql, "jql", "", "JQL selecting the issues to relabel (default: all issues with --from-label)")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "Only print what would be changed")
+	fs.DurationVar(&o.pace, "pace", time.Second, "Time to wait between updating two issues")
+
+	o.jira.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.fromLabel == "" {
+		return fmt.Errorf("--from-label must be specified and nonempty")
+	}
+
+	if o.toLabel == "" {
+		return fmt.Errorf("--to-label must be specified and nonempty")
+	}
+
+	if o.fromLabel == o.toLabel {
+		return fmt.Errorf("--from-label and --to-label must differ")
+	}
+
+	if o.pace < 0 {
+		return fmt.Errorf("--pace must not be negative")
+	}
+
+	return o.jira.Validate()
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota monitor jira relabel
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	jiraClient, err := o.jira.Client()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create Jira client")
+	}
+
+	query := o.jql
+	if query == "" {
+		query = fmt.Sprintf("labels = %q", o.fromLabel)
+	}
+
+	logrus.Infof("Obtaining issues matching '%s'", query)
+	issues, err := jirautil.SearchAll(ctx, jiraClient, query)
+	if err != nil {
+		exitcode.JiraFatal(err, "Failed to query JIRA")
+	}
+
+	var relabeled, skipped int
+	for _, issue := range issues {
+		labels := sets.New[string](issue.Fields.Labels...)
+		if !labels.Has(o.fromLabel) {
+			logrus.Infof("%s: Skipping, does not have the %s label", issue.Key, o.fromLabel)
+			skipped++
+			continue
+		}
+
+		labels.Delete(o.fromLabel).Insert(o.toLabel)
+		if o.dryRun {
+			logrus.Infof("%s: Would replace %s with %s (dry run)", issue.Key, o.fromLabel, o.toLabel)
+			relabeled++
+			continue
+		}
+
+		if relabeled > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(o.pace):
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			code := exitcode.Interrupted
+			if relabeled > 0 {
+				code = exitcode.PartialFailure
+			}
+			exitcode.Fatal(code, err, fmt.Sprintf("interrupted (relabeled %d issues so far, rerun to continue)", relabeled))
+		}
+		logrus.Infof("%s: Replacing %s with %s", issue.Key, o.fromLabel, o.toLabel)
+		if _, err := jiraClient.UpdateIssue(&jira.Issue{
+			Key:    issue.Key,
+			Fields: &jira.IssueFields{Labels: sets.List(labels)},
+		}); err != nil {
+			code := exitcode.ForJira(err)
+			if relabeled > 0 {
+				code = exitcode.PartialFailure
+			}
+			exitcode.Fatal(code, err, fmt.Sprintf("cannot update issue %s (relabeled %d issues so far, rerun to continue)", issue.Key, relabeled))
+		}
+		relabeled++
+	}
+
+	logrus.Infof("Relabeled %d issues, skipped %d", relabeled, skipped)
+}
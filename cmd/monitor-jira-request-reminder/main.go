@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/timefmt"
+)
+
+const (
+	jqlImpactStatementRequested = "{{frag:upgrade_blockers}} AND labels in (ImpactStatementRequested)"
+)
+
+type options struct {
+	inactive time.Duration
+	interval time.Duration
+
+	dryRun bool
+	pace   time.Duration
+
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.DurationVar(&o.inactive, "inactive", 7*24*time.Hour, "Remind about impact statement request cards that had no activity for at least this long")
+	fs.DurationVar(&o.interval, "interval", 7*24*time.Hour, "Never remind about the same impact statement request card more often than this")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "Only print which cards would get a reminder")
+	fs.DurationVar(&o.pace, "pace", time.Second, "Time to wait between posting two reminders")
+
+	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.inactive <= 0 {
+		return fmt.Errorf("--inactive must be positive")
+	}
+
+	if o.interval < 0 {
+		return fmt.Errorf("--interval must not be negative")
+	}
+
+	if o.pace < 0 {
+		return fmt.Errorf("--pace must not be negative")
+	}
+
+	return o.jira.Validate()
+}
+
+type result struct {
+	bug      string
+	isr      string
+	inactive time.Duration
+	outcome  string
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota monitor jira request-reminder
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	jiraClient, err := o.jira.Client()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create Jira client")
+	}
+
+	flow, err := blockerflow.NewFlow(jiraClient)
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot initialize blocker workflow")
+	}
+	flow.Visibility = o.jira.CommentVisibility(flow.Visibility)
+	flow.BugProjects = o.bugProjects.Projects()
+
+	sent, err := loadReminders()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot load the sent reminders")
+	}
+
+	fragments, err := config.LoadJQLFragments()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load JQL fragments")
+	}
+	query, err := fragments.Expand(jqlImpactStatementRequested)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot expand JQL fragments")
+	}
+
+	logrus.Infof("Obtaining bugs matching '%s'", query)
+	bugs, err := jirautil.SearchAll(ctx, jiraClient, query)
+	if err != nil {
+		exitcode.JiraFatal(err, "Failed to query JIRA")
+	}
+
+	var candidates []*blockerflow.Candidate
+	var keys []string
+	for i := range bugs {
+		key := blockerflow.LinkedImpactStatementRequest(&bugs[i], flow.BugProjects)
+		if key == "" {
+			logrus.Warnf("%s: Skipping, no impact statement request card is linked", bugs[i].Key)
+			continue
+		}
+		candidates = append(candidates, &blockerflow.Candidate{Bug: &bugs[i]})
+		keys = append(keys, key)
+	}
+
+	logrus.Infof("Obtaining %d impact statement request cards", len(keys))
+	isrs, err := jirautil.DefaultFetcher.GetIssues(ctx, jiraClient, keys)
+	if err != nil {
+		exitcode.JiraFatal(err, "cannot get issue")
+	}
+
+	// Forget the reminders about cards that no longer wait for an answer
+	waiting := sets.New[string](keys...)
+	for key := range sent {
+		if !waiting.Has(key) {
+			delete(sent, key)
+		}
+	}
+
+	now := time.Now()
+	var results []result
+	var reminded int
+	for i, candidate := range candidates {
+		isr := isrs[i]
+		candidate.ImpactStatementRequest = isr
+		r := result{bug: candidate.Bug.Key, isr: isr.Key, inactive: now.Sub(time.Time(isr.Fields.Updated))}
+
+		last, remindedBefore := sent[isr.Key]
+		switch {
+		case isr.Fields.Status != nil && isr.Fields.Status.StatusCategory.Key == jira.StatusCategoryComplete:
+			r.outcome = "skipped: card is " + isr.Fields.Status.Name
+		case r.inactive < o.inactive:
+			r.outcome = "skipped: recent activity"
+		case remindedBefore && now.Sub(last) < o.interval:
+			r.outcome = fmt.Sprintf("skipped: reminded %s ago", timefmt.Duration(now.Sub(last)))
+		case o.dryRun:
+			r.outcome = "would remind (dry run)"
+		}
+		if r.outcome != "" {
+			results = append(results, r)
+			continue
+		}
+
+		if reminded > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(o.pace):
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			exitcode.Fatal(exitcode.Interrupted, err, fmt.Sprintf("interrupted (sent %d reminders so far)", reminded))
+		}
+		if err := flow.RemindImpactStatement(candidate); err != nil {
+			exitcode.JiraFatal(err, fmt.Sprintf("cannot remind about %s (sent %d reminders so far)", isr.Key, reminded))
+		}
+		reminded++
+		r.outcome = "reminded"
+		results = append(results, r)
+
+		sent[isr.Key] = now
+		if err := sent.save(); err != nil {
+			logrus.WithError(err).Warn("Cannot record the sent reminder, the next run may repeat it")
+		}
+	}
+	if !o.dryRun {
+		if err := sent.save(); err != nil {
+			logrus.WithError(err).Warn("Cannot record the sent reminders")
+		}
+	}
+
+	tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	_, _ = tabw.Write([]byte("BUG\tISR\tINACTIVE\tRESULT\n"))
+	for _, r := range results {
+		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%s\n", r.bug, r.isr, timefmt.Duration(r.inactive), r.outcome)))
+	}
+	_ = tabw.Flush()
+	logrus.Infof("Sent %d reminders about %d impact statement request cards", reminded, len(candidates))
+}
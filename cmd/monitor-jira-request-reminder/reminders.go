@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/petr-muller/ota/internal/config"
+)
+
+// remindersFileName is a file in the ota data directory that records when the reminders were sent
+const remindersFileName = "request-reminders.json"
+
+// reminders holds when the last reminder was posted on each impact statement request card
+type reminders map[string]time.Time
+
+func remindersPath() (string, error) {
+	dataDir, err := config.OtaDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, remindersFileName), nil
+}
+
+// loadReminders returns the recorded reminders; a missing record means no reminders were sent yet
+func loadReminders() (reminders, error) {
+	path, err := remindersPath()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return reminders{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read reminders %s: %w", path, err)
+	}
+	sent := reminders{}
+	if err := json.Unmarshal(raw, &sent); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal reminders %s: %w", path, err)
+	}
+	return sent, nil
+}
+
+func (r reminders) save() error {
+	path, err := remindersPath()
+	if err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0644)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/updateblockers"
+)
+
+type options struct {
+	bugId int
+
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+	risks       flagutil.RiskSourceOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.IntVar(&o.bugId, "bug", 0, "The numerical part of the bug card (in the first --bug-project) to show the upgrade blocker workflow status of")
+
+	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.risks.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.bugId == 0 {
+		return fmt.Errorf("--bug must be specified and nonzero")
+	}
+
+	if err := o.risks.Validate(); err != nil {
+		return err
+	}
+
+	return o.jira.Validate()
+}
+
+// nextStep suggests the command that moves the candidate forward in the upgrade blocker workflow
+func nextStep(c *blockerflow.Candidate, bugId int) string {
+	switch updateblockers.StageOf(c.Bug.Fields.Labels) {
+	case updateblockers.StageNone:
+		return fmt.Sprintf("Nothing to do: %s is not labeled as an upgrade blocker candidate (%s)", c.Bug.Key, updateblockers.LabelBlocker)
+	case updateblockers.StageCandidate:
+		return fmt.Sprintf("monitor-jira-create-impact-statement-request --bug %d --for <component project>", bugId)
+	case updateblockers.StageImpactStatementRequested:
+		if c.ImpactStatementRequest == nil {
+			return fmt.Sprintf("Link the impact statement request card to %s or pass it with --impact-statement-card to the workflow commands", c.Bug.Key)
+		}
+		return fmt.Sprintf("Wait for the impact statement in %s, then: monitor-jira-move-to-proposed --bug %d", c.ImpactStatementRequest.Key, bugId)
+	case updateblockers.StageImpactStatementProposed:
+		if c.ImpactStatementRequest != nil && len(c.Risks) == 0 {
+			return fmt.Sprintf("Declare the risk if the impact statement warrants it: graph-declare --url %s%s ...", graph.JiraBrowsePrefix, c.ImpactStatementRequest.Key)
+		}
+		return fmt.Sprintf("monitor-jira-move-to-updaterecommendationblocked --bug %d", bugId)
+	case updateblockers.StageKnownIssueAnnounced:
+		return "Nothing to do: the known issue is announced"
+	}
+	return ""
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota monitor jira status
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	jiraClient, err := o.jira.Client()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create Jira client")
+	}
+
+	// The command only inspects the bug, so it never changes anything regardless of --read-only. Only
+	// Load is used, which does not need the transitions and hooks configuration.
+	flow := &blockerflow.Flow{Client: jirautil.ReadOnly(jiraClient), BugProjects: o.bugProjects.Projects()}
+	candidate, err := flow.Load(o.bugProjects.BugKey(o.bugId), "")
+	if err != nil {
+		exitcode.JiraFatal(err, "cannot get issue")
+	}
+	if err := candidate.FindRisks(ctx, o.risks.EdgesByURL); err != nil {
+		exitcode.Fatal(exitcode.Error, err, "cannot look up conditional risks")
+	}
+
+	bug := candidate.Bug
+	fmt.Printf("%s: %s\n\n", bug.Key, bug.Fields.Summary)
+	fmt.Printf("Stage:  %s\n", updateblockers.StageOf(bug.Fields.Labels))
+	fmt.Printf("Labels: %s\n", strings.Join(bug.Fields.Labels, ", "))
+
+	fmt.Printf("\nImpact statement requests:\n")
+	if len(candidate.ImpactStatementRequestCandidates) == 0 {
+		fmt.Println("  (none linked)")
+	}
+	tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	for _, isr := range candidate.ImpactStatementRequestCandidates {
+		var status string
+		if isr.Fields.Status != nil {
+			status = isr.Fields.Status.Name
+		}
+		_, _ = tabw.Write([]byte(fmt.Sprintf("  %s\t%s\t%s\n", isr.Key, status, isr.Fields.Summary)))
+	}
+	_ = tabw.Flush()
+
+	fmt.Printf("\nBlocked edges:\n")
+	switch {
+	case candidate.ImpactStatementRequest == nil:
+		fmt.Println("  (not looked up without a single impact statement request)")
+	case len(candidate.Risks) == 0:
+		fmt.Printf("  (none reference %s)\n", candidate.ImpactStatementRequest.Key)
+	}
+	for _, edge := range candidate.Risks {
+		fmt.Printf("  %s\n", graph.EdgeFileName(edge.To, edge.Name))
+	}
+
+	fmt.Printf("\nNext: %s\n", nextStep(candidate, o.bugId))
+}
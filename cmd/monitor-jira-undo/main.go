@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+)
+
+type options struct {
+	bugId  int
+	dryRun bool
+
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.IntVar(&o.bugId, "bug", 0, "The numerical part of the bug card (in the first --bug-project) to revert the last workflow operation on")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "Only print the changes that would be reverted")
+
+	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.bugId == 0 {
+		return fmt.Errorf("--bug must be specified and nonzero")
+	}
+
+	return o.jira.Validate()
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota monitor jira undo
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+
+	bugKey := o.bugProjects.BugKey(o.bugId)
+	journal, err := blockerflow.LoadJournal(bugKey)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot load the journal")
+	}
+	if journal == nil || len(journal.Entries) == 0 {
+		logrus.Infof("%s: No workflow operation to undo", bugKey)
+		return
+	}
+
+	logrus.Infof("%s: Reverting %d changes of %s started at %s", bugKey, len(journal.Entries), journal.Operation, journal.Started.Local().Format(time.DateTime))
+	if o.dryRun {
+		for i := len(journal.Entries) - 1; i >= 0; i-- {
+			fmt.Println(journal.Entries[i])
+		}
+		return
+	}
+
+	jiraClient, err := o.jira.Client()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create Jira client")
+	}
+
+	flow, err := blockerflow.NewFlow(jiraClient)
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot initialize blocker workflow")
+	}
+
+	if err := flow.Undo(journal); err != nil {
+		exitcode.Fatal(exitcode.PartialFailure, err, "cannot undo the workflow operation")
+	}
+	logrus.Infof("%s: Reverted %s", bugKey, journal.Operation)
+}
@@ -1,214 +1,322 @@
 package main
 
 import (
-	"context"
 	"flag"
 	"fmt"
-	"net/url"
 	"os"
-	"os/exec"
 	"strings"
-	"time"
 
-	"github.com/andygrunwald/go-jira"
-	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirupsen/logrus"
 
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
 	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/jiratui"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/timefmt"
 )
 
 type options struct {
-	jira flagutil.JiraOptions
-}
-
-func (o *options) validate() error {
-	return o.jira.Validate()
-}
+	focus       string
+	overdueOnly bool
 
-type optionsMsg options
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
 
-type jiraClientMsg jiraClient
+	fragments  config.JQLFragments
+	timestamps timefmt.Formatter
 
-type jiraClient interface {
-	SearchWithContext(context.Context, string, *jira.SearchOptions) ([]jira.Issue, *jira.Response, error)
-	JiraURL() string
+	log flagutil.LogOptions
 }
 
-type jiraItems struct {
-	query   string
-	fetched bool
-	items   []jira.Issue
-	table   table.Model
-	spinner spinner.Model
-
-	getUrlForItem func(key string) string
-}
-
-func (i jiraItems) View() string {
-	if !i.fetched {
-		return i.spinner.View()
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
 	}
 
-	return i.table.View()
+	return o.jira.Validate()
 }
 
-func (i jiraItems) openSelectedIssue() tea.Cmd {
-	return func() tea.Msg {
-		if i.table.Cursor() >= 0 {
-			issue := i.items[i.table.Cursor()]
-			_ = exec.Command("xdg-open", i.getUrlForItem(issue.Key)).Start()
-		}
-		return nil
-	}
-}
+type optionsMsg options
 
-func initialModel() model {
-	return model{
-		needImpactStatementRequest: jiraItems{
-			query:   "project = OCPBUGS AND labels in (UpgradeBlocker) AND labels not in (ImpactStatementRequested, ImpactStatementProposed, UpdateRecommendationsBlocked)",
-			spinner: spinner.New(spinner.WithSpinner(spinner.Points)),
+type jiraClientMsg jirautil.Client
+
+type jiraClient jiratui.Client
+
+// workflowActions are the blocker workflow actions available on the selected bug
+var workflowActions = []jiratui.Action{jiratui.RequestImpactStatement, jiratui.ProposeImpactStatement, jiratui.ClearLabels}
+
+func initialModel(o options, client jirautil.Client) model {
+	m := model{
+		options: o,
+		client:  client,
+		actions: jiratui.NewActions(nil, workflowActions...),
+		panels: []jiratui.Panel{
+			jiratui.NewPanel(
+				"needImpactStatementRequest",
+				"Bugs that need an impact statement request",
+				"{{frag:upgrade_blockers}} AND labels not in (ImpactStatementRequested, ImpactStatementProposed, UpdateRecommendationsBlocked)",
+				false,
+			).Focus(),
+			jiratui.NewPanel(
+				"needImpactStatement",
+				"Bugs waiting for an impact statement",
+				"{{frag:upgrade_blockers}} AND labels in (ImpactStatementRequested)",
+				true,
+			),
+			jiratui.NewPanel(
+				"haveImpactStatement",
+				"Bugs with a proposed impact statement",
+				"{{frag:ocpbugs}} AND labels in (ImpactStatementProposed)",
+				true,
+			),
+			jiratui.NewPanel(
+				"openImpactStatementRequests",
+				"Open impact statement requests",
+				"{{frag:impact_statement_requests}} AND statusCategory != Done ORDER BY created ASC",
+				false,
+			).WithOutstanding(),
 		},
 	}
-}
-
-type needImpactStatementRequestMsg jiraItems
-
-func refreshNeedImpactStatementRequest(jiras jiraItems, jira jiraClient) tea.Cmd {
-	return func() tea.Msg {
-		now := time.Now()
-
-		jiraUrl := jira.JiraURL()
-
-		jiras.getUrlForItem = func(key string) string {
-			itemUrl, err := url.JoinPath(jiraUrl, "browse", key)
-			if err != nil {
-				panic(err)
-			}
-			return itemUrl
-		}
-
-		items, _, err := jira.SearchWithContext(context.Background(), jiras.query, nil)
-		if err != nil {
-			// TODO(muller): Something
+	if o.overdueOnly {
+		for i := range m.panels {
+			m.panels[i] = m.panels[i].WithOverdueOnly()
 		}
-		jiras.items = items
-		jiras.fetched = true
-
-		lengths := [...]int{len("ID"), len("Summary"), len("Component"), len("Modified"), len("Affects")}
-		var rows []table.Row
-		for _, item := range items {
-			var affects []string
-			for _, version := range item.Fields.AffectsVersions {
-				affects = append(affects, version.Name)
-			}
-			row := table.Row{
-				item.Key,
-				item.Fields.Summary,
-				item.Fields.Components[0].Name,
-				now.Sub(time.Time(item.Fields.Updated)).Truncate(time.Minute).String(),
-				strings.Join(affects, "|"),
-			}
-			for i := range lengths {
-				if length := len(row[i]); length > lengths[i] {
-					lengths[i] = min(length, 75)
-				}
-			}
-			rows = append(rows, row)
-		}
-
-		height := min(10, len(rows)+2)
-
-		jiras.table = table.New(
-			table.WithColumns(
-				[]table.Column{
-					{Width: lengths[0], Title: "ID"},
-					{Width: lengths[1], Title: "Summary"},
-					{Width: lengths[2], Title: "Component"},
-					{Width: lengths[3], Title: "Modified"},
-					{Width: lengths[4], Title: "Affects"},
-				},
-			),
-			table.WithRows(rows),
-			table.WithFocused(true),
-			table.WithHeight(height),
-		)
-		return needImpactStatementRequestMsg(jiras)
 	}
+	return m
 }
 
 type model struct {
+	// options and client are created before the program starts and handed over by Init
+	options options
+	client  jirautil.Client
+
 	jira jiraClient
+	// jiraOptions are kept until the client is created to configure the workflow actions
+	jiraOptions flagutil.JiraOptions
+	bugProjects []string
 
-	needImpactStatementRequest jiraItems
+	panels []jiratui.Panel
+
+	// focused is the index of the panel that receives keyboard input
+	focused int
+	// focusKey is the issue to select once the panels are loaded (--focus)
+	focusKey string
+	notice   string
+
+	actions jiratui.Actions
 }
 
-func gatherOptions() tea.Msg {
+func gatherOptions() options {
 	var o options
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+	fs.StringVar(&o.focus, "focus", "", "Start with the given issue (e.g. OCPBUGS-123) selected in the panel that contains it")
+	fs.BoolVar(&o.overdueOnly, "overdue-only", false, fmt.Sprintf("Only show the impact statement requests waiting for an answer for more than %s", timefmt.Duration(blockerflow.ImpactStatementSLA)))
 	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
-		// TODO(muller): Something
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
 	}
 
-	if err := o.validate(); err != nil {
-		// TODO(muller): Something
+	return o
+}
+
+// loadConfig loads the configuration files the terminal UI uses into the options
+func (o *options) loadConfig() error {
+	fragments, err := config.LoadJQLFragments()
+	if err != nil {
+		return fmt.Errorf("cannot load JQL fragments: %w", err)
 	}
-	return optionsMsg(o)
+	o.fragments = fragments
+
+	timestamps, err := timefmt.Load()
+	if err != nil {
+		return fmt.Errorf("cannot load display config: %w", err)
+	}
+	o.timestamps = timestamps
+	return nil
 }
 
-func makeJiraClientCmd(o options) tea.Cmd {
+func makeJiraClientCmd(jc jirautil.Client) tea.Cmd {
 	return func() tea.Msg {
-		jc, err := o.jira.Client()
-		if err != nil {
-			// TODO(muller): Something
-		}
 		return jiraClientMsg(jc)
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(gatherOptions, m.needImpactStatementRequest.spinner.Tick)
+	cmds := []tea.Cmd{func() tea.Msg { return optionsMsg(m.options) }}
+	for _, panel := range m.panels {
+		cmds = append(cmds, panel.Tick)
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, isKey := msg.(tea.KeyMsg); isKey && m.actions.Active() {
+		var cmd tea.Cmd
+		m.actions, cmd = m.actions.Update(msg)
+		return m, cmd
+	}
+
 	switch msg := msg.(type) {
+	case jiratui.ActionDoneMsg:
+		m.actions, _ = m.actions.Update(msg)
+		if msg.Err != nil {
+			return m, nil
+		}
+		// the bug moves between the panels
+		var cmds []tea.Cmd
+		for i := range m.panels {
+			var cmd tea.Cmd
+			m.panels[i], cmd = m.panels[i].Refresh(m.jira)
+			cmds = append(cmds, cmd)
+		}
+		return m, tea.Batch(cmds...)
 	case optionsMsg:
-		return m, makeJiraClientCmd(options(msg))
+		for i, panel := range m.panels {
+			panel = panel.WithTimestamps(msg.timestamps).WithBugProjects(msg.bugProjects.Projects())
+			if query, err := msg.fragments.Expand(panel.Query); err == nil {
+				panel.Query = query
+			} else {
+				panel = panel.WithQueryError(err)
+			}
+			m.panels[i] = panel
+		}
+		m.jiraOptions = msg.jira
+		m.bugProjects = msg.bugProjects.Projects()
+		m.focusKey = strings.ToUpper(msg.focus)
+		return m, makeJiraClientCmd(m.client)
 	case jiraClientMsg:
 		m.jira = jiraClient(msg)
-		return m, refreshNeedImpactStatementRequest(m.needImpactStatementRequest, m.jira)
-	case needImpactStatementRequestMsg:
-		m.needImpactStatementRequest = jiraItems(msg)
-		return m, nil
+		// Without a flow (broken transitions or hooks config) browsing still works and the actions
+		// report they are not available
+		flow, _ := blockerflow.NewFlow(jirautil.Client(msg))
+		if flow != nil {
+			flow.Visibility = m.jiraOptions.CommentVisibility(flow.Visibility)
+			flow.BugProjects = m.bugProjects
+		}
+		m.actions = jiratui.NewActions(flow, workflowActions...)
+		var cmds []tea.Cmd
+		for i := range m.panels {
+			var cmd tea.Cmd
+			m.panels[i], cmd = m.panels[i].Refresh(m.jira)
+			cmds = append(cmds, cmd)
+		}
+		return m, tea.Batch(cmds...)
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
+		case "tab", "shift+tab":
+			m.panels[m.focused] = m.panels[m.focused].Blur()
+			if msg.String() == "tab" {
+				m.focused = (m.focused + 1) % len(m.panels)
+			} else {
+				m.focused = (m.focused + len(m.panels) - 1) % len(m.panels)
+			}
+			m.panels[m.focused] = m.panels[m.focused].Focus()
+			return m, nil
+		case "r":
+			if m.jira != nil {
+				var cmd tea.Cmd
+				m.panels[m.focused], cmd = m.panels[m.focused].Refresh(m.jira)
+				return m, cmd
+			}
 		case "enter":
-			if m.needImpactStatementRequest.fetched {
-				return m, m.needImpactStatementRequest.openSelectedIssue()
+			if m.jira != nil {
+				return m, m.panels[m.focused].OpenSelected(m.jira)
+			}
+		}
+		if selected := m.panels[m.focused].Selected(); selected != nil {
+			var cmd tea.Cmd
+			var started bool
+			if m.actions, cmd, started = m.actions.Start(msg.String(), selected.Key); started {
+				return m, cmd
 			}
 		}
 	}
 
 	var cmds []tea.Cmd
-	var cmd tea.Cmd
-
-	m.needImpactStatementRequest.table, cmd = m.needImpactStatementRequest.table.Update(msg)
-	cmds = append(cmds, cmd)
-	m.needImpactStatementRequest.spinner, cmd = m.needImpactStatementRequest.spinner.Update(msg)
-	cmds = append(cmds, cmd)
+	for i := range m.panels {
+		var cmd tea.Cmd
+		m.panels[i], cmd = m.panels[i].Update(msg, m.jira)
+		cmds = append(cmds, cmd)
+	}
+	if _, isPage := msg.(jiratui.PageMsg); isPage && m.focusKey != "" {
+		m = m.focusIssue()
+	}
 	return m, tea.Batch(cmds...)
 }
 
+// focusIssue focuses the first panel containing the issue requested with --focus and selects it there.
+// The request is dropped once found or when all panels are loaded without it.
+func (m model) focusIssue() model {
+	loaded := true
+	for i := range m.panels {
+		panel, found := m.panels[i].Select(m.focusKey)
+		if found {
+			m.panels[m.focused] = m.panels[m.focused].Blur()
+			m.focused = i
+			m.panels[i] = panel.Focus()
+			m.focusKey = ""
+			return m
+		}
+		loaded = loaded && panel.Loaded()
+	}
+	if loaded {
+		m.notice = fmt.Sprintf("%s is not in any panel", m.focusKey)
+		m.focusKey = ""
+	}
+	return m
+}
+
 func (m model) View() string {
-	return m.needImpactStatementRequest.View() + "\n\nPress 'q' to quit"
+	var tabs []string
+	for i, panel := range m.panels {
+		if i == m.focused {
+			tabs = append(tabs, "[ "+panel.Label()+" ]")
+		} else {
+			tabs = append(tabs, "  "+panel.Label()+"  ")
+		}
+	}
+	view := strings.Join(tabs, " ") + "\n\n" + m.panels[m.focused].View() + "\n\n"
+	if m.notice != "" {
+		view += m.notice + "\n"
+	}
+	if status := m.actions.View(); status != "" {
+		view += status + "\n"
+	}
+	if !m.actions.Active() {
+		view += "Press 'tab'/'shift+tab' to switch panels, 'enter' to open the selected issue, " + m.actions.Help() + ", 'r' to refresh the panel, 'q' to quit"
+	}
+	return view
 }
 
 func main() {
-	if _, err := tea.NewProgram(initialModel()).Run(); err != nil {
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+
+	if err := o.loadConfig(); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load configuration")
+	}
+
+	jc, err := o.jira.Client()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create Jira client")
+	}
+
+	if _, err := jiratui.Run(initialModel(o, jc)); err != nil {
 		fmt.Printf("There was an error: %v\n", err)
 		os.Exit(1)
 	}
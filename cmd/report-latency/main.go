@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/gitutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/timefmt"
+	"github.com/petr-muller/ota/internal/updateblockers"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type options struct {
+	graphRepositoryPath string
+	since               time.Duration
+	output              string
+
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository (a git checkout of the branch served by OSUS)")
+	fs.DurationVar(&o.since, "since", 365*24*time.Hour, "Only report risks declared at most this long ago (0 reports all risks)")
+	fs.StringVar(&o.output, "output", outputTable, fmt.Sprintf("Output format: %s or %s", outputTable, outputJSON))
+
+	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.graphRepositoryPath == "" {
+		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
+	}
+
+	if o.since < 0 {
+		return fmt.Errorf("--since must not be negative")
+	}
+
+	if o.output != outputTable && o.output != outputJSON {
+		return fmt.Errorf("--output must be one of %s, %s", outputTable, outputJSON)
+	}
+
+	return o.jira.Validate()
+}
+
+// riskLatency is the time it took from labeling a bug as an upgrade blocker candidate to declaring
+// the risk for it in the update graph
+type riskLatency struct {
+	Risk                   string    `json:"risk"`
+	ImpactStatementRequest string    `json:"impactStatementRequest"`
+	Bug                    string    `json:"bug"`
+	Labeled                time.Time `json:"labeled"`
+	Declared               time.Time `json:"declared"`
+
+	Latency time.Duration `json:"latency"`
+}
+
+// period summarizes the latencies of the risks declared in a calendar month
+type period struct {
+	Month string        `json:"month"`
+	Count int           `json:"count"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	Max   time.Duration `json:"max"`
+}
+
+type report struct {
+	Risks   []riskLatency `json:"risks"`
+	Periods []period      `json:"periods"`
+}
+
+// declaredRisks returns the time each risk was first declared on the checked out branch of the graph
+// repository, keyed by risk name
+func declaredRisks(repositoryPath string) (map[string]time.Time, error) {
+	added, err := gitutil.Repository{Path: repositoryPath}.Added(path.Join("blocked-edges", "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	declared := map[string]time.Time{}
+	for file, when := range added {
+		_, risk, err := graph.ParseEdgeFileName(file)
+		if err != nil {
+			logrus.WithError(err).Debugf("Skipping file %s", file)
+			continue
+		}
+		if first, ok := declared[risk]; !ok || when.Before(first) {
+			declared[risk] = when
+		}
+	}
+	return declared, nil
+}
+
+// labeled returns when the UpgradeBlocker label was first added to the bug, or a zero time when the
+// changelog does not show it
+func labeled(bug *jira.Issue) time.Time {
+	if bug.Changelog == nil {
+		return time.Time{}
+	}
+	var first time.Time
+	for _, history := range bug.Changelog.Histories {
+		for _, item := range history.Items {
+			if item.Field != "labels" {
+				continue
+			}
+			if strings.Contains(" "+item.FromString+" ", " "+updateblockers.LabelBlocker+" ") || !strings.Contains(" "+item.ToString+" ", " "+updateblockers.LabelBlocker+" ") {
+				continue
+			}
+			created, err := history.CreatedTime()
+			if err != nil {
+				logrus.WithError(err).Debugf("%s: cannot parse changelog time", bug.Key)
+				continue
+			}
+			if first.IsZero() || created.Before(first) {
+				first = created
+			}
+		}
+	}
+	return first
+}
+
+// measure finds the bugs linked to the impact statement request card and computes the latency from
+// the earliest time one of them was labeled as an upgrade blocker candidate
+func measure(client jirautil.Client, bugProjects flagutil.BugProjectsOptions, risk, isrKey string, declared time.Time) (*riskLatency, error) {
+	isr, err := client.GetIssue(isrKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var measured *riskLatency
+	for _, link := range isr.Fields.IssueLinks {
+		for _, linked := range []*jira.Issue{link.OutwardIssue, link.InwardIssue} {
+			if linked == nil || !bugProjects.IsBug(linked.Key) {
+				continue
+			}
+			bug, err := client.GetIssueWithChangelog(linked.Key)
+			if err != nil {
+				return nil, err
+			}
+			when := labeled(bug)
+			if when.IsZero() || (measured != nil && !when.Before(measured.Labeled)) {
+				continue
+			}
+			measured = &riskLatency{Risk: risk, ImpactStatementRequest: isrKey, Bug: bug.Key, Labeled: when, Declared: declared, Latency: declared.Sub(when)}
+		}
+	}
+	return measured, nil
+}
+
+// percentile returns the nearest-rank percentile of the sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func summarize(latencies []riskLatency) []period {
+	byMonth := map[string][]time.Duration{}
+	for _, latency := range latencies {
+		month := latency.Declared.Format("2006-01")
+		byMonth[month] = append(byMonth[month], latency.Latency)
+	}
+	var periods []period
+	for month, durations := range byMonth {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		periods = append(periods, period{
+			Month: month,
+			Count: len(durations),
+			P50:   percentile(durations, 50),
+			P90:   percentile(durations, 90),
+			Max:   durations[len(durations)-1],
+		})
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Month < periods[j].Month })
+	return periods
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota report latency
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	declared, err := declaredRisks(o.graphRepositoryPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read graph repository history")
+	}
+
+	index, err := graph.LoadIndex(o.graphRepositoryPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read graph repository")
+	}
+	urls := map[string]string{}
+	_ = index.Walk(graph.Filter{}, func(_ string, edge graph.ConditionallyBlockedEdge) error {
+		urls[edge.Name] = edge.URL
+		return nil
+	})
+
+	jiraClient, err := o.jira.Client()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create Jira client")
+	}
+
+	var risks []string
+	for risk, when := range declared {
+		if o.since == 0 || time.Since(when) <= o.since {
+			risks = append(risks, risk)
+		}
+	}
+	sort.Strings(risks)
+
+	var r report
+	for _, risk := range risks {
+		if err := ctx.Err(); err != nil {
+			exitcode.Fatal(exitcode.Interrupted, err, "cannot measure latencies")
+		}
+		url, ok := urls[risk]
+		if !ok {
+			logrus.Debugf("%s: risk is no longer declared, skipping", risk)
+			continue
+		}
+		if !strings.HasPrefix(url, graph.JiraBrowsePrefix) {
+			logrus.Warnf("%s: risk URL %s is not a Jira card, skipping", risk, url)
+			continue
+		}
+		isrKey := strings.TrimPrefix(url, graph.JiraBrowsePrefix)
+		logrus.Infof("%s: Obtaining the bugs linked to %s", risk, isrKey)
+		latency, err := measure(jiraClient, o.bugProjects, risk, isrKey, declared[risk])
+		if err != nil {
+			exitcode.JiraFatal(err, "cannot get issue")
+		}
+		if latency == nil {
+			logrus.Warnf("%s: no bug linked to %s was labeled %s, skipping", risk, isrKey, updateblockers.LabelBlocker)
+			continue
+		}
+		r.Risks = append(r.Risks, *latency)
+	}
+	r.Periods = summarize(r.Risks)
+
+	if o.output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r); err != nil {
+			logrus.WithError(err).Fatal("cannot encode report")
+		}
+		return
+	}
+
+	tabw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	_, _ = tabw.Write([]byte("RISK\tISR\tBUG\tLABELED\tDECLARED\tLATENCY\n"))
+	for _, latency := range r.Risks {
+		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\n", latency.Risk, latency.ImpactStatementRequest, latency.Bug, latency.Labeled.Format(time.DateOnly), latency.Declared.Format(time.DateOnly), timefmt.Duration(latency.Latency))))
+	}
+	_ = tabw.Flush()
+
+	fmt.Println()
+	tabw = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	_, _ = tabw.Write([]byte("MONTH\tRISKS\tP50\tP90\tMAX\n"))
+	for _, p := range r.Periods {
+		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%d\t%s\t%s\t%s\n", p.Month, p.Count, timefmt.Duration(p.P50), timefmt.Duration(p.P90), timefmt.Duration(p.Max))))
+	}
+	_ = tabw.Flush()
+}
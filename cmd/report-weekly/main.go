@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/exitcode"
+	"github.com/petr-muller/ota/internal/flagutil"
+	"github.com/petr-muller/ota/internal/gitutil"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/interrupt"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/releasecontroller"
+)
+
+const (
+	formatMarkdown = "markdown"
+	formatHTML     = "html"
+
+	jqlOpenImpactStatementRequests = "{{frag:impact_statement_requests}} AND statusCategory != Done ORDER BY created ASC"
+)
+
+type options struct {
+	graphRepositoryPath string
+	since               time.Duration
+	format              string
+
+	releaseControllerURL string
+	releaseStream        string
+
+	jira        flagutil.JiraOptions
+	bugProjects flagutil.BugProjectsOptions
+
+	log flagutil.LogOptions
+}
+
+func gatherOptions() options {
+	var o options
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	config.AddConfigDirFlag(fs)
+
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository (a git checkout of the branch served by OSUS)")
+	fs.DurationVar(&o.since, "since", 7*24*time.Hour, "Report the risks declared, extended and fixed in this period")
+	fs.StringVar(&o.format, "format", formatMarkdown, fmt.Sprintf("Report format: %s or %s", formatMarkdown, formatHTML))
+	fs.StringVar(&o.releaseControllerURL, "release-controller", releasecontroller.DefaultURL, "The release controller to learn about the accepted payloads from")
+	fs.StringVar(&o.releaseStream, "release-stream", "4-stable", "Report the payloads accepted into this release stream since the previous report (empty to skip)")
+
+	o.jira.AddFlags(fs)
+	o.bugProjects.AddFlags(fs)
+	o.log.AddFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		logrus.WithError(err).Fatalf("cannot parse args: '%s'", os.Args[1:])
+	}
+	if err := config.ApplyFlagDefaults(fs); err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot apply flag defaults")
+	}
+
+	return o
+}
+
+func (o *options) validate() error {
+	if err := o.log.Validate(); err != nil {
+		return err
+	}
+
+	if o.graphRepositoryPath == "" {
+		return fmt.Errorf("--graph-repository-path must be specified and nonempty")
+	}
+
+	if o.since <= 0 {
+		return fmt.Errorf("--since must be positive")
+	}
+
+	if o.format != formatMarkdown && o.format != formatHTML {
+		return fmt.Errorf("--format must be either %s or %s", formatMarkdown, formatHTML)
+	}
+
+	return o.jira.Validate()
+}
+
+// riskChange is a risk declared, extended or fixed in the reported period
+type riskChange struct {
+	Risk string
+	URL  string
+	// Versions are the versions the risk was declared or extended to, or the versions fixed in the period
+	Versions []string
+	FixedIn  []string
+
+	ImpactStatementRequest *jira.Issue
+}
+
+// outstandingRequest is an impact statement request card waiting for an answer
+type outstandingRequest struct {
+	Issue   *jira.Issue
+	Created time.Time
+}
+
+type report struct {
+	From time.Time
+	Now  time.Time
+
+	Declared []*riskChange
+	Extended []*riskChange
+	Fixed    []*riskChange
+
+	Outstanding []outstandingRequest
+
+	// ReleaseStream is empty when the releases are not reported
+	ReleaseStream string
+	// ReleasesSince is the time of the previous report; it is nil when there is no previous report to
+	// compare the accepted payloads against
+	ReleasesSince *time.Time
+	Releases      []string
+}
+
+// riskChanges reads the graph repository history and returns the risks first declared, extended to
+// new versions and fixed since the given time
+func riskChanges(repositoryPath string, from time.Time) (declared, extended, fixed []*riskChange, err error) {
+	repository := gitutil.Repository{Path: repositoryPath}
+	pathspec := path.Join("blocked-edges", "*.yaml")
+	added, err := repository.Added(pathspec)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	firstDeclared := map[string]time.Time{}
+	for file, when := range added {
+		if _, risk, err := graph.ParseEdgeFileName(file); err == nil {
+			if first, ok := firstDeclared[risk]; !ok || when.Before(first) {
+				firstDeclared[risk] = when
+			}
+		}
+	}
+
+	declaredByRisk, extendedByRisk, fixedByRisk := map[string]*riskChange{}, map[string]*riskChange{}, map[string]*riskChange{}
+	record := func(changes map[string]*riskChange, risk string) *riskChange {
+		if changes[risk] == nil {
+			changes[risk] = &riskChange{Risk: risk}
+		}
+		return changes[risk]
+	}
+
+	for file, when := range added {
+		version, risk, err := graph.ParseEdgeFileName(file)
+		if err != nil || when.Before(from) {
+			continue
+		}
+		changes := extendedByRisk
+		if !firstDeclared[risk].Before(from) {
+			changes = declaredByRisk
+		}
+		change := record(changes, risk)
+		change.Versions = append(change.Versions, version)
+	}
+
+	modified, err := repository.Modified(pathspec, from)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, file := range modified {
+		edge, err := graph.LoadEdge(filepath.Join(repositoryPath, file))
+		if err != nil {
+			// removed since
+			logrus.WithError(err).Debugf("Skipping file %s", file)
+			continue
+		}
+		if edge.FixedIn == "" {
+			continue
+		}
+		change := record(fixedByRisk, edge.Name)
+		change.Versions = append(change.Versions, edge.To)
+		change.FixedIn = append(change.FixedIn, edge.FixedIn)
+	}
+
+	return sorted(declaredByRisk), sorted(extendedByRisk), sorted(fixedByRisk), nil
+}
+
+func sorted(changes map[string]*riskChange) []*riskChange {
+	var all []*riskChange
+	for _, change := range changes {
+		sort.Strings(change.Versions)
+		change.FixedIn = sets.List(sets.New[string](change.FixedIn...))
+		all = append(all, change)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Risk < all[j].Risk })
+	return all
+}
+
+// linkImpactStatementRequests fills in the URLs of the risks and fetches the impact statement request
+// cards they point to
+func linkImpactStatementRequests(ctx context.Context, client jirautil.Client, index *graph.Index, changes []*riskChange) error {
+	urls := map[string]string{}
+	_ = index.Walk(graph.Filter{}, func(_ string, edge graph.ConditionallyBlockedEdge) error {
+		urls[edge.Name] = edge.URL
+		return nil
+	})
+
+	var keys []string
+	var linked []*riskChange
+	for _, change := range changes {
+		change.URL = urls[change.Risk]
+		if !strings.HasPrefix(change.URL, graph.JiraBrowsePrefix) {
+			continue
+		}
+		keys = append(keys, strings.TrimPrefix(change.URL, graph.JiraBrowsePrefix))
+		linked = append(linked, change)
+	}
+
+	isrs, err := jirautil.DefaultFetcher.GetIssues(ctx, client, keys)
+	if err != nil {
+		return err
+	}
+	for i, change := range linked {
+		change.ImpactStatementRequest = isrs[i]
+	}
+	return nil
+}
+
+func main() {
+	// TODO(muller): Cobrify as ota report weekly
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		exitcode.Fatal(exitcode.InvalidOptions, err, "invalid options")
+	}
+	o.log.Apply()
+	ctx := interrupt.Context()
+
+	r := report{Now: time.Now()}
+	r.From = r.Now.Add(-o.since)
+
+	var err error
+	logrus.Infof("Reading the graph repository history since %s", r.From.Local().Format(time.DateTime))
+	r.Declared, r.Extended, r.Fixed, err = riskChanges(o.graphRepositoryPath, r.From)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read graph repository history")
+	}
+
+	index, err := graph.LoadIndex(o.graphRepositoryPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot read graph repository")
+	}
+
+	jiraClient, err := o.jira.Client()
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create Jira client")
+	}
+
+	logrus.Info("Obtaining the impact statement request cards of the changed risks")
+	changes := append(append(append([]*riskChange{}, r.Declared...), r.Extended...), r.Fixed...)
+	if err := linkImpactStatementRequests(ctx, jiraClient, index, changes); err != nil {
+		exitcode.JiraFatal(err, "cannot get issue")
+	}
+
+	fragments, err := config.LoadJQLFragments()
+	if err != nil {
+		exitcode.Fatal(exitcode.Config, err, "cannot load JQL fragments")
+	}
+	query, err := fragments.Expand(jqlOpenImpactStatementRequests)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot expand JQL fragments")
+	}
+	logrus.Infof("Obtaining open impact statement requests matching '%s'", query)
+	open, err := jirautil.SearchAll(ctx, jiraClient, query)
+	if err != nil {
+		exitcode.JiraFatal(err, "Failed to query JIRA")
+	}
+	for i := range open {
+		r.Outstanding = append(r.Outstanding, outstandingRequest{Issue: &open[i], Created: time.Time(open[i].Fields.Created)})
+	}
+
+	if o.releaseStream != "" {
+		logrus.Infof("Obtaining the payloads accepted into %s", o.releaseStream)
+		if r.ReleasesSince, r.Releases, err = newReleases(ctx, releasecontroller.NewClient(o.releaseControllerURL), o.releaseStream, r.Now); err != nil {
+			logrus.WithError(err).Warn("Cannot obtain the accepted payloads, leaving them out of the report")
+		} else {
+			r.ReleaseStream = o.releaseStream
+		}
+	}
+
+	if err := writeReport(os.Stdout, o.format, r); err != nil {
+		logrus.WithError(err).Fatal("cannot render the report")
+	}
+}
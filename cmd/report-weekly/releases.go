@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/releasecontroller"
+)
+
+// releasesFileName is a file in the ota data directory that records the payloads accepted into each
+// release stream when the previous report was generated; the release controller does not tell when
+// the payloads were accepted
+const releasesFileName = "weekly-report-releases.json"
+
+type seenReleases struct {
+	Taken    time.Time `json:"taken"`
+	Accepted []string  `json:"accepted"`
+}
+
+func releasesPath() (string, error) {
+	dataDir, err := config.OtaDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, releasesFileName), nil
+}
+
+// newReleases returns the payloads accepted into the stream since the previous report and when that
+// report was generated, and records the currently accepted payloads for the next report. Without a
+// previous report, the returned time is nil.
+func newReleases(ctx context.Context, client *releasecontroller.Client, stream string, now time.Time) (*time.Time, []string, error) {
+	s, err := client.Stream(ctx, stream)
+	if err != nil {
+		return nil, nil, err
+	}
+	accepted := s.Accepted()
+
+	path, err := releasesPath()
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := map[string]seenReleases{}
+	raw, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, nil, fmt.Errorf("cannot read %s: %w", path, err)
+	default:
+		if err := json.Unmarshal(raw, &seen); err != nil {
+			return nil, nil, fmt.Errorf("cannot unmarshal %s: %w", path, err)
+		}
+	}
+
+	var since *time.Time
+	var fresh []string
+	if previous, ok := seen[stream]; ok {
+		since = &previous.Taken
+		known := sets.New[string](previous.Accepted...)
+		for _, name := range accepted {
+			if !known.Has(name) {
+				fresh = append(fresh, name)
+			}
+		}
+	}
+
+	seen[stream] = seenReleases{Taken: now, Accepted: accepted}
+	if raw, err = json.MarshalIndent(seen, "", "  "); err != nil {
+		return nil, nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return nil, nil, fmt.Errorf("cannot write %s: %w", path, err)
+	}
+	return since, fresh, nil
+}
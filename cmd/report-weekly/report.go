@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/graph"
+)
+
+// funcs are the template functions shared by both report formats
+func funcs(r report) map[string]any {
+	return map[string]any{
+		"browse": func(key string) string { return graph.JiraBrowsePrefix + key },
+		"date":   func(t time.Time) string { return t.Local().Format(time.DateOnly) },
+		"join":   strings.Join,
+		"status": func(issue *jira.Issue) string {
+			if issue.Fields.Status == nil {
+				return ""
+			}
+			return issue.Fields.Status.Name
+		},
+		"assignee": func(issue *jira.Issue) string {
+			if issue.Fields.Assignee == nil {
+				return "unassigned"
+			}
+			return issue.Fields.Assignee.DisplayName
+		},
+		"outstanding": func(created time.Time) string { return blockerflow.FormatOutstanding(created, r.Now) },
+		"overdue":     func(created time.Time) bool { return blockerflow.Overdue(created, r.Now) },
+	}
+}
+
+// writeReport renders the report in the given format
+func writeReport(w io.Writer, format string, r report) error {
+	switch format {
+	case formatMarkdown:
+		tmpl, err := template.New(format).Funcs(funcs(r)).Parse(markdownTemplate)
+		if err != nil {
+			return fmt.Errorf("cannot parse report template: %w", err)
+		}
+		return tmpl.Execute(w, r)
+	case formatHTML:
+		tmpl, err := htmltemplate.New(format).Funcs(funcs(r)).Parse(htmlTemplate)
+		if err != nil {
+			return fmt.Errorf("cannot parse report template: %w", err)
+		}
+		return tmpl.Execute(w, r)
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+const markdownTemplate = `{{ define "isr" }}{{ with .ImpactStatementRequest }} ([{{ .Key }}]({{ browse .Key }}): {{ .Fields.Summary }}, {{ status . }}){{ end }}{{ end -}}
+{{ define "risk" }}{{ if .URL }}[{{ .Risk }}]({{ .URL }}){{ else }}{{ .Risk }}{{ end }}{{ end -}}
+# Update risks report {{ date .From }} – {{ date .Now }}
+
+## New risks declared ({{ len .Declared }})
+{{ range .Declared }}
+- {{ template "risk" . }} declared for {{ join .Versions ", " }}{{ template "isr" . }}
+{{- else }}
+No new risks.
+{{- end }}
+
+## Risks extended ({{ len .Extended }})
+{{ range .Extended }}
+- {{ template "risk" . }} extended to {{ join .Versions ", " }}{{ template "isr" . }}
+{{- else }}
+No risks extended.
+{{- end }}
+
+## Risks fixed ({{ len .Fixed }})
+{{ range .Fixed }}
+- {{ template "risk" . }} fixed in {{ join .FixedIn ", " }}{{ template "isr" . }}
+{{- else }}
+No risks fixed.
+{{- end }}
+
+## Outstanding impact statement requests ({{ len .Outstanding }})
+{{ range .Outstanding }}
+- [{{ .Issue.Key }}]({{ browse .Issue.Key }}): {{ .Issue.Fields.Summary }} ({{ assignee .Issue }}, {{ status .Issue }}, {{ if overdue .Created }}**{{ outstanding .Created }}**{{ else }}{{ outstanding .Created }}{{ end }})
+{{- else }}
+No outstanding requests.
+{{- end }}
+{{ with .ReleaseStream }}
+## Payloads accepted into {{ . }}
+{{ with $.ReleasesSince }}
+Since the previous report on {{ date . }}: {{ if $.Releases }}{{ join $.Releases ", " }}{{ else }}none{{ end }}
+{{- else }}
+There is no previous report to compare the accepted payloads against; the next report will list them.
+{{- end }}
+{{ end }}`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Update risks report {{ date .From }} – {{ date .Now }}</title></head>
+<body style="font-family: sans-serif;">
+{{- define "isr" }}{{ with .ImpactStatementRequest }} (<a href="{{ browse .Key }}">{{ .Key }}</a>: {{ .Fields.Summary }}, {{ status . }}){{ end }}{{ end }}
+{{- define "risk" }}{{ if .URL }}<a href="{{ .URL }}">{{ .Risk }}</a>{{ else }}{{ .Risk }}{{ end }}{{ end }}
+<h1>Update risks report {{ date .From }} – {{ date .Now }}</h1>
+<h2>New risks declared ({{ len .Declared }})</h2>
+{{- if .Declared }}
+<ul>
+{{- range .Declared }}
+<li>{{ template "risk" . }} declared for {{ join .Versions ", " }}{{ template "isr" . }}</li>
+{{- end }}
+</ul>
+{{- else }}
+<p>No new risks.</p>
+{{- end }}
+<h2>Risks extended ({{ len .Extended }})</h2>
+{{- if .Extended }}
+<ul>
+{{- range .Extended }}
+<li>{{ template "risk" . }} extended to {{ join .Versions ", " }}{{ template "isr" . }}</li>
+{{- end }}
+</ul>
+{{- else }}
+<p>No risks extended.</p>
+{{- end }}
+<h2>Risks fixed ({{ len .Fixed }})</h2>
+{{- if .Fixed }}
+<ul>
+{{- range .Fixed }}
+<li>{{ template "risk" . }} fixed in {{ join .FixedIn ", " }}{{ template "isr" . }}</li>
+{{- end }}
+</ul>
+{{- else }}
+<p>No risks fixed.</p>
+{{- end }}
+<h2>Outstanding impact statement requests ({{ len .Outstanding }})</h2>
+{{- if .Outstanding }}
+<ul>
+{{- range .Outstanding }}
+<li><a href="{{ browse .Issue.Key }}">{{ .Issue.Key }}</a>: {{ .Issue.Fields.Summary }} ({{ assignee .Issue }}, {{ status .Issue }}, {{ if overdue .Created }}<strong style="color: #c00;">{{ outstanding .Created }}</strong>{{ else }}{{ outstanding .Created }}{{ end }})</li>
+{{- end }}
+</ul>
+{{- else }}
+<p>No outstanding requests.</p>
+{{- end }}
+{{- with .ReleaseStream }}
+<h2>Payloads accepted into {{ . }}</h2>
+{{- with $.ReleasesSince }}
+<p>Since the previous report on {{ date . }}: {{ if $.Releases }}{{ join $.Releases ", " }}{{ else }}none{{ end }}</p>
+{{- else }}
+<p>There is no previous report to compare the accepted payloads against; the next report will list them.</p>
+{{- end }}
+{{- end }}
+</body>
+</html>
+`
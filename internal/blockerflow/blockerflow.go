@@ -0,0 +1,682 @@
+// Package blockerflow implements the upgrade blocker workflow over a blocker candidate: a bug, its impact statement request (ISR) card and the conditional risks declared for it. The
+// operations are implemented once here so that all commands apply the same labels, links,
+// comments, transitions and hooks.
+package blockerflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/hooks"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/notify"
+	"github.com/petr-muller/ota/internal/updateblockers"
+)
+
+const (
+	// impactStatementRequestType is the issue type of the impact statement request cards
+	impactStatementRequestType = "Spike"
+	// defaultImpactStatementRequestPriority is the priority of new impact statement request cards
+	defaultImpactStatementRequestPriority = "Critical"
+)
+
+// otaLabels are all labels the workflow manages on the bug
+var otaLabels = []string{
+	updateblockers.LabelBlocker,
+	updateblockers.LabelImpactStatementRequested,
+	updateblockers.LabelImpactStatementProposed,
+	updateblockers.LabelKnownIssueAnnounced,
+}
+
+// Candidate is a blocker candidate: a bug together with its impact statement request card and
+// the conditional risks declared for it
+type Candidate struct {
+	Bug *jira.Issue
+
+	// ImpactStatementRequest is nil when no ISR card is linked or when the linked cards are ambiguous
+	ImpactStatementRequest *jira.Issue
+	// ImpactStatementRequestCandidates holds all cards linked to the bug that may be its ISR
+	ImpactStatementRequestCandidates []*jira.Issue
+
+	// Risks holds the blocked edges that reference the ISR card, see FindRisks
+	Risks []graph.ConditionallyBlockedEdge
+}
+
+// Client is the subset of the Jira client the workflow operations need
+type Client interface {
+	updateblockers.TransitionClient
+
+	GetIssue(id string) (*jira.Issue, error)
+	CreateIssue(issue *jira.Issue) (*jira.Issue, error)
+	UpdateIssue(issue *jira.Issue) (*jira.Issue, error)
+	CreateIssueLink(link *jira.IssueLink) error
+	AddComment(issueID string, comment *jira.Comment) (*jira.Comment, error)
+	GetSelf() (*jira.User, error)
+	GetProjectMetadata(key string) (*jirautil.ProjectMetadata, error)
+	DeleteComment(issueID, commentID string) error
+	DeleteIssue(key string) error
+}
+
+// Flow performs the workflow operations on blocker candidates
+type Flow struct {
+	Client       Client
+	Transitioner *updateblockers.Transitioner
+	Hooks        *hooks.Runner
+	// Notifier announces the workflow events (impact statement requested and proposed, risk declared);
+	// no notifications are sent when it is nil
+	Notifier *notify.Notifier
+
+	// Visibility restricts the comments the workflow posts
+	Visibility jira.CommentVisibility
+	// DescriptionTemplate renders the description of new impact statement request cards
+	DescriptionTemplate *template.Template
+	// Priority is the priority of new impact statement request cards; Critical when empty
+	Priority string
+	// Epics are the epics that new impact statement request cards are linked to, by component project
+	Epics config.Epics
+	// Epic is the epic that new impact statement request cards are linked to in any project; it
+	// overrides Epics
+	Epic string
+	// BugProjects are the Jira projects whose cards are bugs; linked cards in other projects may be
+	// impact statement requests
+	BugProjects []string
+
+	// author is the user associated with the client, looked up with the first comment
+	author *jira.User
+	// journal records the changes of the current operation
+	journal *Journal
+}
+
+// NewFlow loads the transitions and hooks configuration and returns a Flow using the given client
+func NewFlow(client Client) (*Flow, error) {
+	transitioner, err := updateblockers.LoadTransitioner()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load transitions config: %w", err)
+	}
+
+	hookRunner, err := hooks.Load()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load hooks config: %w", err)
+	}
+
+	comments, err := config.LoadComments()
+	if err != nil {
+		return nil, err
+	}
+
+	description, err := LoadDescriptionTemplate("")
+	if err != nil {
+		return nil, err
+	}
+
+	epics, err := config.LoadEpics()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Flow{
+		Client:       client,
+		Transitioner: transitioner,
+		Hooks:        hookRunner,
+		Visibility:   jira.CommentVisibility{Type: comments.Visibility.Type, Value: comments.Visibility.Value},
+
+		DescriptionTemplate: description,
+		Epics:               epics,
+		BugProjects:         []string{jirautil.DefaultBugProject},
+	}, nil
+}
+
+// Load obtains the bug and discovers its impact statement request card among the linked Spike
+// cards. When the discovery is not conclusive, impactStatementRequestCard (if nonempty) is used to
+// select or fetch the ISR card.
+func (f *Flow) Load(bugKey, impactStatementRequestCard string) (*Candidate, error) {
+	logrus.Infof("Obtaining issue %s", bugKey)
+	bug, err := f.Client.GetIssue(bugKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get issue %s: %w", bugKey, err)
+	}
+
+	c := &Candidate{Bug: bug}
+	for _, link := range bug.Fields.IssueLinks {
+		// TODO(muller): Handle non-spikes (interactively?)
+		if outward := link.OutwardIssue; IsImpactStatementRequestCandidate(outward, f.BugProjects) {
+			logrus.Infof("%s is a potential impact statement request (%s %s %s)", outward.Key, bug.Key, link.Type.Outward, outward.Key)
+			c.ImpactStatementRequestCandidates = append(c.ImpactStatementRequestCandidates, outward)
+		}
+		if inward := link.InwardIssue; IsImpactStatementRequestCandidate(inward, f.BugProjects) {
+			logrus.Infof("%s is a potential impact statement request (%s %s %s)", inward.Key, bug.Key, link.Type.Inward, inward.Key)
+			c.ImpactStatementRequestCandidates = append(c.ImpactStatementRequestCandidates, inward)
+		}
+	}
+
+	switch len(c.ImpactStatementRequestCandidates) {
+	case 0:
+		logrus.Warning("No impact statement requests found")
+		if impactStatementRequestCard != "" {
+			logrus.Infof("%s: Attempting to get the impact statement request card", impactStatementRequestCard)
+			if isr, err := f.Client.GetIssue(impactStatementRequestCard); err == nil {
+				c.ImpactStatementRequest = isr
+			} else {
+				logrus.WithError(err).Error("Cannot get the impact statement request card")
+			}
+		}
+	case 1:
+		c.ImpactStatementRequest = c.ImpactStatementRequestCandidates[0]
+		logrus.Infof("Found a single impact statement request: %s %s", c.ImpactStatementRequest.Key, c.ImpactStatementRequest.Fields.Summary)
+	default:
+		logrus.Infof("Found multiple possible impact statement requests:")
+		for _, candidate := range c.ImpactStatementRequestCandidates {
+			var selected string
+			if candidate.Key == impactStatementRequestCard {
+				c.ImpactStatementRequest = candidate
+				selected = " (selected)"
+			}
+			logrus.Infof("  %s: %s%s", candidate.Key, candidate.Fields.Summary, selected)
+		}
+		if impactStatementRequestCard == "" {
+			logrus.Infof("Rerun and pass the correct one with --impact-statement-card:")
+		}
+	}
+
+	return c, nil
+}
+
+// IsImpactStatementRequestCandidate returns true if the card linked to a bug may be its impact statement
+// request: a Spike outside of the bug projects
+func IsImpactStatementRequestCandidate(issue *jira.Issue, bugProjects []string) bool {
+	return issue != nil && !jirautil.InProjects(issue.Key, bugProjects) && issue.Fields != nil && issue.Fields.Type.Name == impactStatementRequestType
+}
+
+// LinkedImpactStatementRequest returns the key of the first impact statement request candidate linked
+// to the bug, if any
+func LinkedImpactStatementRequest(bug *jira.Issue, bugProjects []string) string {
+	for _, link := range bug.Fields.IssueLinks {
+		for _, linked := range []*jira.Issue{link.OutwardIssue, link.InwardIssue} {
+			if IsImpactStatementRequestCandidate(linked, bugProjects) {
+				return linked.Key
+			}
+		}
+	}
+	return ""
+}
+
+// RiskFinder returns the blocked edges whose risk references the given URL
+type RiskFinder func(ctx context.Context, url string) ([]graph.ConditionallyBlockedEdge, error)
+
+// FindRisks populates Risks with the blocked edges that reference the impact statement request card
+func (c *Candidate) FindRisks(ctx context.Context, find RiskFinder) error {
+	if c.ImpactStatementRequest == nil {
+		return nil
+	}
+
+	logrus.Infof("Looking for conditional risk that links to %s", c.ImpactStatementRequest.Key)
+	risks, err := find(ctx, graph.JiraBrowsePrefix+c.ImpactStatementRequest.Key)
+	if err != nil {
+		return err
+	}
+	c.Risks = risks
+	return nil
+}
+
+// hookData returns the hook payload data describing the candidate
+func (c *Candidate) hookData() map[string]string {
+	data := map[string]string{"bug": c.Bug.Key}
+	if c.ImpactStatementRequest != nil {
+		data["impactStatementRequest"] = c.ImpactStatementRequest.Key
+	}
+	return data
+}
+
+// notifySource is the notification source of the workflow events
+const notifySource = "workflow"
+
+// notify announces the workflow event about the bug. Delivery failures are logged by the Notifier and
+// do not fail the action, which already happened.
+func (f *Flow) notify(event hooks.Event, bug *jira.Issue, title string, details []string, data map[string]string) {
+	if f.Notifier == nil {
+		return
+	}
+	body := append([]string{fmt.Sprintf("%s: %s", bug.Key, bug.Fields.Summary)}, details...)
+	_ = f.Notifier.Notify(notify.Message{
+		Source: notifySource,
+		Event:  string(event),
+		Title:  title,
+		Body:   strings.Join(body, "\n"),
+		URL:    graph.JiraBrowsePrefix + bug.Key,
+		Data:   data,
+	})
+}
+
+func (f *Flow) setLabels(issue *jira.Issue, labels sets.Set[string]) error {
+	f.record(JournalEntry{Kind: changeLabels, Issue: issue.Key, Labels: issue.Fields.Labels})
+	if _, err := f.Client.UpdateIssue(&jira.Issue{
+		Key:    issue.Key,
+		Fields: &jira.IssueFields{Labels: sets.List(labels)},
+	}); err != nil {
+		return fmt.Errorf("cannot update issue %s: %w", issue.Key, err)
+	}
+	issue.Fields.Labels = sets.List(labels)
+	return nil
+}
+
+func (f *Flow) comment(issue *jira.Issue, body string) error {
+	if f.author == nil {
+		author, err := f.Client.GetSelf()
+		if err != nil {
+			return fmt.Errorf("cannot obtain the user associated with the Jira client: %w", err)
+		}
+		f.author = author
+	}
+
+	comment := &jira.Comment{
+		Author:     *f.author,
+		Body:       body,
+		Visibility: f.Visibility,
+	}
+	added, err := f.Client.AddComment(issue.ID, comment)
+	if err != nil {
+		return fmt.Errorf("cannot create comment on %s: %w", issue.Key, err)
+	}
+	f.record(JournalEntry{Kind: changeComment, Issue: issue.Key, IssueID: issue.ID, CommentID: added.ID})
+	return nil
+}
+
+// impactStatementRequestSummary returns the summary of the impact statement request card for the bug
+func impactStatementRequestSummary(bug *jira.Issue) string {
+	return fmt.Sprintf("Impact statement request for %s %s", bug.Key, bug.Fields.Summary)
+}
+
+// CheckImpactStatementRequest returns an error when the bug should not get an impact statement request:
+// when it is not labeled as an upgrade blocker candidate, when the impact statement was already requested
+// or proposed, or when an open impact statement request card is already linked to it
+func (f *Flow) CheckImpactStatementRequest(c *Candidate) error {
+	bug := c.Bug
+	labels := sets.New[string](bug.Fields.Labels...)
+	if !labels.Has(updateblockers.LabelBlocker) {
+		return fmt.Errorf("%s does not have the %s label", bug.Key, updateblockers.LabelBlocker)
+	}
+	for _, label := range []string{updateblockers.LabelImpactStatementRequested, updateblockers.LabelImpactStatementProposed} {
+		if labels.Has(label) {
+			return fmt.Errorf("%s already has the %s label", bug.Key, label)
+		}
+	}
+	if err := updateblockers.ValidateTransition(updateblockers.StageOf(bug.Fields.Labels), updateblockers.StageImpactStatementRequested); err != nil {
+		return fmt.Errorf("%s: %w", bug.Key, err)
+	}
+
+	summary := impactStatementRequestSummary(bug)
+	for _, link := range bug.Fields.IssueLinks {
+		for _, linked := range []*jira.Issue{link.OutwardIssue, link.InwardIssue} {
+			if !IsImpactStatementRequestCandidate(linked, f.BugProjects) || linked.Fields.Summary != summary {
+				continue
+			}
+			if status := linked.Fields.Status; status != nil && status.StatusCategory.Key == jira.StatusCategoryComplete {
+				continue
+			}
+			return fmt.Errorf("%s already has an open impact statement request %s", bug.Key, linked.Key)
+		}
+	}
+	return nil
+}
+
+// transition moves the issue to the state and records its previous status in the journal
+func (f *Flow) transition(issue *jira.Issue, state updateblockers.State) (string, error) {
+	if issue.Fields != nil && issue.Fields.Status != nil {
+		f.record(JournalEntry{Kind: changeTransition, Issue: issue.Key, Status: issue.Fields.Status.Name})
+	}
+	return f.Transitioner.Transition(f.Client, issue, state)
+}
+
+// copyVersions copies the Affects Version/s and Target Version of the bug to the fields of the new
+// impact statement request card, so that the component team sees which releases are in play. Versions
+// are project-specific, so only the versions the project also has are copied, and only when its Spikes
+// have the fields.
+func copyVersions(bug *jira.Issue, project *jirautil.ProjectMetadata, fields *jira.IssueFields) {
+	var skipped []string
+	if project.HasField(impactStatementRequestType, "versions") {
+		for _, version := range bug.Fields.AffectsVersions {
+			if !project.HasVersion(version.Name) {
+				skipped = append(skipped, version.Name)
+				continue
+			}
+			fields.AffectsVersions = append(fields.AffectsVersions, &jira.AffectsVersion{Name: version.Name})
+		}
+	}
+
+	targets, err := jirautil.GetIssueTargetVersion(bug)
+	if err != nil {
+		logrus.WithError(err).Warnf("Cannot read the Target Version of %s", bug.Key)
+	}
+	if project.HasField(impactStatementRequestType, jirautil.TargetVersionField) {
+		var versions []*jira.Version
+		for _, version := range targets {
+			if !project.HasVersion(version.Name) {
+				skipped = append(skipped, version.Name)
+				continue
+			}
+			versions = append(versions, &jira.Version{Name: version.Name})
+		}
+		if len(versions) > 0 {
+			fields.Unknowns[jirautil.TargetVersionField] = versions
+		}
+	}
+
+	if len(skipped) > 0 {
+		logrus.Warnf("Project %s has no versions %s, not copying them from %s", project.Key, strings.Join(skipped, ", "), bug.Key)
+	}
+}
+
+// epic returns the epic that new impact statement request cards in the project are linked to
+func (f *Flow) epic(project string) string {
+	if f.Epic != "" {
+		return f.Epic
+	}
+	return f.Epics.For(project)
+}
+
+// RequestImpactStatement creates an impact statement request Spike card in the component project
+// (linked to the project's epic, if any), links it to the bug, informs the bug assignee and labels the
+// bug. Unless force is set, the bug must pass CheckImpactStatementRequest.
+func (f *Flow) RequestImpactStatement(c *Candidate, componentProject string, force bool) error {
+	if err := f.CheckImpactStatementRequest(c); err != nil {
+		if !force {
+			return err
+		}
+		logrus.WithError(err).Warn("Requesting the impact statement anyway")
+	}
+
+	project, err := f.Client.GetProjectMetadata(componentProject)
+	if err != nil {
+		return fmt.Errorf("cannot validate project %s: %w", componentProject, err)
+	}
+	if err := project.ValidateIssueType(impactStatementRequestType); err != nil {
+		return err
+	}
+	priority := f.Priority
+	if priority == "" {
+		priority = defaultImpactStatementRequestPriority
+	}
+	if err := project.ValidatePriority(priority); err != nil {
+		return err
+	}
+	componentProject = project.Key
+
+	bug := c.Bug
+	description, err := renderDescription(f.DescriptionTemplate, bug)
+	if err != nil {
+		return err
+	}
+
+	assignee := bug.Fields.Assignee
+	if assignee == nil {
+		logrus.Warnf("Issue %s has no assignee", bug.Key)
+	} else {
+		logrus.Infof("Issue %s is assigned to %s", bug.Key, assignee.Name)
+	}
+
+	impactStatementRequest := jira.Issue{
+		Fields: &jira.IssueFields{
+			Type:        jira.IssueType{Name: impactStatementRequestType},
+			Project:     jira.Project{Key: componentProject},
+			Priority:    &jira.Priority{Name: priority},
+			Labels:      []string{updateblockers.LabelBlocker},
+			Description: description,
+			Summary:     impactStatementRequestSummary(bug),
+		},
+	}
+	if assignee != nil {
+		impactStatementRequest.Fields.Assignee = assignee
+	}
+	impactStatementRequest.Fields.Unknowns = map[string]interface{}{}
+	copyVersions(bug, project, impactStatementRequest.Fields)
+
+	hookData := map[string]string{"bug": bug.Key, "project": componentProject}
+	if epic := f.epic(componentProject); epic != "" {
+		logrus.Infof("The impact statement request will be linked to epic %s", epic)
+		impactStatementRequest.Fields.Unknowns[jirautil.EpicLinkField] = epic
+		hookData["epic"] = epic
+	}
+	if err := f.Hooks.Pre(hooks.EventImpactStatementRequested, hookData); err != nil {
+		return fmt.Errorf("pre-action hook failed: %w", err)
+	}
+	f.begin(string(hooks.EventImpactStatementRequested), bug)
+
+	logrus.Infof("Creating impact statement request Spike card in %s project", componentProject)
+	isrIssue, err := f.Client.CreateIssue(&impactStatementRequest)
+	if err != nil {
+		return fmt.Errorf("cannot create impact statement request: %w", err)
+	}
+	c.ImpactStatementRequest = isrIssue
+	f.record(JournalEntry{Kind: changeCreated, Issue: isrIssue.Key, IssueID: isrIssue.ID})
+
+	logrus.Infof("Creating a '%s blocks %s' link between the cards", isrIssue.Key, bug.Key)
+	blockLink := jira.IssueLink{
+		OutwardIssue: &jira.Issue{ID: bug.ID},
+		InwardIssue:  &jira.Issue{ID: isrIssue.ID},
+		Type: jira.IssueLinkType{
+			Name:    "Blocks",
+			Inward:  "is blocked by",
+			Outward: "blocks",
+		},
+	}
+	if err := f.Client.CreateIssueLink(&blockLink); err != nil {
+		return fmt.Errorf("cannot create issue link: %w", err)
+	}
+
+	logrus.Infof("Adding an informative comment to %s card", bug.Key)
+	var assigneeComment string
+	if assignee != nil {
+		assigneeComment = fmt.Sprintf(" and assigned it to [~%s] (this card's assignee)", assignee.Name)
+	}
+	if err := f.comment(bug, fmt.Sprintf(
+		"This card has been labeled as a potential upgrade risk with an {{UpgradeBlock}} label. We have created a card %s to help us understand the impact of the bug so that we can warn exposed cluster owners about it before they upgrade to an affected OCP version%s. The card simply asks for answers to several questions and should not require too much time to answer.",
+		isrIssue.Key, assigneeComment,
+	)); err != nil {
+		return err
+	}
+
+	logrus.Infof("Adding the ImpactStatementRequested label to %s card", bug.Key)
+	labels := sets.New[string](bug.Fields.Labels...).Insert(updateblockers.LabelImpactStatementRequested, updateblockers.LabelBlocker)
+	if err := f.setLabels(bug, labels); err != nil {
+		return err
+	}
+
+	hookData["impactStatementRequest"] = isrIssue.Key
+	f.Hooks.Post(hooks.EventImpactStatementRequested, hookData)
+	f.notify(hooks.EventImpactStatementRequested, bug, fmt.Sprintf("Impact statement requested for %s", bug.Key),
+		[]string{fmt.Sprintf("Impact statement request: %s%s", graph.JiraBrowsePrefix, isrIssue.Key)}, hookData)
+	return nil
+}
+
+// checkStage returns an error when the bug should not move to the lifecycle stage. With force, the
+// problem is only logged.
+func checkStage(bug *jira.Issue, to updateblockers.Stage, force bool) error {
+	err := updateblockers.ValidateTransition(updateblockers.StageOf(bug.Fields.Labels), to)
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("%s: %w", bug.Key, err)
+	if !force {
+		return err
+	}
+	logrus.WithError(err).Warn("Moving the bug anyway")
+	return nil
+}
+
+// MarkProposed marks the bug as having a proposed impact statement and moves the ISR card to review.
+// Unless force is set, the impact statement must have been requested.
+func (f *Flow) MarkProposed(c *Candidate, force bool) error {
+	if err := checkStage(c.Bug, updateblockers.StageImpactStatementProposed, force); err != nil {
+		return err
+	}
+
+	hookData := c.hookData()
+	if err := f.Hooks.Pre(hooks.EventImpactStatementProposed, hookData); err != nil {
+		return fmt.Errorf("pre-action hook failed: %w", err)
+	}
+	f.begin(string(hooks.EventImpactStatementProposed), c.Bug)
+
+	// TODO(muller): Actually add a comment - but only if we actually change some state
+	logrus.Infof("%s: Removing %s and adding %s", c.Bug.Key, updateblockers.LabelImpactStatementRequested, updateblockers.LabelImpactStatementProposed)
+	labels := sets.New[string](c.Bug.Fields.Labels...).Delete(updateblockers.LabelImpactStatementRequested).Insert(updateblockers.LabelImpactStatementProposed)
+	if err := f.setLabels(c.Bug, labels); err != nil {
+		return err
+	}
+
+	// TODO(muller): Actually add a comment - but only if we actually change some state
+	if isr := c.ImpactStatementRequest; isr != nil {
+		logrus.Infof("%s: Moving Impact Statement Request card to review", isr.Key)
+		status, err := f.transition(isr, updateblockers.StateReview)
+		if err != nil {
+			return fmt.Errorf("failed to move impact statement request card to review: %w", err)
+		}
+		logrus.Infof("%s: Moved Impact Statement Request card to %s", isr.Key, status)
+	}
+
+	f.Hooks.Post(hooks.EventImpactStatementProposed, hookData)
+	var details []string
+	if isr := c.ImpactStatementRequest; isr != nil {
+		details = append(details, fmt.Sprintf("Impact statement request: %s%s", graph.JiraBrowsePrefix, isr.Key))
+	}
+	f.notify(hooks.EventImpactStatementProposed, c.Bug, fmt.Sprintf("Impact statement proposed for %s", c.Bug.Key), details, hookData)
+	return nil
+}
+
+// AnnounceKnownIssue marks the bug as a known issue with a conditional risk declared in the update graph,
+// closes the ISR card and comments on both cards with the details of the risk. The risk details are
+// taken from Risks, so FindRisks should be called first. Unless force is set, the impact statement
+// must have been requested or proposed.
+func (f *Flow) AnnounceKnownIssue(c *Candidate, force bool) error {
+	if err := checkStage(c.Bug, updateblockers.StageKnownIssueAnnounced, force); err != nil {
+		return err
+	}
+
+	var riskName, riskSummary string
+	if len(c.Risks) > 0 {
+		riskName, riskSummary = c.Risks[0].Name, c.Risks[0].Message
+	}
+
+	hookData := c.hookData()
+	if err := f.Hooks.Pre(hooks.EventKnownIssueAnnounced, hookData); err != nil {
+		return fmt.Errorf("pre-action hook failed: %w", err)
+	}
+	bug := c.Bug
+	f.begin(string(hooks.EventKnownIssueAnnounced), bug)
+
+	logrus.Infof("%s: Removing %s,%s (if present) and adding %s,%s", bug.Key, updateblockers.LabelImpactStatementRequested, updateblockers.LabelImpactStatementProposed, updateblockers.LabelKnownIssueAnnounced, updateblockers.LabelBlocker)
+	labels := sets.New[string](bug.Fields.Labels...).Delete(updateblockers.LabelImpactStatementRequested, updateblockers.LabelImpactStatementProposed).Insert(updateblockers.LabelKnownIssueAnnounced, updateblockers.LabelBlocker)
+	if err := f.setLabels(bug, labels); err != nil {
+		return err
+	}
+
+	if isr := c.ImpactStatementRequest; isr != nil {
+		logrus.Infof("%s: Labelling Impact Statement Request card with %s for searchability", isr.Key, updateblockers.LabelBlocker)
+		if err := f.setLabels(isr, sets.New[string](isr.Fields.Labels...).Insert(updateblockers.LabelBlocker)); err != nil {
+			return err
+		}
+
+		logrus.Infof("%s: Closing Impact Statement Request card", isr.Key)
+		status, err := f.transition(isr, updateblockers.StateClosed)
+		if err != nil {
+			return fmt.Errorf("failed to close impact statement request card: %w", err)
+		}
+		logrus.Infof("%s: Moved Impact Statement Request card to %s", isr.Key, status)
+
+		logrus.Infof("%s: Adding an informative comment to bug card", bug.Key)
+		if err := f.comment(bug, fmt.Sprintf(`Based on the impact assessment %s, known issue / conditional risk for this bug was added to the update graph. {{%s}}, {{%s}} labels were added to this card. {{%s}}, {{%s}}, labels were removed if they were present.
+
+Details of the conditional risk:
+
+* *Name:* {{%s}}
+* *Summary:* %s`,
+			isr.Key,
+			updateblockers.LabelKnownIssueAnnounced, updateblockers.LabelBlocker, updateblockers.LabelImpactStatementRequested, updateblockers.LabelImpactStatementProposed,
+			riskName, riskSummary)); err != nil {
+			return err
+		}
+
+		logrus.Infof("%s: Adding an informative comment to impact statement card", isr.Key)
+		if err := f.comment(isr, fmt.Sprintf(`Based on the impact assessment, known issue / conditional risk for this bug was added to the update graph. {{%s}} label was added to this card for searchability.
+
+This card has been closed. _Note this does not mean the bug is resolved, only that its impact is understood enough for setting up a conditional risk in the update graph. Please refer to %s and its clones for information about fix state in particular versions._
+
+----
+
+Details of the conditional risk:
+
+* *Name:* {{%s}}
+* *Summary:* %s`,
+			updateblockers.LabelBlocker, bug.Key, riskName, riskSummary)); err != nil {
+			return err
+		}
+	}
+
+	hookData["risk"] = riskName
+	f.Hooks.Post(hooks.EventKnownIssueAnnounced, hookData)
+	title, details := fmt.Sprintf("Known issue announced for %s", bug.Key), []string(nil)
+	if riskName != "" {
+		title = fmt.Sprintf("Conditional risk %s declared for %s", riskName, bug.Key)
+		details = append(details, fmt.Sprintf("Risk summary: %s", riskSummary))
+	}
+	f.notify(hooks.EventKnownIssueAnnounced, bug, title, details, hookData)
+	return nil
+}
+
+// ClearLabels removes all workflow labels from the bug
+func (f *Flow) ClearLabels(c *Candidate) error {
+	// TODO(muller): Actually add a comment
+	hookData := map[string]string{"bug": c.Bug.Key}
+	if err := f.Hooks.Pre(hooks.EventLabelsCleared, hookData); err != nil {
+		return fmt.Errorf("pre-action hook failed: %w", err)
+	}
+	f.begin(string(hooks.EventLabelsCleared), c.Bug)
+
+	toRemove := sets.New[string](otaLabels...)
+	logrus.Infof("Clearing OTA labels (%s) from %s card", strings.Join(sets.List(toRemove), ","), c.Bug.Key)
+	if err := f.setLabels(c.Bug, sets.New[string](c.Bug.Fields.Labels...).Difference(toRemove)); err != nil {
+		return err
+	}
+
+	f.Hooks.Post(hooks.EventLabelsCleared, hookData)
+	return nil
+}
+
+// AnnouncePullRequest comments on the impact statement request card with the URL of the graph
+// repository pull request that changes the risk declared for it
+func (f *Flow) AnnouncePullRequest(isr *jira.Issue, url string) error {
+	logrus.Infof("Commenting on %s with the pull request URL", isr.Key)
+	return f.comment(isr, fmt.Sprintf("The conditional risk for this card is being changed in the update graph: %s", url))
+}
+
+// RemindImpactStatement comments on the impact statement request card of the candidate with a reminder
+// that the impact statement is still needed, mentioning the assignee of the card (or of the bug when
+// the card is not assigned)
+func (f *Flow) RemindImpactStatement(c *Candidate) error {
+	isr := c.ImpactStatementRequest
+	if isr == nil {
+		return fmt.Errorf("%s has no impact statement request card", c.Bug.Key)
+	}
+
+	assignee := isr.Fields.Assignee
+	if assignee == nil {
+		assignee = c.Bug.Fields.Assignee
+	}
+	var greeting string
+	if assignee != nil {
+		greeting = fmt.Sprintf("Hi [~%s], ", assignee.Name)
+	}
+
+	logrus.Infof("Commenting on %s with a reminder", isr.Key)
+	return f.comment(isr, fmt.Sprintf(
+		"%sa friendly reminder that we are still waiting for the impact statement of %s. Answering the questions on this card helps us decide whether to warn exposed cluster owners before they upgrade to an affected OCP version. Thank you!",
+		greeting, c.Bug.Key,
+	))
+}
@@ -0,0 +1,651 @@
+package blockerflow
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/hooks"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/updateblockers"
+)
+
+// fakeClient is an in-memory Jira holding issues by key
+type fakeClient struct {
+	issues   map[string]*jira.Issue
+	projects map[string]*jirautil.ProjectMetadata
+	// transitions lists the statuses every issue can be transitioned to
+	transitions []string
+
+	created  []*jira.Issue
+	links    []*jira.IssueLink
+	comments map[string][]string
+
+	// deletedComments holds the deleted comments as ISSUEID/COMMENTID
+	deletedComments []string
+	deletedIssues   []string
+}
+
+func newFakeClient(issues ...*jira.Issue) *fakeClient {
+	c := &fakeClient{
+		issues: map[string]*jira.Issue{},
+		projects: map[string]*jirautil.ProjectMetadata{
+			"MCO": {Key: "MCO", IssueTypes: []string{"Bug", "Spike"}},
+		},
+		transitions: []string{"In Progress", "Code Review", "Closed"},
+		comments:    map[string][]string{},
+	}
+	for _, issue := range issues {
+		c.issues[issue.Key] = issue
+	}
+	return c
+}
+
+func (c *fakeClient) issueByKeyOrID(id string) (*jira.Issue, error) {
+	for _, issue := range c.issues {
+		if issue.Key == id || issue.ID == id {
+			return issue, nil
+		}
+	}
+	return nil, fmt.Errorf("issue %s does not exist", id)
+}
+
+func (c *fakeClient) GetIssue(id string) (*jira.Issue, error) {
+	return c.issueByKeyOrID(id)
+}
+
+func (c *fakeClient) CreateIssue(issue *jira.Issue) (*jira.Issue, error) {
+	created := *issue
+	created.Key = fmt.Sprintf("%s-%d", issue.Fields.Project.Key, len(c.created)+1)
+	created.ID = "id-" + created.Key
+	c.created = append(c.created, &created)
+	c.issues[created.Key] = &created
+	return &created, nil
+}
+
+func (c *fakeClient) UpdateIssue(update *jira.Issue) (*jira.Issue, error) {
+	issue, err := c.issueByKeyOrID(update.Key)
+	if err != nil {
+		return nil, err
+	}
+	issue.Fields.Labels = update.Fields.Labels
+	return issue, nil
+}
+
+func (c *fakeClient) CreateIssueLink(link *jira.IssueLink) error {
+	c.links = append(c.links, link)
+	return nil
+}
+
+func (c *fakeClient) AddComment(issueID string, comment *jira.Comment) (*jira.Comment, error) {
+	issue, err := c.issueByKeyOrID(issueID)
+	if err != nil {
+		return nil, err
+	}
+	c.comments[issue.Key] = append(c.comments[issue.Key], comment.Body)
+	return &jira.Comment{ID: fmt.Sprintf("%d", len(c.comments[issue.Key])), Body: comment.Body}, nil
+}
+
+func (c *fakeClient) DeleteComment(issueID, commentID string) error {
+	c.deletedComments = append(c.deletedComments, issueID+"/"+commentID)
+	return nil
+}
+
+func (c *fakeClient) DeleteIssue(key string) error {
+	if _, ok := c.issues[key]; !ok {
+		return fmt.Errorf("issue %s does not exist", key)
+	}
+	delete(c.issues, key)
+	c.deletedIssues = append(c.deletedIssues, key)
+	return nil
+}
+
+func (c *fakeClient) GetSelf() (*jira.User, error) {
+	return &jira.User{Name: "ota-bot"}, nil
+}
+
+func (c *fakeClient) GetProjectMetadata(key string) (*jirautil.ProjectMetadata, error) {
+	if project, ok := c.projects[key]; ok {
+		return project, nil
+	}
+	return nil, fmt.Errorf("%s: %w", key, jirautil.ErrNoSuchProject)
+}
+
+func (c *fakeClient) GetTransitions(string) ([]jira.Transition, error) {
+	var transitions []jira.Transition
+	for i, status := range c.transitions {
+		transitions = append(transitions, jira.Transition{ID: fmt.Sprintf("%d", i), To: jira.Status{Name: status}})
+	}
+	return transitions, nil
+}
+
+func (c *fakeClient) DoTransition(issueID, transitionID string) error {
+	issue, err := c.issueByKeyOrID(issueID)
+	if err != nil {
+		return err
+	}
+	for i, status := range c.transitions {
+		if fmt.Sprintf("%d", i) == transitionID {
+			issue.Fields.Status = &jira.Status{Name: status}
+			return nil
+		}
+	}
+	return fmt.Errorf("no transition %s", transitionID)
+}
+
+// newTestFlow returns a flow over the client that uses the default configuration
+func newTestFlow(t *testing.T, client Client) *Flow {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	description, err := LoadDescriptionTemplate("")
+	if err != nil {
+		t.Fatalf("cannot load the default description template: %v", err)
+	}
+	return &Flow{
+		Client:              client,
+		Transitioner:        &updateblockers.Transitioner{},
+		Hooks:               &hooks.Runner{},
+		DescriptionTemplate: description,
+		BugProjects:         []string{jirautil.DefaultBugProject},
+	}
+}
+
+func newBug(labels ...string) *jira.Issue {
+	return &jira.Issue{
+		ID:  "id-OCPBUGS-1",
+		Key: "OCPBUGS-1",
+		Fields: &jira.IssueFields{
+			Summary:    "Nodes fail to drain",
+			Labels:     labels,
+			Assignee:   &jira.User{Name: "developer"},
+			Components: []*jira.Component{{Name: "Machine Config Operator"}},
+		},
+	}
+}
+
+func newIsr(status string, labels ...string) *jira.Issue {
+	return &jira.Issue{
+		ID:  "id-MCO-7",
+		Key: "MCO-7",
+		Fields: &jira.IssueFields{
+			Type:   jira.IssueType{Name: "Spike"},
+			Status: &jira.Status{Name: status},
+			Labels: labels,
+		},
+	}
+}
+
+func TestRequestImpactStatement(t *testing.T) {
+	bug := newBug(updateblockers.LabelBlocker, "Other")
+	client := newFakeClient(bug)
+	flow := newTestFlow(t, client)
+	candidate := &Candidate{Bug: bug}
+
+	if err := flow.RequestImpactStatement(candidate, "MCO", false); err != nil {
+		t.Fatalf("RequestImpactStatement failed: %v", err)
+	}
+
+	if len(client.created) != 1 {
+		t.Fatalf("expected a single created card, got %d", len(client.created))
+	}
+	isr := client.created[0]
+	if candidate.ImpactStatementRequest != isr {
+		t.Errorf("candidate ISR is %v, expected the created card", candidate.ImpactStatementRequest)
+	}
+	if isr.Fields.Project.Key != "MCO" || isr.Fields.Type.Name != "Spike" {
+		t.Errorf("expected a Spike in MCO, got %s in %s", isr.Fields.Type.Name, isr.Fields.Project.Key)
+	}
+	if expected := "Impact statement request for OCPBUGS-1 Nodes fail to drain"; isr.Fields.Summary != expected {
+		t.Errorf("expected summary %q, got %q", expected, isr.Fields.Summary)
+	}
+	if isr.Fields.Assignee == nil || isr.Fields.Assignee.Name != "developer" {
+		t.Errorf("expected the ISR to be assigned to the bug assignee, got %v", isr.Fields.Assignee)
+	}
+	if !strings.Contains(isr.Fields.Description, "OCPBUGS-1") {
+		t.Errorf("expected the description to reference the bug")
+	}
+
+	if len(client.links) != 1 || client.links[0].InwardIssue.ID != isr.ID || client.links[0].OutwardIssue.ID != bug.ID {
+		t.Errorf("expected a single '%s blocks %s' link, got %v", isr.Key, bug.Key, client.links)
+	}
+	if comments := client.comments[bug.Key]; len(comments) != 1 || !strings.Contains(comments[0], isr.Key) {
+		t.Errorf("expected a single comment on the bug mentioning %s, got %v", isr.Key, comments)
+	}
+
+	expectedLabels := []string{updateblockers.LabelImpactStatementRequested, "Other", updateblockers.LabelBlocker}
+	if diff := cmp.Diff(expectedLabels, bug.Fields.Labels); diff != "" {
+		t.Errorf("unexpected bug labels (-want +got):\n%s", diff)
+	}
+}
+
+func TestRequestImpactStatementPreconditions(t *testing.T) {
+	linkedIsr := func(status string) *jira.Issue {
+		bug := newBug(updateblockers.LabelBlocker)
+		isr := newIsr(status)
+		isr.Fields.Summary = impactStatementRequestSummary(bug)
+		bug.Fields.IssueLinks = []*jira.IssueLink{{InwardIssue: isr}}
+		return bug
+	}
+
+	testCases := []struct {
+		name    string
+		bug     *jira.Issue
+		project string
+		force   bool
+
+		expectedError string
+	}{
+		{
+			name:          "bug without the UpgradeBlocker label",
+			bug:           newBug(),
+			project:       "MCO",
+			expectedError: "does not have the UpgradeBlocker label",
+		},
+		{
+			name:          "impact statement already requested",
+			bug:           newBug(updateblockers.LabelBlocker, updateblockers.LabelImpactStatementRequested),
+			project:       "MCO",
+			expectedError: "already has the ImpactStatementRequested label",
+		},
+		{
+			name:          "impact statement already proposed",
+			bug:           newBug(updateblockers.LabelBlocker, updateblockers.LabelImpactStatementProposed),
+			project:       "MCO",
+			expectedError: "already has the ImpactStatementProposed label",
+		},
+		{
+			name:          "open impact statement request already linked",
+			bug:           linkedIsr("New"),
+			project:       "MCO",
+			expectedError: "already has an open impact statement request MCO-7",
+		},
+		{
+			name: "closed impact statement request linked",
+			bug: func() *jira.Issue {
+				bug := linkedIsr("Closed")
+				bug.Fields.IssueLinks[0].InwardIssue.Fields.Status.StatusCategory.Key = jira.StatusCategoryComplete
+				return bug
+			}(),
+			project: "MCO",
+		},
+		{
+			name:    "forced despite a missing label",
+			bug:     newBug(),
+			project: "MCO",
+			force:   true,
+		},
+		{
+			name:          "unknown project",
+			bug:           newBug(updateblockers.LabelBlocker),
+			project:       "NOPE",
+			force:         true,
+			expectedError: "no such project",
+		},
+		{
+			name:          "project without Spikes",
+			bug:           newBug(updateblockers.LabelBlocker),
+			project:       "BUGS",
+			force:         true,
+			expectedError: `project BUGS has no issue type "Spike"`,
+		},
+		{
+			name:          "project without the Critical priority",
+			bug:           newBug(updateblockers.LabelBlocker),
+			project:       "LOW",
+			force:         true,
+			expectedError: `project LOW has no priority "Critical" (valid: Minor, Normal)`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newFakeClient(tc.bug)
+			client.projects["BUGS"] = &jirautil.ProjectMetadata{Key: "BUGS", IssueTypes: []string{"Bug"}}
+			client.projects["LOW"] = &jirautil.ProjectMetadata{Key: "LOW", IssueTypes: []string{"Spike"}, Priorities: []string{"Minor", "Normal"}}
+			flow := newTestFlow(t, client)
+
+			err := flow.RequestImpactStatement(&Candidate{Bug: tc.bug}, tc.project, tc.force)
+			switch {
+			case tc.expectedError == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.expectedError != "" && err == nil:
+				t.Fatalf("expected an error containing %q", tc.expectedError)
+			case tc.expectedError != "" && !strings.Contains(err.Error(), tc.expectedError):
+				t.Fatalf("expected an error containing %q, got %v", tc.expectedError, err)
+			}
+
+			expectedCreated := 1
+			if tc.expectedError != "" {
+				expectedCreated = 0
+			}
+			if len(client.created) != expectedCreated {
+				t.Errorf("expected %d created cards, got %d", expectedCreated, len(client.created))
+			}
+		})
+	}
+}
+
+func TestMarkProposed(t *testing.T) {
+	bug := newBug(updateblockers.LabelBlocker, updateblockers.LabelImpactStatementRequested)
+	isr := newIsr("New")
+	client := newFakeClient(bug, isr)
+	flow := newTestFlow(t, client)
+
+	if err := flow.MarkProposed(&Candidate{Bug: bug, ImpactStatementRequest: isr}, false); err != nil {
+		t.Fatalf("MarkProposed failed: %v", err)
+	}
+
+	expectedLabels := []string{updateblockers.LabelImpactStatementProposed, updateblockers.LabelBlocker}
+	if diff := cmp.Diff(expectedLabels, bug.Fields.Labels); diff != "" {
+		t.Errorf("unexpected bug labels (-want +got):\n%s", diff)
+	}
+	if isr.Fields.Status.Name != "Code Review" {
+		t.Errorf("expected the ISR to be moved to Code Review, got %s", isr.Fields.Status.Name)
+	}
+}
+
+func TestMarkProposedRequiresRequestedStage(t *testing.T) {
+	bug := newBug(updateblockers.LabelBlocker)
+	client := newFakeClient(bug)
+	flow := newTestFlow(t, client)
+
+	if err := flow.MarkProposed(&Candidate{Bug: bug}, false); err == nil {
+		t.Fatal("expected MarkProposed to refuse a bug without a requested impact statement")
+	}
+	if diff := cmp.Diff([]string{updateblockers.LabelBlocker}, bug.Fields.Labels); diff != "" {
+		t.Errorf("bug labels changed (-want +got):\n%s", diff)
+	}
+
+	if err := flow.MarkProposed(&Candidate{Bug: bug}, true); err != nil {
+		t.Fatalf("MarkProposed with force failed: %v", err)
+	}
+	expectedLabels := []string{updateblockers.LabelImpactStatementProposed, updateblockers.LabelBlocker}
+	if diff := cmp.Diff(expectedLabels, bug.Fields.Labels); diff != "" {
+		t.Errorf("unexpected bug labels (-want +got):\n%s", diff)
+	}
+}
+
+func TestAnnounceKnownIssue(t *testing.T) {
+	bug := newBug(updateblockers.LabelImpactStatementProposed)
+	isr := newIsr("Code Review")
+	client := newFakeClient(bug, isr)
+	flow := newTestFlow(t, client)
+
+	candidate := &Candidate{
+		Bug:                    bug,
+		ImpactStatementRequest: isr,
+		Risks:                  []graph.ConditionallyBlockedEdge{{Name: "NodesFailToDrain", Message: "Nodes may fail to drain."}},
+	}
+	if err := flow.AnnounceKnownIssue(candidate, false); err != nil {
+		t.Fatalf("AnnounceKnownIssue failed: %v", err)
+	}
+
+	expectedLabels := []string{updateblockers.LabelKnownIssueAnnounced, updateblockers.LabelBlocker}
+	if diff := cmp.Diff(expectedLabels, bug.Fields.Labels); diff != "" {
+		t.Errorf("unexpected bug labels (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{updateblockers.LabelBlocker}, isr.Fields.Labels); diff != "" {
+		t.Errorf("unexpected ISR labels (-want +got):\n%s", diff)
+	}
+	if isr.Fields.Status.Name != "Closed" {
+		t.Errorf("expected the ISR to be closed, got %s", isr.Fields.Status.Name)
+	}
+	for _, key := range []string{bug.Key, isr.Key} {
+		if comments := client.comments[key]; len(comments) != 1 || !strings.Contains(comments[0], "NodesFailToDrain") {
+			t.Errorf("expected a single comment on %s naming the risk, got %v", key, comments)
+		}
+	}
+}
+
+func TestClearLabels(t *testing.T) {
+	bug := newBug(updateblockers.LabelBlocker, updateblockers.LabelImpactStatementProposed, "Other")
+	client := newFakeClient(bug)
+	flow := newTestFlow(t, client)
+
+	if err := flow.ClearLabels(&Candidate{Bug: bug}); err != nil {
+		t.Fatalf("ClearLabels failed: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"Other"}, bug.Fields.Labels); diff != "" {
+		t.Errorf("unexpected bug labels (-want +got):\n%s", diff)
+	}
+	if len(client.comments) != 0 {
+		t.Errorf("expected no comments, got %v", client.comments)
+	}
+}
+
+func TestRemindImpactStatement(t *testing.T) {
+	testCases := []struct {
+		name        string
+		isrAssignee *jira.User
+		expected    string
+	}{
+		{
+			name:        "mentions the ISR assignee",
+			isrAssignee: &jira.User{Name: "manager"},
+			expected:    "Hi [~manager], ",
+		},
+		{
+			name:     "falls back to the bug assignee",
+			expected: "Hi [~developer], ",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bug := newBug(updateblockers.LabelBlocker, updateblockers.LabelImpactStatementRequested)
+			isr := newIsr("New")
+			isr.Fields.Assignee = tc.isrAssignee
+			client := newFakeClient(bug, isr)
+			flow := newTestFlow(t, client)
+
+			if err := flow.RemindImpactStatement(&Candidate{Bug: bug, ImpactStatementRequest: isr}); err != nil {
+				t.Fatalf("RemindImpactStatement failed: %v", err)
+			}
+
+			comments := client.comments[isr.Key]
+			if len(comments) != 1 || !strings.HasPrefix(comments[0], tc.expected) || !strings.Contains(comments[0], bug.Key) {
+				t.Errorf("expected a single reminder on %s starting with %q and mentioning %s, got %v", isr.Key, tc.expected, bug.Key, comments)
+			}
+			if len(client.comments[bug.Key]) != 0 {
+				t.Errorf("expected no comments on the bug, got %v", client.comments[bug.Key])
+			}
+		})
+	}
+
+	bug := newBug(updateblockers.LabelBlocker, updateblockers.LabelImpactStatementRequested)
+	flow := newTestFlow(t, newFakeClient(bug))
+	if err := flow.RemindImpactStatement(&Candidate{Bug: bug}); err == nil {
+		t.Errorf("expected an error for a candidate without an impact statement request card")
+	}
+}
+
+func TestFormatOutstanding(t *testing.T) {
+	requested := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		now      time.Time
+		expected string
+	}{
+		{name: "requested today", now: requested.Add(time.Hour), expected: "0 days"},
+		{name: "single day", now: requested.Add(30 * time.Hour), expected: "1 day"},
+		{name: "exactly at the SLA", now: requested.Add(ImpactStatementSLA), expected: "7 days"},
+		{name: "past the SLA", now: requested.Add(9*24*time.Hour + time.Hour), expected: "9 days, OVERDUE"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := FormatOutstanding(requested, tc.now); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUndo(t *testing.T) {
+	bug := newBug(updateblockers.LabelBlocker, "Other")
+	client := newFakeClient(bug)
+	flow := newTestFlow(t, client)
+
+	if err := flow.RequestImpactStatement(&Candidate{Bug: bug}, "MCO", false); err != nil {
+		t.Fatalf("RequestImpactStatement failed: %v", err)
+	}
+
+	journal, err := LoadJournal(bug.Key)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	if journal == nil || journal.Operation != "impact-statement-requested" {
+		t.Fatalf("expected a journal of the impact statement request, got %v", journal)
+	}
+
+	if err := flow.Undo(journal); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{updateblockers.LabelBlocker, "Other"}, bug.Fields.Labels); diff != "" {
+		t.Errorf("labels were not restored (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"MCO-1"}, client.deletedIssues); diff != "" {
+		t.Errorf("unexpected deleted cards (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{bug.ID + "/1"}, client.deletedComments); diff != "" {
+		t.Errorf("unexpected deleted comments (-want +got):\n%s", diff)
+	}
+
+	if journal, err := LoadJournal(bug.Key); err != nil || journal != nil {
+		t.Errorf("expected the journal to be removed after undo, got %v (err=%v)", journal, err)
+	}
+}
+
+func TestComponentProjects(t *testing.T) {
+	withIsr := func(bug, isr string) jira.Issue {
+		return jira.Issue{Key: bug, Fields: &jira.IssueFields{IssueLinks: []*jira.IssueLink{
+			{InwardIssue: &jira.Issue{Key: isr, Fields: &jira.IssueFields{Type: jira.IssueType{Name: "Spike"}}}},
+		}}}
+	}
+	bugs := []jira.Issue{
+		withIsr("OCPBUGS-5", "MCO-9"),
+		withIsr("OCPBUGS-4", "OCPNODE-3"),
+		withIsr("OCPBUGS-3", "OCPNODE-2"),
+		{Key: "OCPBUGS-2", Fields: &jira.IssueFields{}},
+		withIsr("OCPBUGS-1", "MCO-1"),
+		withIsr("OCPBUGS-0", "OCPBUGS-10"),
+	}
+
+	// tied projects keep the order of the bugs, which are sorted from the most recent
+	expected := []string{"MCO", "OCPNODE"}
+	if diff := cmp.Diff(expected, componentProjects(bugs, []string{jirautil.DefaultBugProject})); diff != "" {
+		t.Errorf("unexpected projects (-want +got):\n%s", diff)
+	}
+
+	if projects := componentProjects(bugs[1:], []string{jirautil.DefaultBugProject}); len(projects) == 0 || projects[0] != "OCPNODE" {
+		t.Errorf("expected OCPNODE with the most requests first, got %v", projects)
+	}
+}
+
+func TestRequestImpactStatementEpic(t *testing.T) {
+	testCases := []struct {
+		name  string
+		epics config.Epics
+		epic  string
+
+		expected interface{}
+	}{
+		{
+			name: "no epic",
+		},
+		{
+			name:     "project epic",
+			epics:    config.Epics{"mco": "MCO-100", "OCPNODE": "OCPNODE-5"},
+			expected: "MCO-100",
+		},
+		{
+			name:     "epic overrides the project epic",
+			epics:    config.Epics{"MCO": "MCO-100"},
+			epic:     "MCO-200",
+			expected: "MCO-200",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bug := newBug(updateblockers.LabelBlocker)
+			client := newFakeClient(bug)
+			flow := newTestFlow(t, client)
+			flow.Epics = tc.epics
+			flow.Epic = tc.epic
+
+			if err := flow.RequestImpactStatement(&Candidate{Bug: bug}, "MCO", false); err != nil {
+				t.Fatalf("RequestImpactStatement failed: %v", err)
+			}
+			if epic := client.created[0].Fields.Unknowns[jirautil.EpicLinkField]; epic != tc.expected {
+				t.Errorf("expected the card linked to epic %v, got %v", tc.expected, epic)
+			}
+		})
+	}
+}
+
+func TestRequestImpactStatementVersions(t *testing.T) {
+	testCases := []struct {
+		name   string
+		fields []string
+
+		expectedAffected []*jira.AffectsVersion
+		expectedTarget   interface{}
+	}{
+		{
+			name:             "versions the project has are copied",
+			fields:           []string{"summary", "versions", jirautil.TargetVersionField},
+			expectedAffected: []*jira.AffectsVersion{{Name: "4.16"}},
+			expectedTarget:   []*jira.Version{{Name: "4.17.z"}},
+		},
+		{
+			name:   "fields missing on Spikes are not copied",
+			fields: []string{"summary"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bug := newBug(updateblockers.LabelBlocker)
+			bug.Fields.AffectsVersions = []*jira.AffectsVersion{{ID: "1", Name: "4.16"}, {ID: "2", Name: "4.15"}}
+			bug.Fields.Unknowns = map[string]interface{}{
+				jirautil.TargetVersionField: []interface{}{map[string]interface{}{"id": "3", "name": "4.17.z"}},
+			}
+			client := newFakeClient(bug)
+			client.projects["MCO"].Versions = []string{"4.16", "4.17.z"}
+			client.projects["MCO"].Fields = map[string][]string{"Spike": tc.fields}
+			flow := newTestFlow(t, client)
+
+			if err := flow.RequestImpactStatement(&Candidate{Bug: bug}, "MCO", false); err != nil {
+				t.Fatalf("RequestImpactStatement failed: %v", err)
+			}
+			isr := client.created[0]
+			if diff := cmp.Diff(tc.expectedAffected, isr.Fields.AffectsVersions); diff != "" {
+				t.Errorf("unexpected affected versions (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedTarget, isr.Fields.Unknowns[jirautil.TargetVersionField]); diff != "" {
+				t.Errorf("unexpected target versions (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImpactStatement(t *testing.T) {
+	isr := &jira.Issue{Fields: &jira.IssueFields{
+		Description: "h2. Which types of clusters?\n * reasoning: populates matchingRules\n * example: GCP clusters with thousands of namespaces\nAWS clusters only",
+		Comments:    &jira.Comments{Comments: []*jira.Comment{{Body: "Also with a proxy"}}},
+	}}
+
+	expected := "h2. Which types of clusters?\nAWS clusters only\n\nAlso with a proxy"
+	if diff := cmp.Diff(expected, ImpactStatement(isr)); diff != "" {
+		t.Errorf("unexpected impact statement (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,131 @@
+package blockerflow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/andygrunwald/go-jira"
+
+	"github.com/petr-muller/ota/internal/config"
+)
+
+const (
+	// descriptionTemplateFileName is a file in the OTA config directory that overrides the default
+	// impact statement request description
+	descriptionTemplateFileName = "impact-statement-request.tmpl"
+)
+
+// DescriptionData is available to the impact statement request description templates. The templates
+// use [[ and ]] as delimiters because {{ }} is Jira markup.
+type DescriptionData struct {
+	Bug             string
+	Summary         string
+	Component       string
+	AffectsVersions []string
+}
+
+func newDescriptionData(bug *jira.Issue) DescriptionData {
+	data := DescriptionData{Bug: bug.Key, Summary: bug.Fields.Summary}
+	if len(bug.Fields.Components) > 0 {
+		data.Component = bug.Fields.Components[0].Name
+	}
+	for _, version := range bug.Fields.AffectsVersions {
+		data.AffectsVersions = append(data.AffectsVersions, version.Name)
+	}
+	return data
+}
+
+// LoadDescriptionTemplate parses the impact statement request description template from path. With
+// an empty path, the template is read from the OTA config directory when present there, otherwise the
+// default template is used.
+func LoadDescriptionTemplate(path string) (*template.Template, error) {
+	source := defaultDescriptionTemplate
+	if path == "" {
+		var err error
+		if path, err = config.OtaConfigPath(descriptionTemplateFileName); err != nil {
+			path = ""
+		} else if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			path = ""
+		}
+	}
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read description template: %w", err)
+		}
+		source = string(raw)
+	}
+
+	tmpl, err := template.New("description").Delims("[[", "]]").Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse description template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+func renderDescription(tmpl *template.Template, bug *jira.Issue) (string, error) {
+	var description bytes.Buffer
+	if err := tmpl.Execute(&description, newDescriptionData(bug)); err != nil {
+		return "", fmt.Errorf("cannot render impact statement request description: %w", err)
+	}
+	return description.String(), nil
+}
+
+// templateLineRegexp matches the reasoning and example lines of the impact statement request
+// description template, which are not a part of the answers
+var templateLineRegexp = regexp.MustCompile(`^\s*\*\s*(reasoning|example):`)
+
+// ImpactStatement returns the text of the answered impact statement request card: its description
+// without the reasoning and example lines of the template, followed by its comments
+func ImpactStatement(isr *jira.Issue) string {
+	var lines []string
+	for _, line := range strings.Split(isr.Fields.Description, "\n") {
+		if !templateLineRegexp.MatchString(line) {
+			lines = append(lines, line)
+		}
+	}
+	if isr.Fields.Comments != nil {
+		for _, comment := range isr.Fields.Comments.Comments {
+			lines = append(lines, "", comment.Body)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+const defaultDescriptionTemplate = `We're asking the following questions to evaluate whether or not [[ .Bug ]] warrants changing update recommendations from either the previous X.Y or X.Y.Z. The ultimate goal is to avoid recommending an update which introduces new risk or reduces cluster functionality in any way. In the absence of a declared update risk (the status quo), there is some risk that the existing fleet updates into the at-risk releases. Depending on the bug and estimated risk, leaving the update risk undeclared may be acceptable.
+
+Sample answers are provided to give more context and the {{ImpactStatementRequested}} label has been added to [[ .Bug ]]. When responding, please move this ticket to {{{}Code Review{}}}. The expectation is that the assignee answers these questions.
+
+h2. Which 4.y.z to 4.y'.z' updates increase vulnerability?
+ * reasoning: This allows us to populate [{{from}} and {{to}} in conditional update recommendations|https://github.com/openshift/cincinnati-graph-data/tree/0335e56cde6b17230106f137382cbbd9aa5038ed#block-edges] for "the {{$SOURCE_RELEASE}} to {{$TARGET_RELEASE}} update is exposed.
+ * example: Customers upgrading from any 4.y (or specific 4.y.z) to 4.(y+1).z'. Use {{oc adm upgrade}} to show your current cluster version.
+
+h2. Which types of clusters?
+ * reasoning: This allows us to populate [{{matchingRules}} in conditional update recommendations|https://github.com/openshift/cincinnati-graph-data/tree/0335e56cde6b17230106f137382cbbd9aa5038ed#block-edges] for "clusters like {{{}$THIS{}}}".
+ * example: GCP clusters with thousands of namespaces, approximately 5% of the subscribed fleet. Check your vulnerability with {{oc ...}} or the following PromQL {{{}count (...) > 0{}}}.
+
+The two questions above are sufficient to declare an initial update risk, and we would like as much detail as possible on them as quickly as you can get it. Perfectly crisp responses are nice, but are not required. For example "it seems like these platforms are involved, because..." in a day 1 draft impact statement is helpful, even if you follow up with "actually, it was these other platforms" on day 3. In the absence of a response within 7 days, we may or may not declare a conditional update risk based on our current understanding of the issue.
+
+If you can, answers to the following questions will make the conditional risk declaration more actionable for customers.
+
+h2. What is the impact? Is it serious enough to warrant removing update recommendations?
+ * reasoning: This allows us to populate [{{name}} and {{message}} in conditional update recommendations|https://github.com/openshift/cincinnati-graph-data/tree/0335e56cde6b17230106f137382cbbd9aa5038ed#block-edges] for "...because if you update, {{$THESE_CONDITIONS}} may cause {{{}$THESE_UNFORTUNATE_SYMPTOMS{}}}".
+ * example: Around 2 minute disruption in edge routing for 10% of clusters. Check with {{{}oc ...{}}}.
+ * example: Up to 90 seconds of API downtime. Check with {{{}curl ...{}}}.
+ * example: etcd loses quorum and you have to restore from backup. Check with {{{}ssh ...{}}}.
+
+h2. How involved is remediation?
+ * reasoning: This allows administrators who are already vulnerable, or who chose to waive conditional-update risks, to recover their cluster. And even moderately serious impacts might be acceptable if they are easy to mitigate.
+ * example: Issue resolves itself after five minutes.
+ * example: Admin can run a single: {{{}oc ...{}}}.
+ * example: Admin must SSH to hosts, restore from backups, or other non standard admin activities.
+
+h2. Is this a regression?
+ * reasoning: Updating between two vulnerable releases may not increase exposure (unless rebooting during the update increases vulnerability, etc.). We only qualify update recommendations if the update increases exposure.
+ * example: No, it has always been like this we just never noticed.
+ * example: Yes, from 4.y.z to 4.y+1.z Or 4.y.z to 4.y.z+1.`
@@ -0,0 +1,59 @@
+package blockerflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/updateblockers"
+)
+
+// SuggestComponentProject suggests the project to create the impact statement request for the bug in:
+// the project where the impact statement requests for earlier bugs in the same component were created.
+// It returns an empty string when the bug has no component or the component has no such history.
+func SuggestComponentProject(ctx context.Context, client jirautil.Searcher, bug *jira.Issue, bugProjects []string) (string, error) {
+	if bug.Fields == nil || len(bug.Fields.Components) == 0 {
+		return "", nil
+	}
+	component := bug.Fields.Components[0].Name
+
+	query := fmt.Sprintf("project in (%s) AND component = %q AND labels = %s ORDER BY created DESC",
+		strings.Join(bugProjects, ", "), component, updateblockers.LabelImpactStatementRequested)
+	logrus.Infof("Obtaining earlier bugs in the %s component with impact statement requests", component)
+	earlier, _, err := jirautil.SearchPage(ctx, client, query, 0)
+	if err != nil {
+		return "", fmt.Errorf("cannot search for earlier impact statement requests of the %s component: %w", component, err)
+	}
+
+	projects := componentProjects(earlier, bugProjects)
+	if len(projects) == 0 {
+		return "", nil
+	}
+	logrus.Infof("Impact statement requests for earlier bugs in the %s component were created in %s", component, strings.Join(projects, ", "))
+	return projects[0], nil
+}
+
+// componentProjects returns the projects of the impact statement requests linked to the bugs, the
+// projects with the most requests first and the projects of the more recent bugs first on a tie
+func componentProjects(bugs []jira.Issue, bugProjects []string) []string {
+	counts := map[string]int{}
+	var projects []string
+	for i := range bugs {
+		isr := LinkedImpactStatementRequest(&bugs[i], bugProjects)
+		if isr == "" {
+			continue
+		}
+		project, _, _ := strings.Cut(isr, "-")
+		if counts[project] == 0 {
+			projects = append(projects, project)
+		}
+		counts[project]++
+	}
+	sort.SliceStable(projects, func(i, j int) bool { return counts[projects[i]] > counts[projects[j]] })
+	return projects
+}
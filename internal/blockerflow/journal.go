@@ -0,0 +1,179 @@
+package blockerflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/config"
+)
+
+// Kinds of the changes recorded in a journal
+const (
+	changeLabels     = "labels"
+	changeComment    = "comment"
+	changeTransition = "transition"
+	changeCreated    = "created"
+)
+
+// JournalEntry is a single change a workflow operation made in Jira, with what is needed to revert it
+type JournalEntry struct {
+	Kind    string `json:"kind"`
+	Issue   string `json:"issue"`
+	IssueID string `json:"issueId,omitempty"`
+
+	// Labels are the labels of the issue before the change
+	Labels []string `json:"labels,omitempty"`
+	// CommentID is the ID of the added comment
+	CommentID string `json:"commentId,omitempty"`
+	// Status is the status of the issue before the transition
+	Status string `json:"status,omitempty"`
+}
+
+func (e JournalEntry) String() string {
+	switch e.Kind {
+	case changeLabels:
+		return fmt.Sprintf("%s: restore labels %s", e.Issue, strings.Join(e.Labels, ","))
+	case changeComment:
+		return fmt.Sprintf("%s: delete comment %s", e.Issue, e.CommentID)
+	case changeTransition:
+		return fmt.Sprintf("%s: move back to %s", e.Issue, e.Status)
+	case changeCreated:
+		return fmt.Sprintf("%s: delete the created card", e.Issue)
+	}
+	return fmt.Sprintf("%s: unknown change %s", e.Issue, e.Kind)
+}
+
+// Journal records the changes made by the last workflow operation on a bug, so that a half-failed
+// operation can be reverted. It is saved after every change in the ota data directory.
+type Journal struct {
+	Bug       string         `json:"bug"`
+	Operation string         `json:"operation"`
+	Started   time.Time      `json:"started"`
+	Entries   []JournalEntry `json:"entries"`
+}
+
+func journalPath(bug string) (string, error) {
+	dataDir, err := config.OtaDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "journal", bug+".json"), nil
+}
+
+// LoadJournal returns the journal of the last workflow operation on the bug, or nil when there is none
+func LoadJournal(bug string) (*Journal, error) {
+	path, err := journalPath(bug)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read journal %s: %w", path, err)
+	}
+	var journal Journal
+	if err := json.Unmarshal(raw, &journal); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal journal %s: %w", path, err)
+	}
+	return &journal, nil
+}
+
+func (j *Journal) save() error {
+	path, err := journalPath(j.Bug)
+	if err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0644)
+}
+
+func (j *Journal) remove() error {
+	path, err := journalPath(j.Bug)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// begin starts a new journal for the operation on the bug, replacing the journal of the previous one
+func (f *Flow) begin(operation string, bug *jira.Issue) {
+	f.journal = &Journal{Bug: bug.Key, Operation: operation, Started: time.Now()}
+	if err := f.journal.save(); err != nil {
+		logrus.WithError(err).Warn("Cannot save the journal, the operation will not be possible to undo")
+	}
+}
+
+// record adds the change to the journal of the current operation
+func (f *Flow) record(entry JournalEntry) {
+	if f.journal == nil {
+		return
+	}
+	f.journal.Entries = append(f.journal.Entries, entry)
+	if err := f.journal.save(); err != nil {
+		logrus.WithError(err).Warn("Cannot save the journal, the operation will not be possible to undo")
+	}
+}
+
+// Undo reverts the changes recorded in the journal, newest first, and removes the journal when all of
+// them were reverted
+func (f *Flow) Undo(journal *Journal) error {
+	f.journal = nil
+	for i := len(journal.Entries) - 1; i >= 0; i-- {
+		entry := journal.Entries[i]
+		logrus.Infof("Reverting: %s", entry)
+		if err := f.revert(entry); err != nil {
+			// Drop what was already reverted so that the undo can be retried
+			journal.Entries = journal.Entries[:i+1]
+			if saveErr := journal.save(); saveErr != nil {
+				logrus.WithError(saveErr).Warn("Cannot save the journal")
+			}
+			return fmt.Errorf("cannot revert (%s): %w", entry, err)
+		}
+	}
+	return journal.remove()
+}
+
+func (f *Flow) revert(entry JournalEntry) error {
+	switch entry.Kind {
+	case changeLabels:
+		// Labels must be sent even when empty to remove all of them
+		labels := entry.Labels
+		if labels == nil {
+			labels = []string{}
+		}
+		_, err := f.Client.UpdateIssue(&jira.Issue{Key: entry.Issue, Fields: &jira.IssueFields{Labels: labels}})
+		return err
+	case changeComment:
+		return f.Client.DeleteComment(entry.IssueID, entry.CommentID)
+	case changeTransition:
+		transitions, err := f.Client.GetTransitions(entry.Issue)
+		if err != nil {
+			return err
+		}
+		for _, transition := range transitions {
+			if strings.EqualFold(transition.To.Name, entry.Status) {
+				return f.Client.DoTransition(entry.Issue, transition.ID)
+			}
+		}
+		return fmt.Errorf("no transition back to %s", entry.Status)
+	case changeCreated:
+		return f.Client.DeleteIssue(entry.Issue)
+	}
+	return fmt.Errorf("unknown change %s", entry.Kind)
+}
@@ -0,0 +1,36 @@
+package blockerflow
+
+import (
+	"fmt"
+	"time"
+)
+
+// ImpactStatementSLA is the time the teams are asked to answer an impact statement request in; the
+// default request description says we may act on our own understanding after it passes
+const ImpactStatementSLA = 7 * 24 * time.Hour
+
+// DaysOutstanding returns the number of whole days the impact statement request created at requested
+// has been waiting for an answer
+func DaysOutstanding(requested, now time.Time) int {
+	return int(now.Sub(requested) / (24 * time.Hour))
+}
+
+// Overdue returns true when the impact statement request created at requested has been waiting for an
+// answer for longer than ImpactStatementSLA
+func Overdue(requested, now time.Time) bool {
+	return now.Sub(requested) > ImpactStatementSLA
+}
+
+// FormatOutstanding describes how long the impact statement request has been waiting for an answer,
+// e.g. "3 days" or "9 days, OVERDUE"
+func FormatOutstanding(requested, now time.Time) string {
+	days := DaysOutstanding(requested, now)
+	unit := "days"
+	if days == 1 {
+		unit = "day"
+	}
+	if Overdue(requested, now) {
+		return fmt.Sprintf("%d %s, OVERDUE", days, unit)
+	}
+	return fmt.Sprintf("%d %s", days, unit)
+}
@@ -0,0 +1,190 @@
+// Package cincinnati implements a client for the live update graph served by OSUS (OpenShift Update
+// Service, the Cincinnati implementation behind api.openshift.com)
+package cincinnati
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/petr-muller/ota/internal/graph"
+	"github.com/petr-muller/ota/internal/httputil"
+)
+
+// DefaultURL is the graph endpoint of the public OSUS instance
+const DefaultURL = "https://api.openshift.com/api/upgrades_info/graph"
+
+// Node is a release in the update graph
+type Node struct {
+	Version string `json:"version"`
+	Payload string `json:"payload"`
+}
+
+type PromQLQuery struct {
+	Query string `json:"promql"`
+}
+
+type PromQLRule struct {
+	Type   string      `json:"type"`
+	PromQL PromQLQuery `json:"promql"`
+}
+
+// Risk is a conditional risk served with conditional edges
+type Risk struct {
+	URL           string       `json:"url"`
+	Name          string       `json:"name"`
+	Message       string       `json:"message"`
+	MatchingRules []PromQLRule `json:"matchingRules"`
+}
+
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ConditionalEdge is a set of edges that are recommended only for clusters not exposed to the risks
+type ConditionalEdge struct {
+	Edges []Edge `json:"edges"`
+	Risks []Risk `json:"risks"`
+}
+
+// Graph is the update graph served for a single channel and architecture
+type Graph struct {
+	Nodes            []Node            `json:"nodes"`
+	ConditionalEdges []ConditionalEdge `json:"conditionalEdges"`
+}
+
+// Client fetches update graphs from an OSUS endpoint
+type Client struct {
+	URL  string
+	Arch string
+}
+
+// NewClient returns a client querying the given OSUS graph endpoint for the given architecture
+func NewClient(osusURL, arch string) *Client {
+	return &Client{URL: osusURL, Arch: arch}
+}
+
+// Graph fetches the update graph served in the given channel
+func (c *Client) Graph(ctx context.Context, channel string) (*Graph, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OSUS URL %s: %w", c.URL, err)
+	}
+	q := u.Query()
+	q.Set("channel", channel)
+	q.Set("arch", c.Arch)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httputil.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query OSUS: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OSUS returned %s for channel %s", resp.Status, channel)
+	}
+
+	var g Graph
+	if err := json.NewDecoder(resp.Body).Decode(&g); err != nil {
+		return nil, fmt.Errorf("cannot decode OSUS graph: %w", err)
+	}
+	return &g, nil
+}
+
+// LoadGraph reads an update graph snapshot saved from OSUS, like the response of the graph endpoint
+// saved with curl
+func LoadGraph(path string) (*Graph, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var g Graph
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, fmt.Errorf("cannot decode OSUS graph %s: %w", path, err)
+	}
+	return &g, nil
+}
+
+// RiskNames returns the names of all risks served in the graph
+func (g *Graph) RiskNames() sets.Set[string] {
+	names := sets.New[string]()
+	for _, edge := range g.ConditionalEdges {
+		for _, risk := range edge.Risks {
+			names.Insert(risk.Name)
+		}
+	}
+	return names
+}
+
+// BlockedEdges returns the served conditional edges as blocked edges, one for each edge and risk
+// that matches the filter. Unlike in the graph repository, From holds the exact source version.
+func (g *Graph) BlockedEdges(match func(Risk) bool) []graph.ConditionallyBlockedEdge {
+	var blocked []graph.ConditionallyBlockedEdge
+	for _, conditional := range g.ConditionalEdges {
+		for _, risk := range conditional.Risks {
+			if !match(risk) {
+				continue
+			}
+			var rules []graph.PromQLRule
+			for _, rule := range risk.MatchingRules {
+				rules = append(rules, graph.PromQLRule{Type: rule.Type, PromQL: graph.PromQLQuery{Query: rule.PromQL.Query}})
+			}
+			for _, edge := range conditional.Edges {
+				blocked = append(blocked, graph.ConditionallyBlockedEdge{
+					To:            edge.To,
+					From:          edge.From,
+					URL:           risk.URL,
+					Name:          risk.Name,
+					Message:       risk.Message,
+					MatchingRules: rules,
+				})
+			}
+		}
+	}
+	return blocked
+}
+
+// ByName matches risks with the given name
+func ByName(name string) func(Risk) bool {
+	return func(risk Risk) bool { return risk.Name == name }
+}
+
+// ByURL matches risks referencing the given URL (usually the impact statement request card)
+func ByURL(url string) func(Risk) bool {
+	return func(risk Risk) bool { return risk.URL == url }
+}
+
+// BlockedEdges fetches the graphs for all channels and returns the served conditional edges that
+// match the filter, see Graph.BlockedEdges
+func (c *Client) BlockedEdges(ctx context.Context, channels []string, match func(Risk) bool) ([]graph.ConditionallyBlockedEdge, error) {
+	var blocked []graph.ConditionallyBlockedEdge
+	seen := sets.New[string]()
+	for _, channel := range channels {
+		g, err := c.Graph(ctx, channel)
+		if err != nil {
+			return nil, err
+		}
+		for _, edge := range g.BlockedEdges(match) {
+			key := fmt.Sprintf("%s %s %s", edge.From, edge.To, edge.Name)
+			if seen.Has(key) {
+				continue
+			}
+			seen.Insert(key)
+			blocked = append(blocked, edge)
+		}
+	}
+	return blocked, nil
+}
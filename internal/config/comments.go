@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// commentsFileName is a file in the OTA config directory with the settings of the comments the tools post
+	commentsFileName string = "comments.yaml"
+)
+
+// Comments holds the settings of the comments the tools post to Jira
+type Comments struct {
+	// Visibility restricts who can see the comments; visible to everyone who can see the card when empty
+	Visibility CommentVisibility `yaml:"visibility"`
+}
+
+// CommentVisibility restricts a comment to a Jira group or project role
+type CommentVisibility struct {
+	// Type is "group" or "role"
+	Type string `yaml:"type"`
+	// Value is the name of the group or role, e.g. "Red Hat Employee"
+	Value string `yaml:"value"`
+}
+
+// Validate checks that the visibility is either empty or restricts to a named group or role
+func (v CommentVisibility) Validate() error {
+	switch v.Type {
+	case "":
+		if v.Value != "" {
+			return fmt.Errorf("comment visibility %q needs a type (group or role)", v.Value)
+		}
+	case "group", "role":
+		if v.Value == "" {
+			return fmt.Errorf("comment visibility of type %s needs a value", v.Type)
+		}
+	default:
+		return fmt.Errorf("comment visibility type must be group or role, not %q", v.Type)
+	}
+	return nil
+}
+
+// LoadComments reads the comment settings from the OTA config directory. A missing file is not an
+// error and results in the defaults.
+func LoadComments() (Comments, error) {
+	var comments Comments
+
+	raw, path, err := ReadConfigFile(commentsFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return comments, nil
+	}
+	if err != nil {
+		return comments, fmt.Errorf("cannot read comments config %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(raw, &comments); err != nil {
+		return comments, fmt.Errorf("cannot unmarshal comments config %s: %w", path, err)
+	}
+	if err := comments.Visibility.Validate(); err != nil {
+		return comments, fmt.Errorf("invalid comments config %s: %w", path, err)
+	}
+	return comments, nil
+}
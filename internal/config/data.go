@@ -0,0 +1,21 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// OtaDataDir returns the directory where OTA keeps its state: $XDG_DATA_HOME/ota, or ~/.local/share/ota
+// when $XDG_DATA_HOME is not set
+func OtaDataDir() (string, error) {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot obtain user home dir: %w", err)
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, configDirName), nil
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// flagDefaultsFileName is a file in the OTA config directory with the user's defaults for command line flags
+	flagDefaultsFileName string = "defaults.yaml"
+)
+
+// FlagValues are the values of a flag; repeatable flags can have several values, other flags take a single
+// value that may be written as a YAML scalar
+type FlagValues []string
+
+func (v *FlagValues) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*v = FlagValues{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+func (v FlagValues) MarshalYAML() (interface{}, error) {
+	if len(v) == 1 {
+		return v[0], nil
+	}
+	return []string(v), nil
+}
+
+// FlagDefaults are the user's defaults for command line flags, e.g.:
+//
+//	flags:
+//	  graph-repository-path: /home/user/cincinnati-graph-data
+//	  comment-visibility: group:Red Hat Employee
+//	commands:
+//	  monitor-jira-dashboard:
+//	    output: html
+//	    html-dir: /var/www/dashboard
+type FlagDefaults struct {
+	// Flags holds the defaults for the flags of all commands, keyed by the flag name
+	Flags map[string]FlagValues `yaml:"flags"`
+	// Commands holds the defaults for the flags of a single command, keyed by the command name. They
+	// take precedence over Flags.
+	Commands map[string]map[string]FlagValues `yaml:"commands"`
+}
+
+// LoadFlagDefaults loads the flag defaults from the OTA config directory. A missing file is not an
+// error and results in no defaults.
+func LoadFlagDefaults() (*FlagDefaults, error) {
+	raw, path, err := ReadConfigFile(flagDefaultsFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return &FlagDefaults{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read flag defaults %s: %w", path, err)
+	}
+
+	var defaults FlagDefaults
+	if err := yaml.Unmarshal(raw, &defaults); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal flag defaults %s: %w", path, err)
+	}
+	return &defaults, nil
+}
+
+// Save writes the flag defaults into the OTA config directory
+func (d *FlagDefaults) Save() error {
+	raw, err := yaml.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("cannot marshal flag defaults: %w", err)
+	}
+	path, err := OtaConfigPath(flagDefaultsFileName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("cannot create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return fmt.Errorf("cannot write flag defaults %s: %w", path, err)
+	}
+	return nil
+}
+
+// scope returns the defaults of the command, or the defaults for all commands when command is empty
+func (d *FlagDefaults) scope(command string) map[string]FlagValues {
+	if command == "" {
+		return d.Flags
+	}
+	return d.Commands[command]
+}
+
+// Get returns the configured default of the flag for the command (or for all commands when command is
+// empty), falling back to the default for all commands
+func (d *FlagDefaults) Get(command, name string) (FlagValues, bool) {
+	if values, ok := d.scope(command)[name]; ok {
+		return values, true
+	}
+	values, ok := d.Flags[name]
+	return values, ok
+}
+
+// Set configures the default of the flag for the command, or for all commands when command is empty
+func (d *FlagDefaults) Set(command, name string, values FlagValues) {
+	if command == "" {
+		if d.Flags == nil {
+			d.Flags = map[string]FlagValues{}
+		}
+		d.Flags[name] = values
+		return
+	}
+	if d.Commands == nil {
+		d.Commands = map[string]map[string]FlagValues{}
+	}
+	if d.Commands[command] == nil {
+		d.Commands[command] = map[string]FlagValues{}
+	}
+	d.Commands[command][name] = values
+}
+
+// Unset removes the default of the flag for the command, or for all commands when command is empty
+func (d *FlagDefaults) Unset(command, name string) {
+	delete(d.scope(command), name)
+	if command != "" && len(d.Commands[command]) == 0 {
+		delete(d.Commands, command)
+	}
+}
+
+// FlagDefault is a single configured default; Command is empty for the defaults of all commands
+type FlagDefault struct {
+	Command string
+	Name    string
+	Values  FlagValues
+}
+
+// List returns all configured defaults, the defaults for all commands first, sorted by the command and
+// flag names
+func (d *FlagDefaults) List() []FlagDefault {
+	var all []FlagDefault
+	for name, values := range d.Flags {
+		all = append(all, FlagDefault{Name: name, Values: values})
+	}
+	for command, defaults := range d.Commands {
+		for name, values := range defaults {
+			all = append(all, FlagDefault{Command: command, Name: name, Values: values})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Command != all[j].Command {
+			return all[i].Command < all[j].Command
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// Apply sets the flags that were not passed on the command line to their configured defaults, so
+// that flags always win over the configuration. Configured flags the command does not have are
+// ignored, so that a single set of defaults can serve all commands.
+func (d *FlagDefaults) Apply(fs *flag.FlagSet, command string) error {
+	passed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+
+	var errs []error
+	set := func(defaults map[string]FlagValues, source string) {
+		for name, values := range defaults {
+			if passed[name] || fs.Lookup(name) == nil {
+				continue
+			}
+			for _, value := range values {
+				if err := fs.Set(name, value); err != nil {
+					errs = append(errs, fmt.Errorf("%s: --%s: %w", source, name, err))
+				}
+			}
+		}
+	}
+	if commandDefaults, ok := d.Commands[command]; ok {
+		set(commandDefaults, "commands."+command)
+		// Flags set from the command defaults must not be overridden by the global ones
+		fs.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+	}
+	set(d.Flags, "flags")
+	return errors.Join(errs...)
+}
+
+// ApplyFlagDefaults loads the flag defaults and applies them to the flags of the command, which is
+// identified by the base name of the FlagSet name (usually os.Args[0]). The flags still not set that
+// default to files in the config directory then follow --config-dir.
+func ApplyFlagDefaults(fs *flag.FlagSet) error {
+	defaults, err := LoadFlagDefaults()
+	if err != nil {
+		return err
+	}
+	if err := defaults.Apply(fs, filepath.Base(fs.Name())); err != nil {
+		return err
+	}
+	return FollowConfigDir(fs)
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
+)
+
+func TestFlagDefaultsApply(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(configDirEnv, configDir)
+	raw := `flags:
+  graph-repository-path: /global/graph
+  output: json
+  read-only: true
+  unknown-flag: ignored
+  channel: [candidate-4.16, candidate-4.17]
+commands:
+  graph-risks:
+    output: markdown
+`
+	if err := os.WriteFile(filepath.Join(configDir, flagDefaultsFileName), []byte(raw), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		command string
+		args    []string
+
+		expectedPath     string
+		expectedOutput   string
+		expectedChannels []string
+	}{
+		{
+			name:             "global defaults",
+			command:          "graph-lint",
+			expectedPath:     "/global/graph",
+			expectedOutput:   "json",
+			expectedChannels: []string{"candidate-4.16", "candidate-4.17"},
+		},
+		{
+			name:             "command defaults take precedence over global ones",
+			command:          "/usr/bin/graph-risks",
+			expectedPath:     "/global/graph",
+			expectedOutput:   "markdown",
+			expectedChannels: []string{"candidate-4.16", "candidate-4.17"},
+		},
+		{
+			name:             "flags take precedence over defaults",
+			command:          "graph-risks",
+			args:             []string{"--output=table", "--graph-repository-path=/flag/graph", "--channel=stable-4.16"},
+			expectedPath:     "/flag/graph",
+			expectedOutput:   "table",
+			expectedChannels: []string{"stable-4.16"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var path, output string
+			var readOnly bool
+			var channels prowflagutil.Strings
+			fs := flag.NewFlagSet(tc.command, flag.ContinueOnError)
+			fs.StringVar(&path, "graph-repository-path", "", "")
+			fs.StringVar(&output, "output", "table", "")
+			fs.BoolVar(&readOnly, "read-only", false, "")
+			fs.Var(&channels, "channel", "")
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ApplyFlagDefaults(fs); err != nil {
+				t.Fatalf("ApplyFlagDefaults failed: %v", err)
+			}
+			if path != tc.expectedPath || output != tc.expectedOutput || !readOnly {
+				t.Errorf("expected path=%s output=%s read-only=true, got path=%s output=%s read-only=%t", tc.expectedPath, tc.expectedOutput, path, output, readOnly)
+			}
+			if diff := cmp.Diff(tc.expectedChannels, channels.Strings()); diff != "" {
+				t.Errorf("channels differ from expected:\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,22 +1,100 @@
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const (
 	// configDirName is a directory in the user's config directory where OTA configuration is stored
 	configDirName string = "ota"
+
+	// configDirEnv overrides the OTA config directory
+	configDirEnv = "OTA_CONFIG_DIR"
+	// configDirFlag overrides the OTA config directory and takes precedence over configDirEnv
+	configDirFlag = "config-dir"
+)
+
+var (
+	// configDirOverride is set by --config-dir
+	configDirOverride string
+	// configDirAtFlags is the config directory at the time the flags were added; flags defaulting to
+	// files in it follow --config-dir
+	configDirAtFlags string
 )
 
-func MustOtaConfigDir() string {
-	configDig, err := os.UserConfigDir()
+// OtaConfigDir returns the directory where OTA configuration is stored: the --config-dir flag,
+// $OTA_CONFIG_DIR, or ota in the user config directory ($XDG_CONFIG_HOME/ota or ~/.config/ota on
+// Linux). An error means none of them is available, e.g. in a container without HOME.
+func OtaConfigDir() (string, error) {
+	if configDirOverride != "" {
+		return configDirOverride, nil
+	}
+	if dir := os.Getenv(configDirEnv); dir != "" {
+		return dir, nil
+	}
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot obtain user config dir (set $%s or --%s): %w", configDirEnv, configDirFlag, err)
+	}
+	return filepath.Join(userConfigDir, configDirName), nil
+}
+
+// OtaConfigPath returns the path of the named file in the OTA config directory
+func OtaConfigPath(name string) (string, error) {
+	dir, err := OtaConfigDir()
 	if err != nil {
-		panic(fmt.Errorf("cannot obtain user config dir: %w", err))
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// ReadConfigFile reads the named file from the OTA config directory and also returns its path. Without
+// a config directory the error wraps os.ErrNotExist, so that the tools fall back to their defaults like
+// with a missing file.
+func ReadConfigFile(name string) ([]byte, string, error) {
+	path, err := OtaConfigPath(name)
+	if err != nil {
+		return nil, name, fmt.Errorf("%w: %w", os.ErrNotExist, err)
+	}
+	raw, err := os.ReadFile(path)
+	return raw, path, err
+}
+
+// AddConfigDirFlag injects the --config-dir flag into the given FlagSet. It must be added before the
+// flags that default to files in the config directory.
+func AddConfigDirFlag(fs *flag.FlagSet) {
+	configDirAtFlags, _ = OtaConfigDir()
+	fs.Func(configDirFlag, fmt.Sprintf("The OTA config directory (default: $%s, or ota in the user config directory)", configDirEnv), func(dir string) error {
+		if dir == "" {
+			return errors.New("must not be empty")
+		}
+		configDirOverride = dir
+		return nil
+	})
+}
+
+// FollowConfigDir points the flags that were not set and default to files in the config directory
+// (like the token files) to the same files in the directory passed with --config-dir
+func FollowConfigDir(fs *flag.FlagSet) error {
+	if configDirOverride == "" || configDirAtFlags == "" || configDirOverride == configDirAtFlags {
+		return nil
 	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
 
-	otaConfigDir := filepath.Join(configDig, configDirName)
-	return otaConfigDir
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || !strings.HasPrefix(f.DefValue, configDirAtFlags+string(filepath.Separator)) {
+			return
+		}
+		if err := fs.Set(f.Name, filepath.Join(configDirOverride, strings.TrimPrefix(f.DefValue, configDirAtFlags))); err != nil {
+			errs = append(errs, fmt.Errorf("--%s: %w", f.Name, err))
+		}
+	})
+	return errors.Join(errs...)
 }
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOtaConfigDir(t *testing.T) {
+	userConfigDir := t.TempDir()
+
+	testCases := []struct {
+		name string
+		home string
+		env  string
+		args []string
+
+		expected      string
+		expectedError bool
+	}{
+		{name: "user config dir", home: userConfigDir, expected: filepath.Join(userConfigDir, configDirName)},
+		{name: "environment", home: userConfigDir, env: "/env/ota", expected: "/env/ota"},
+		{name: "flag wins over environment", home: userConfigDir, env: "/env/ota", args: []string{"--config-dir=/flag/ota"}, expected: "/flag/ota"},
+		{name: "no home", expectedError: true},
+		{name: "no home with environment", env: "/env/ota", expected: "/env/ota"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() { configDirOverride, configDirAtFlags = "", "" })
+			t.Setenv("XDG_CONFIG_HOME", tc.home)
+			t.Setenv("HOME", "")
+			t.Setenv(configDirEnv, tc.env)
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			AddConfigDirFlag(fs)
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatalf("cannot parse args: %v", err)
+			}
+
+			dir, err := OtaConfigDir()
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %t, got %v", tc.expectedError, err)
+			}
+			if dir != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, dir)
+			}
+
+			// without a config directory, the config files are missing rather than broken
+			if _, _, err := ReadConfigFile(flagDefaultsFileName); tc.expectedError && !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("expected a missing file, got %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyFlagDefaultsFollowsConfigDir(t *testing.T) {
+	t.Cleanup(func() { configDirOverride, configDirAtFlags = "", "" })
+	defaultDir, flagDir := t.TempDir(), t.TempDir()
+	t.Setenv(configDirEnv, defaultDir)
+	if err := os.WriteFile(filepath.Join(flagDir, flagDefaultsFileName), []byte("flags:\n  output: json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	AddConfigDirFlag(fs)
+	token := fs.String("token-file", filepath.Join(defaultDir, "token"), "")
+	passed := fs.String("other-token-file", filepath.Join(defaultDir, "other"), "")
+	output := fs.String("output", "table", "")
+	if err := fs.Parse([]string{"--config-dir", flagDir, "--other-token-file", filepath.Join(defaultDir, "other")}); err != nil {
+		t.Fatalf("cannot parse args: %v", err)
+	}
+	if err := ApplyFlagDefaults(fs); err != nil {
+		t.Fatalf("ApplyFlagDefaults failed: %v", err)
+	}
+
+	if expected := filepath.Join(flagDir, "token"); *token != expected {
+		t.Errorf("expected token file %q, got %q", expected, *token)
+	}
+	if expected := filepath.Join(defaultDir, "other"); *passed != expected {
+		t.Errorf("expected passed token file %q, got %q", expected, *passed)
+	}
+	if *output != "json" {
+		t.Errorf("expected the defaults from the --config-dir directory to apply, got output %q", *output)
+	}
+}
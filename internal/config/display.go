@@ -0,0 +1,41 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// displayFileName is a file in the OTA config directory with the user's output formatting preferences
+	displayFileName string = "display.yaml"
+)
+
+// Display holds the user's preferences for formatting the tools' output
+type Display struct {
+	// AbsoluteTimestamps shows timestamps instead of humanized ages (e.g. "3d 2h") in tables
+	AbsoluteTimestamps bool `yaml:"absoluteTimestamps"`
+	// Timezone is the IANA timezone (e.g. Europe/Prague) of the absolute timestamps; local time when empty
+	Timezone string `yaml:"timezone"`
+}
+
+// LoadDisplay reads the display preferences from the OTA config directory. A missing file is not an
+// error and results in the defaults.
+func LoadDisplay() (Display, error) {
+	var display Display
+
+	raw, path, err := ReadConfigFile(displayFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return display, nil
+	}
+	if err != nil {
+		return display, fmt.Errorf("cannot read display config %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(raw, &display); err != nil {
+		return display, fmt.Errorf("cannot unmarshal display config %s: %w", path, err)
+	}
+	return display, nil
+}
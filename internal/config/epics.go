@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// epicsFileName is a file in the OTA config directory with the epics of the component projects
+	epicsFileName string = "epics.yaml"
+)
+
+// Epics maps the keys of component projects to the keys of the epics that the impact statement
+// request cards created in the project are linked to, like the team's tracking epic
+type Epics map[string]string
+
+// LoadEpics reads the epics of the component projects from the OTA config directory. A missing file
+// is not an error and results in no epics.
+func LoadEpics() (Epics, error) {
+	epics := Epics{}
+
+	raw, path, err := ReadConfigFile(epicsFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return epics, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read epics config %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(raw, &epics); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal epics config %s: %w", path, err)
+	}
+	return epics, nil
+}
+
+// For returns the epic of the project or an empty string when the project has none
+func (e Epics) For(project string) string {
+	for key, epic := range e {
+		if strings.EqualFold(key, project) {
+			return epic
+		}
+	}
+	return ""
+}
+
+// Save writes the epics into the OTA config directory
+func (e Epics) Save() error {
+	raw, err := yaml.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("cannot marshal epics: %w", err)
+	}
+	path, err := OtaConfigPath(epicsFileName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("cannot create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return fmt.Errorf("cannot write epics config %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// jqlFragmentsFileName is a file in the OTA config directory with the user's JQL fragments library
+	jqlFragmentsFileName string = "jql-fragments.yaml"
+
+	// maxFragmentDepth limits how deeply fragments may reference other fragments
+	maxFragmentDepth = 10
+)
+
+// DefaultJQLFragments are the fragments available even when the user does not configure any. Fragments
+// with the same name in the user's library override these.
+var DefaultJQLFragments = map[string]string{
+	"ocpbugs":          "project = OCPBUGS",
+	"upgrade_blockers": "{{frag:ocpbugs}} AND labels in (UpgradeBlocker)",
+	// impact_statement_requests are the Spike cards created by create-impact-statement-request
+	"impact_statement_requests": "issuetype = Spike AND labels in (UpgradeBlocker)",
+}
+
+var fragmentRegexp = regexp.MustCompile(`\{\{frag:([A-Za-z0-9_-]+)\}\}`)
+
+// JQLFragments is a library of named JQL fragments that can be referenced as {{frag:name}} in queries
+type JQLFragments map[string]string
+
+type jqlFragmentsFile struct {
+	Fragments map[string]string `yaml:"fragments"`
+}
+
+// LoadJQLFragments returns the default fragments merged with the user's fragments library from the
+// OTA config directory. A missing library is not an error.
+func LoadJQLFragments() (JQLFragments, error) {
+	fragments := JQLFragments{}
+	for name, fragment := range DefaultJQLFragments {
+		fragments[name] = fragment
+	}
+
+	raw, path, err := ReadConfigFile(jqlFragmentsFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return fragments, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read JQL fragments %s: %w", path, err)
+	}
+
+	var file jqlFragmentsFile
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal JQL fragments %s: %w", path, err)
+	}
+	for name, fragment := range file.Fragments {
+		fragments[name] = fragment
+	}
+	return fragments, nil
+}
+
+// Expand replaces all {{frag:name}} references in the query with the referenced fragments, which
+// may themselves reference other fragments
+func (f JQLFragments) Expand(query string) (string, error) {
+	for depth := 0; fragmentRegexp.MatchString(query); depth++ {
+		if depth == maxFragmentDepth {
+			return "", fmt.Errorf("JQL fragments nested too deeply (cyclic reference?) in %q", query)
+		}
+
+		var missing []string
+		query = fragmentRegexp.ReplaceAllStringFunc(query, func(reference string) string {
+			name := fragmentRegexp.FindStringSubmatch(reference)[1]
+			fragment, ok := f[name]
+			if !ok {
+				missing = append(missing, name)
+				return reference
+			}
+			return "(" + fragment + ")"
+		})
+		if len(missing) > 0 {
+			return "", fmt.Errorf("unknown JQL fragments: %v", missing)
+		}
+	}
+	return query, nil
+}
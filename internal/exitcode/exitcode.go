@@ -0,0 +1,64 @@
+// Package exitcode defines the exit codes shared by all tools, so that CI jobs and scripts can branch
+// on the outcome of a command instead of parsing its logs:
+//
+//	0  success, nothing to report
+//	1  unexpected error
+//	2  invalid command line options
+//	3  validation failed: the command ran and found problems (lint findings, failed checklist items)
+//	4  changes detected: the command ran and found changes it was asked to report (e.g. --exit-code)
+//	5  Jira rejected the credentials (HTTP 401 or 403)
+//	6  partial failure: the command failed after it already changed something; rerun to continue
+//	7  configuration error: a file in the ota config directory cannot be loaded
+//	130 interrupted: the command was aborted by SIGINT or SIGTERM (128 + SIGINT, like shells report it)
+package exitcode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	prowjira "sigs.k8s.io/prow/pkg/jira"
+)
+
+const (
+	Success          = 0
+	Error            = 1
+	InvalidOptions   = 2
+	ValidationFailed = 3
+	ChangesDetected  = 4
+	JiraAuth         = 5
+	PartialFailure   = 6
+	Config           = 7
+	Interrupted      = 130
+)
+
+// Fatal logs the error like logrus.Fatal does, but exits with the given code. Unexpected errors caused
+// by an interrupt exit with Interrupted instead of Error.
+func Fatal(code int, err error, msg string) {
+	if code == Error && errors.Is(err, context.Canceled) {
+		code = Interrupted
+	}
+	logrus.WithError(err).Error(msg)
+	os.Exit(code)
+}
+
+// JiraFatal logs the error of a Jira request and exits with JiraAuth when Jira rejected the
+// credentials, or with Error otherwise
+func JiraFatal(err error, msg string) {
+	Fatal(ForJira(err), err, msg)
+}
+
+// ForJira returns JiraAuth when the error of a Jira request means the credentials were rejected,
+// Interrupted when the request was aborted by an interrupt, and Error otherwise
+func ForJira(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return Interrupted
+	}
+	switch prowjira.JiraErrorStatusCode(err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return JiraAuth
+	}
+	return Error
+}
@@ -0,0 +1,40 @@
+package flagutil
+
+import (
+	"flag"
+
+	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
+
+	"github.com/petr-muller/ota/internal/jirautil"
+)
+
+// BugProjectsOptions selects the Jira projects whose cards are treated as bugs when following links
+// between cards, e.g. to also follow RHEL trackers linked to an OCPBUGS card
+type BugProjectsOptions struct {
+	projects prowflagutil.Strings
+}
+
+// AddFlags injects the bug projects options into the given FlagSet
+func (o *BugProjectsOptions) AddFlags(fs *flag.FlagSet) {
+	o.projects = prowflagutil.NewStrings(jirautil.DefaultBugProject)
+	fs.Var(&o.projects, "bug-project", "Jira project whose cards are treated as bugs; can be passed multiple times. Numerical --bug values refer to the first one.")
+}
+
+// Projects returns the keys of the bug projects
+func (o *BugProjectsOptions) Projects() []string {
+	projects := o.projects.Strings()
+	if len(projects) == 0 {
+		return []string{jirautil.DefaultBugProject}
+	}
+	return projects
+}
+
+// IsBug returns true if the card with the given key belongs to one of the bug projects
+func (o *BugProjectsOptions) IsBug(key string) bool {
+	return jirautil.InProjects(key, o.Projects())
+}
+
+// BugKey returns the key of the bug with the given number in the first bug project
+func (o *BugProjectsOptions) BugKey(number int) string {
+	return jirautil.Key(o.Projects()[0], number)
+}
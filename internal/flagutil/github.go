@@ -0,0 +1,45 @@
+package flagutil
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/petr-muller/ota/internal/github"
+)
+
+// GitHubOptions holds the options of the commands that read pull requests of the graph repository on
+// GitHub. The token is optional because the graph repository is public.
+type GitHubOptions struct {
+	repository string
+	tokenPath  string
+}
+
+// AddFlags injects the GitHub options into the given FlagSet
+func (o *GitHubOptions) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.repository, "github-repository", github.GraphRepository, "The GitHub repository whose pull requests change the graph repository")
+	fs.StringVar(&o.tokenPath, "github-token-file", defaultGitHubTokenPath(), "Path to the file with the GitHub token; GitHub is queried anonymously when the file does not exist")
+}
+
+// Repository returns the GitHub repository ("owner/name") of the graph repository
+func (o *GitHubOptions) Repository() string {
+	return o.repository
+}
+
+// Client returns a GitHub client authenticated with the token from the token file, or an anonymous
+// client when there is no token file
+func (o *GitHubOptions) Client() (*github.Client, error) {
+	if o.tokenPath == "" {
+		return github.NewClient(""), nil
+	}
+	raw, err := os.ReadFile(o.tokenPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return github.NewClient(""), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read GitHub token: %w", err)
+	}
+	return github.NewClient(strings.TrimSpace(string(raw))), nil
+}
@@ -0,0 +1,115 @@
+package flagutil
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/petr-muller/ota/internal/gitutil"
+)
+
+// GraphGitOptions make the graph tools that write blocked edges aware of the git repository they
+// write into, so that they do not mix their changes with (or overwrite) uncommitted work
+type GraphGitOptions struct {
+	force      bool
+	workBranch string
+	riskBranch bool
+	showDiff   bool
+}
+
+// AddFlags injects the graph repository git options into the given FlagSet
+func (o *GraphGitOptions) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.force, "force", false, "Write blocked edges even when the graph repository has uncommitted changes, overwriting uncommitted changes of the written files")
+	fs.StringVar(&o.workBranch, "work-branch", "", "A branch where uncommitted changes in the graph repository are expected; when it is checked out, only uncommitted changes of the written files are refused")
+	fs.BoolVar(&o.riskBranch, "branch-per-risk", false, "Check out a branch named after the risk (created when it does not exist) before writing blocked edges")
+	fs.BoolVar(&o.showDiff, "show-diff", false, "Print the diff of the written blocked edges")
+}
+
+// Prepare verifies that the blocked edges can be written into the graph repository and checks out
+// the risk branch when requested, returning its name (empty when no branch was checked out). Writing
+// is refused (unless forced) when any of the written paths has uncommitted changes, or when there are
+// other uncommitted changes outside of the work branch. Uncommitted changes of the paths in expected
+// (such as a manually edited source of the changes) are not considered.
+func (o *GraphGitOptions) Prepare(repositoryPath, risk string, written []string, expected ...string) (string, error) {
+	repository := gitutil.Repository{Path: repositoryPath}
+	if !repository.IsRepository() {
+		logrus.Warnf("%s is not a git repository, cannot check for uncommitted changes", repositoryPath)
+		return "", nil
+	}
+
+	changed, err := repository.Changed()
+	if err != nil {
+		return "", err
+	}
+	uncommitted := sets.New[string]()
+	for _, path := range changed {
+		uncommitted.Insert(absolute(path))
+	}
+	for _, path := range expected {
+		uncommitted.Delete(absolute(path))
+	}
+
+	if !o.force && uncommitted.Len() > 0 {
+		var clobbered []string
+		for _, path := range written {
+			if uncommitted.Has(absolute(path)) {
+				clobbered = append(clobbered, path)
+			}
+		}
+		if len(clobbered) > 0 {
+			return "", fmt.Errorf("refusing to overwrite uncommitted changes in %s (use --force to overwrite them)", strings.Join(clobbered, ", "))
+		}
+
+		current, err := repository.CurrentBranch()
+		if err != nil {
+			return "", err
+		}
+		if o.workBranch == "" || current != o.workBranch {
+			return "", fmt.Errorf("graph repository has uncommitted changes in %d files, e.g. %s (commit them, check out the --work-branch or use --force)", uncommitted.Len(), sets.List(uncommitted)[0])
+		}
+	}
+
+	if !o.riskBranch {
+		return "", nil
+	}
+	logrus.Infof("Checking out branch %s in the graph repository", risk)
+	if err := repository.SwitchBranch(risk); err != nil {
+		return "", err
+	}
+	return risk, nil
+}
+
+// ShowDiff prints the diff of the written paths when requested
+func (o *GraphGitOptions) ShowDiff(repositoryPath string, written []string) {
+	if !o.showDiff {
+		return
+	}
+	repository := gitutil.Repository{Path: repositoryPath}
+	if !repository.IsRepository() {
+		return
+	}
+	diff, err := repository.Diff(written...)
+	if err != nil {
+		logrus.WithError(err).Warn("Cannot show the diff of the written blocked edges")
+		return
+	}
+	fmt.Println(diff)
+}
+
+// absolute returns the absolute form of the path with symlinks resolved, so that paths reported by
+// git and paths built from --graph-repository-path can be compared
+func absolute(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	} else if resolvedDir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		path = filepath.Join(resolvedDir, filepath.Base(path))
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
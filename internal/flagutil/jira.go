@@ -2,32 +2,94 @@ package flagutil
 
 import (
 	"flag"
-	"path/filepath"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
 
 	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/jirautil"
 	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
 )
 
 const (
+	// DefaultJiraEndpoint is the Jira instance the tools work with unless --jira-endpoint says otherwise
+	DefaultJiraEndpoint = "https://issues.redhat.com"
+
 	tokenFileName string = "jira-token"
+
+	// readOnlyEnv allows enabling the read-only mode for all commands, e.g. in cron jobs
+	readOnlyEnv string = "OTA_JIRA_READ_ONLY"
 )
 
 type JiraOptions struct {
 	prowflagutil.JiraOptions
+
+	readOnly          bool
+	commentVisibility string
+}
+
+// DefaultTokenPath returns the path of the file with the Jira personal access token in the OTA config
+// directory, or an empty string when there is no config directory
+func DefaultTokenPath() string {
+	path, _ := config.OtaConfigPath(tokenFileName)
+	return path
 }
 
 // AddFlags injects Jira options into the given FlagSet
 func (o *JiraOptions) AddFlags(fs *flag.FlagSet) {
-	configDir := config.MustOtaConfigDir()
-	defaultTokenPath := filepath.Join(configDir, tokenFileName)
-
 	o.JiraOptions.AddCustomizedFlags(fs,
-		prowflagutil.JiraDefaultEndpoint("https://issues.redhat.com"),
-		prowflagutil.JiraDefaultBearerTokenFile(defaultTokenPath),
+		prowflagutil.JiraDefaultEndpoint(DefaultJiraEndpoint),
+		prowflagutil.JiraDefaultBearerTokenFile(DefaultTokenPath()),
 		prowflagutil.JiraNoBasicAuth(),
 	)
+
+	readOnly, _ := strconv.ParseBool(os.Getenv(readOnlyEnv))
+	fs.BoolVar(&o.readOnly, "read-only", readOnly, "Refuse any change in Jira (labels, comments, transitions...); defaults to $"+readOnlyEnv)
+	fs.StringVar(&o.commentVisibility, "comment-visibility", "", "Restrict posted comments to a group or role, e.g. 'group:Red Hat Employee' (default: comments.yaml in the config directory)")
 }
 
 func (o *JiraOptions) Validate() error {
+	if o.commentVisibility != "" {
+		if _, err := o.parseCommentVisibility(); err != nil {
+			return err
+		}
+	}
 	return o.JiraOptions.Validate(false)
 }
+
+func (o *JiraOptions) parseCommentVisibility() (config.CommentVisibility, error) {
+	visibilityType, value, found := strings.Cut(o.commentVisibility, ":")
+	if !found {
+		return config.CommentVisibility{}, fmt.Errorf("--comment-visibility must be in the form group:<name> or role:<name>")
+	}
+	visibility := config.CommentVisibility{Type: visibilityType, Value: value}
+	if err := visibility.Validate(); err != nil {
+		return visibility, fmt.Errorf("invalid --comment-visibility: %w", err)
+	}
+	return visibility, nil
+}
+
+// CommentVisibility returns the visibility set with --comment-visibility, or the given default when the flag is not set
+func (o *JiraOptions) CommentVisibility(defaultVisibility jira.CommentVisibility) jira.CommentVisibility {
+	if o.commentVisibility == "" {
+		return defaultVisibility
+	}
+	visibility, _ := o.parseCommentVisibility()
+	return jira.CommentVisibility{Type: visibility.Type, Value: visibility.Value}
+}
+
+// Client returns a Jira client, which refuses to make any changes in Jira when --read-only is set
+func (o *JiraOptions) Client() (jirautil.Client, error) {
+	client, err := o.JiraOptions.Client()
+	if err != nil {
+		return nil, err
+	}
+	extended := jirautil.Extend(client)
+	if o.readOnly {
+		return jirautil.ReadOnly(extended), nil
+	}
+	return extended, nil
+}
@@ -0,0 +1,53 @@
+package flagutil
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// LogOptions holds the logging options shared by all tools. Logs always go to stderr so that they do
+// not mix with the output of the tools on stdout when it is piped.
+type LogOptions struct {
+	level  string
+	format string
+
+	parsed logrus.Level
+}
+
+// AddFlags injects the logging options into the given FlagSet
+func (o *LogOptions) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.level, "log-level", logrus.InfoLevel.String(), "Logging level: trace, debug (e.g. HTTP connection reuse and timing), info, warning or error")
+	fs.StringVar(&o.format, "log-format", LogFormatText, fmt.Sprintf("Logging format: %s or %s", LogFormatText, LogFormatJSON))
+}
+
+func (o *LogOptions) Validate() error {
+	level, err := logrus.ParseLevel(o.level)
+	if err != nil {
+		return fmt.Errorf("--log-level: %w", err)
+	}
+	o.parsed = level
+
+	if o.format != LogFormatText && o.format != LogFormatJSON {
+		return fmt.Errorf("--log-format must be either %s or %s", LogFormatText, LogFormatJSON)
+	}
+	return nil
+}
+
+// Apply sets the validated logging level and format on the logrus standard logger
+func (o *LogOptions) Apply() {
+	logrus.SetLevel(o.parsed)
+	logrus.SetOutput(os.Stderr)
+	if o.format == LogFormatJSON {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	}
+}
@@ -0,0 +1,36 @@
+package flagutil
+
+import (
+	"flag"
+
+	"github.com/petr-muller/ota/internal/notify"
+)
+
+// NotifyOptions holds the options of the workflow commands that announce their actions to the sinks
+// configured in notify.yaml
+type NotifyOptions struct {
+	enabled bool
+	channel string
+}
+
+// AddFlags injects the notification options into the given FlagSet
+func (o *NotifyOptions) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.enabled, "notify", false, "Announce the workflow events (impact statement requested or proposed, risk declared) to the sinks configured in notify.yaml")
+	fs.StringVar(&o.channel, "notify-channel", "", "Post the Slack notifications into this channel (e.g. #forum-ota) instead of the channel configured for the sinks")
+}
+
+// Notifier returns the notifier announcing the workflow events, or nil when the notifications are
+// not enabled
+func (o *NotifyOptions) Notifier() (*notify.Notifier, error) {
+	if !o.enabled {
+		return nil, nil
+	}
+	notifier, err := notify.Load()
+	if err != nil {
+		return nil, err
+	}
+	if o.channel != "" {
+		notifier = notifier.WithChannel(o.channel)
+	}
+	return notifier, nil
+}
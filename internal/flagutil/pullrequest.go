@@ -0,0 +1,108 @@
+package flagutil
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/github"
+	"github.com/petr-muller/ota/internal/gitutil"
+)
+
+const githubTokenFileName = "github-token"
+
+// defaultGitHubTokenPath returns the path of the GitHub token file in the OTA config directory, or an
+// empty string when there is no config directory
+func defaultGitHubTokenPath() string {
+	path, _ := config.OtaConfigPath(githubTokenFileName)
+	return path
+}
+
+// PullRequestOptions control proposing the changes the graph tools write into the graph repository
+// as a GitHub pull request
+type PullRequestOptions struct {
+	open       bool
+	comment    bool
+	remote     string
+	base       string
+	repository string
+	tokenPath  string
+}
+
+// AddFlags injects the pull request options into the given FlagSet
+func (o *PullRequestOptions) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.open, "open-pr", false, "Commit the written blocked edges to a new branch, push it and open a pull request")
+	fs.BoolVar(&o.comment, "pr-comment", false, "Comment on the impact statement card with the URL of the opened pull request (with --open-pr)")
+	fs.StringVar(&o.remote, "pr-remote", "origin", "The git remote (a GitHub fork of --pr-repository) to push the branch to (with --open-pr)")
+	fs.StringVar(&o.base, "pr-base", "master", "The branch to open the pull request against (with --open-pr)")
+	fs.StringVar(&o.repository, "pr-repository", github.GraphRepository, "The GitHub repository to open the pull request in (with --open-pr)")
+	fs.StringVar(&o.tokenPath, "github-token-file", defaultGitHubTokenPath(), "Path to the file with the GitHub token used to open the pull request (with --open-pr)")
+}
+
+func (o *PullRequestOptions) Validate() error {
+	if o.comment && !o.open {
+		return fmt.Errorf("--pr-comment requires --open-pr")
+	}
+	if !o.open {
+		return nil
+	}
+	if o.remote == "" || o.base == "" || o.repository == "" || o.tokenPath == "" {
+		return fmt.Errorf("--pr-remote, --pr-base, --pr-repository and --github-token-file must be nonempty with --open-pr")
+	}
+	return nil
+}
+
+// Enabled returns true when a pull request should be opened
+func (o *PullRequestOptions) Enabled() bool {
+	return o.open
+}
+
+// CommentOnCard returns true when the impact statement card should be informed about the pull request
+func (o *PullRequestOptions) CommentOnCard() bool {
+	return o.comment
+}
+
+// Open commits the paths in the graph repository to the branch (created when it does not exist),
+// pushes it and opens a pull request with the title and body, returning its URL. The commit message
+// consists of the title and the body.
+func (o *PullRequestOptions) Open(ctx context.Context, repositoryPath, branch string, paths []string, title, body string) (string, error) {
+	raw, err := os.ReadFile(o.tokenPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot read GitHub token: %w", err)
+	}
+
+	repository := gitutil.Repository{Path: repositoryPath}
+	remoteURL, err := repository.RemoteURL(o.remote)
+	if err != nil {
+		return "", err
+	}
+	owner, err := github.OwnerFromRemoteURL(remoteURL)
+	if err != nil {
+		return "", err
+	}
+
+	logrus.Infof("Committing the changes to branch %s", branch)
+	if err := repository.SwitchBranch(branch); err != nil {
+		return "", err
+	}
+	if err := repository.Commit(title+"\n\n"+body, paths...); err != nil {
+		return "", err
+	}
+	logrus.Infof("Pushing %s to %s", branch, o.remote)
+	if err := repository.Push(o.remote, branch); err != nil {
+		return "", err
+	}
+
+	client := github.NewClient(strings.TrimSpace(string(raw)))
+	return client.CreatePullRequest(ctx, o.repository, github.PullRequest{
+		Title: title,
+		Body:  body,
+		Head:  owner + ":" + branch,
+		Base:  o.base,
+	})
+}
@@ -0,0 +1,95 @@
+package flagutil
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
+
+	"github.com/petr-muller/ota/internal/cincinnati"
+	"github.com/petr-muller/ota/internal/graph"
+)
+
+const (
+	RiskSourceOSUS = "osus"
+	RiskSourceRepo = "repo"
+)
+
+// RiskSourceOptions selects where declared conditional risks are looked up: in a local checkout of
+// the graph repository or in the live graph served by OSUS
+type RiskSourceOptions struct {
+	source              string
+	graphRepositoryPath string
+
+	osusURL  string
+	arch     string
+	channels prowflagutil.Strings
+
+	// index is loaded with the first lookup in the graph repository
+	index *graph.Index
+}
+
+// AddFlags injects risk source options into the given FlagSet
+func (o *RiskSourceOptions) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.source, "source", RiskSourceRepo, fmt.Sprintf("Where to look up conditional risks: %q (local graph repository) or %q (live graph served by OSUS)", RiskSourceRepo, RiskSourceOSUS))
+	fs.StringVar(&o.graphRepositoryPath, "graph-repository-path", "", "The path to the Cincinnati graph repository (with --source=repo)")
+	fs.StringVar(&o.osusURL, "osus-url", cincinnati.DefaultURL, "The OSUS graph endpoint (with --source=osus)")
+	fs.StringVar(&o.arch, "osus-arch", "amd64", "The architecture of the graph served by OSUS (with --source=osus)")
+	fs.Var(&o.channels, "osus-channel", "The channel of the graph served by OSUS, e.g. candidate-4.16 (with --source=osus, can be passed multiple times)")
+}
+
+func (o *RiskSourceOptions) Validate() error {
+	switch o.source {
+	case RiskSourceRepo:
+		if o.graphRepositoryPath == "" {
+			return fmt.Errorf("--graph-repository-path must be specified and nonempty with --source=%s", RiskSourceRepo)
+		}
+	case RiskSourceOSUS:
+		if len(o.channels.Strings()) == 0 {
+			return fmt.Errorf("--osus-channel must be specified at least once with --source=%s", RiskSourceOSUS)
+		}
+	default:
+		return fmt.Errorf("--source must be either %q or %q", RiskSourceRepo, RiskSourceOSUS)
+	}
+	return nil
+}
+
+// Edges returns all blocked edges
+func (o *RiskSourceOptions) Edges(ctx context.Context) ([]graph.ConditionallyBlockedEdge, error) {
+	if o.source == RiskSourceOSUS {
+		return cincinnati.NewClient(o.osusURL, o.arch).BlockedEdges(ctx, o.channels.Strings(), func(cincinnati.Risk) bool { return true })
+	}
+	if err := o.loadIndex(); err != nil {
+		return nil, err
+	}
+	var edges []graph.ConditionallyBlockedEdge
+	_ = o.index.Walk(graph.Filter{}, func(_ string, edge graph.ConditionallyBlockedEdge) error {
+		edges = append(edges, edge)
+		return nil
+	})
+	return edges, nil
+}
+
+func (o *RiskSourceOptions) loadIndex() error {
+	if o.index != nil {
+		return nil
+	}
+	index, err := graph.LoadIndex(o.graphRepositoryPath)
+	if err != nil {
+		return err
+	}
+	o.index = index
+	return nil
+}
+
+// EdgesByURL returns the blocked edges whose risk references the given URL
+func (o *RiskSourceOptions) EdgesByURL(ctx context.Context, url string) ([]graph.ConditionallyBlockedEdge, error) {
+	if o.source == RiskSourceOSUS {
+		return cincinnati.NewClient(o.osusURL, o.arch).BlockedEdges(ctx, o.channels.Strings(), cincinnati.ByURL(url))
+	}
+	if err := o.loadIndex(); err != nil {
+		return nil, err
+	}
+	return o.index.ByURL(url), nil
+}
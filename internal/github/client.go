@@ -0,0 +1,143 @@
+// Package github implements the few GitHub API calls the tools need to propose graph repository changes
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/petr-muller/ota/internal/httputil"
+)
+
+const (
+	// DefaultAPIURL is the endpoint of the public GitHub API
+	DefaultAPIURL = "https://api.github.com"
+	// GraphRepository is the upstream repository of the Cincinnati update graph data
+	GraphRepository = "openshift/cincinnati-graph-data"
+)
+
+// PullRequest describes a pull request to open. Head is "owner:branch" when the branch is in a fork.
+type PullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+}
+
+// Client calls the GitHub API authenticated with a token
+type Client struct {
+	URL   string
+	token string
+}
+
+// NewClient returns a client of the public GitHub API authenticated with the given token. Without a
+// token, the client can only read public repositories with a low rate limit.
+func NewClient(token string) *Client {
+	return &Client{URL: DefaultAPIURL, token: token}
+}
+
+// setHeaders sets the headers every GitHub API request needs
+func (c *Client) setHeaders(req *http.Request) {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
+// responseError returns the error for an unexpected GitHub API response, with the message GitHub sent
+func responseError(resp *http.Response, action string) error {
+	var failure struct {
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&failure)
+	return fmt.Errorf("GitHub returned %s when %s: %s", resp.Status, action, failure.Message)
+}
+
+// CreatePullRequest opens the pull request in the repository ("owner/name") and returns its URL
+func (c *Client) CreatePullRequest(ctx context.Context, repository string, pr PullRequest) (string, error) {
+	raw, err := json.Marshal(pr)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/repos/%s/pulls", c.URL, repository), bytes.NewReader(raw))
+	if err != nil {
+		return "", err
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httputil.Client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot create pull request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusCreated {
+		return "", responseError(resp, "creating a pull request in "+repository)
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("cannot decode created pull request: %w", err)
+	}
+	return created.HTMLURL, nil
+}
+
+// PullRequestState is the state of an existing pull request
+type PullRequestState struct {
+	Number   int        `json:"number"`
+	HTMLURL  string     `json:"html_url"`
+	State    string     `json:"state"`
+	MergedAt *time.Time `json:"merged_at"`
+}
+
+// Merged returns true when the pull request was merged
+func (p PullRequestState) Merged() bool {
+	return p.MergedAt != nil
+}
+
+// CommitPullRequests returns the pull requests in the repository ("owner/name") that contain the
+// commit, or the pull request that merged it
+func (c *Client) CommitPullRequests(ctx context.Context, repository, sha string) ([]PullRequestState, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/repos/%s/commits/%s/pulls", c.URL, repository, sha), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req)
+
+	resp, err := httputil.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list pull requests of commit %s: %w", sha, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, fmt.Sprintf("listing pull requests of commit %s in %s", sha, repository))
+	}
+
+	var pulls []PullRequestState
+	if err := json.NewDecoder(resp.Body).Decode(&pulls); err != nil {
+		return nil, fmt.Errorf("cannot decode pull requests of commit %s: %w", sha, err)
+	}
+	return pulls, nil
+}
+
+var remoteURLRegexp = regexp.MustCompile(`github\.com[:/]([^/]+)/[^/]+?(\.git)?/?$`)
+
+// OwnerFromRemoteURL returns the owner of the GitHub repository with the given git remote URL, in
+// either the SSH (git@github.com:owner/repo.git) or the HTTPS form
+func OwnerFromRemoteURL(url string) (string, error) {
+	match := remoteURLRegexp.FindStringSubmatch(url)
+	if match == nil {
+		return "", fmt.Errorf("%s is not a GitHub repository URL", url)
+	}
+	return match[1], nil
+}
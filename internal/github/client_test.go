@@ -0,0 +1,109 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOwnerFromRemoteURL(t *testing.T) {
+	testCases := []struct {
+		url string
+
+		expected      string
+		expectedError bool
+	}{
+		{url: "git@github.com:petr-muller/cincinnati-graph-data.git", expected: "petr-muller"},
+		{url: "https://github.com/petr-muller/cincinnati-graph-data.git", expected: "petr-muller"},
+		{url: "https://github.com/openshift/cincinnati-graph-data", expected: "openshift"},
+		{url: "https://gitlab.com/petr-muller/cincinnati-graph-data.git", expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			owner, err := OwnerFromRemoteURL(tc.url)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %t, got %v", tc.expectedError, err)
+			}
+			if owner != tc.expected {
+				t.Errorf("expected owner %q, got %q", tc.expected, owner)
+			}
+		})
+	}
+}
+
+func TestCreatePullRequest(t *testing.T) {
+	pr := PullRequest{Title: "Declare NodesFailToDrain", Body: "body", Head: "petr-muller:branch", Base: "master"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/openshift/cincinnati-graph-data/pulls" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer token" {
+			t.Errorf("unexpected Authorization header %q", auth)
+		}
+		var got PullRequest
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("cannot decode request: %v", err)
+		}
+		if diff := cmp.Diff(pr, got); diff != "" {
+			t.Errorf("unexpected pull request (-want +got):\n%s", diff)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"html_url": "https://github.com/openshift/cincinnati-graph-data/pull/1"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("token")
+	client.URL = server.URL
+	url, err := client.CreatePullRequest(context.Background(), GraphRepository, pr)
+	if err != nil {
+		t.Fatalf("CreatePullRequest failed: %v", err)
+	}
+	if url != "https://github.com/openshift/cincinnati-graph-data/pull/1" {
+		t.Errorf("unexpected pull request URL %s", url)
+	}
+}
+
+func TestCommitPullRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("unexpected Authorization header %q without a token", auth)
+		}
+		switch r.URL.Path {
+		case "/repos/openshift/cincinnati-graph-data/commits/abc/pulls":
+			_, _ = w.Write([]byte(`[
+				{"number": 2, "html_url": "https://github.com/openshift/cincinnati-graph-data/pull/2", "state": "closed", "merged_at": null},
+				{"number": 1, "html_url": "https://github.com/openshift/cincinnati-graph-data/pull/1", "state": "closed", "merged_at": "2024-06-01T10:00:00Z"}
+			]`))
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"message": "No commit found for SHA: def"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("")
+	client.URL = server.URL
+	pulls, err := client.CommitPullRequests(context.Background(), GraphRepository, "abc")
+	if err != nil {
+		t.Fatalf("CommitPullRequests failed: %v", err)
+	}
+	var merged []int
+	for _, pull := range pulls {
+		if pull.Merged() {
+			merged = append(merged, pull.Number)
+		}
+	}
+	if len(pulls) != 2 || !cmp.Equal([]int{1}, merged) {
+		t.Errorf("expected pull requests 2 and 1 with 1 merged, got %+v", pulls)
+	}
+
+	if _, err := client.CommitPullRequests(context.Background(), GraphRepository, "def"); err == nil {
+		t.Errorf("expected an error for an unknown commit")
+	}
+}
@@ -0,0 +1,228 @@
+// Package gitutil runs git in local checkouts, such as the graph repository the graph tools write into
+package gitutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Repository is a local git checkout
+type Repository struct {
+	Path string
+}
+
+// run executes git in the repository and returns its standard output without the trailing newlines,
+// also when git fails. The error includes what git printed to its standard error.
+func (r Repository) run(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.Path}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	output := strings.TrimRight(stdout.String(), "\n")
+	if err != nil {
+		return output, fmt.Errorf("git %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// IsRepository returns true if the path is inside a git working tree
+func (r Repository) IsRepository() bool {
+	_, err := r.run("rev-parse", "--is-inside-work-tree")
+	return err == nil
+}
+
+// CurrentBranch returns the name of the checked out branch ("HEAD" when detached)
+func (r Repository) CurrentBranch() (string, error) {
+	return r.run("rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// SwitchBranch checks out the branch, creating it at the current HEAD when it does not exist.
+// Uncommitted changes are carried over to the branch.
+func (r Repository) SwitchBranch(name string) error {
+	current, err := r.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	if current == name {
+		return nil
+	}
+	if _, err := r.run("rev-parse", "--verify", "--quiet", "refs/heads/"+name); err == nil {
+		_, err = r.run("checkout", name)
+		return err
+	}
+	_, err = r.run("checkout", "-b", name)
+	return err
+}
+
+// Changed returns the absolute paths of the files with uncommitted changes (including untracked
+// files) among the given paths, or in the whole working tree when no paths are given
+func (r Repository) Changed(paths ...string) ([]string, error) {
+	root, err := r.run("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	status, err := r.run(append([]string{"status", "--porcelain", "--untracked-files=all", "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for _, line := range strings.Split(status, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		path := line[3:]
+		if _, renamed, ok := strings.Cut(path, " -> "); ok {
+			path = renamed
+		}
+		changed = append(changed, filepath.Join(root, path))
+	}
+	return changed, nil
+}
+
+// Diff returns the uncommitted changes of the given paths as a unified diff, including the content of
+// untracked files
+func (r Repository) Diff(paths ...string) (string, error) {
+	var diffs []string
+	for _, path := range paths {
+		args := []string{"diff", "HEAD", "--", path}
+		if _, err := r.run("ls-files", "--error-unmatch", "--", path); err != nil {
+			args = []string{"diff", "--no-index", "--", "/dev/null", path}
+		}
+		diff, err := r.run(args...)
+		// --no-index exits with 1 when the files differ
+		var exitErr *exec.ExitError
+		if err != nil && !(args[1] == "--no-index" && errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+			return "", err
+		}
+		if diff != "" {
+			diffs = append(diffs, diff)
+		}
+	}
+	return strings.Join(diffs, "\n"), nil
+}
+
+// Added returns the time when each file matching the pathspec was first added to the checked out
+// branch. Only the first parent of merge commits is followed, so for changes merged from pull requests
+// this is the time of the merge.
+func (r Repository) Added(pathspec string) (map[string]time.Time, error) {
+	log, err := r.run("log", "--first-parent", "-m", "--diff-filter=A", "--name-only", "--format=>%cI", "--", pathspec)
+	if err != nil {
+		return nil, err
+	}
+	added := map[string]time.Time{}
+	var commitTime time.Time
+	for _, line := range strings.Split(log, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, ">"):
+			if commitTime, err = time.Parse(time.RFC3339, line[1:]); err != nil {
+				return nil, fmt.Errorf("cannot parse commit time %q: %w", line[1:], err)
+			}
+		default:
+			// The log is ordered from the newest commit, so the oldest addition wins
+			added[line] = commitTime
+		}
+	}
+	return added, nil
+}
+
+// Modified returns the files matching the pathspec that were modified (not added or deleted) on the
+// checked out branch since the given time, following only the first parent like Added
+func (r Repository) Modified(pathspec string, since time.Time) ([]string, error) {
+	log, err := r.run("log", "--first-parent", "-m", "--diff-filter=M", "--name-only", "--format=", "--since="+since.Format(time.RFC3339), "--", pathspec)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var modified []string
+	for _, line := range strings.Split(log, "\n") {
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		modified = append(modified, line)
+	}
+	return modified, nil
+}
+
+// Commit commits the current content of the given paths (which may be new files) with the message
+func (r Repository) Commit(message string, paths ...string) error {
+	if _, err := r.run(append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+	_, err := r.run(append([]string{"commit", "-m", message, "--"}, paths...)...)
+	return err
+}
+
+// Push pushes the branch to the remote
+func (r Repository) Push(remote, branch string) error {
+	_, err := r.run("push", remote, branch)
+	return err
+}
+
+// RemoteURL returns the fetch URL of the remote
+func (r Repository) RemoteURL(remote string) (string, error) {
+	return r.run("remote", "get-url", remote)
+}
+
+// FilesAt returns the content of the files in the directory (not recursively) at the given ref, keyed
+// by their paths relative to the repository root. All contents are read with a single git process.
+func (r Repository) FilesAt(ref, dir string) (map[string][]byte, error) {
+	tree, err := r.run("ls-tree", "--full-tree", ref, "--", strings.TrimSuffix(dir, "/")+"/")
+	if err != nil {
+		return nil, err
+	}
+	var paths, objects []string
+	for _, line := range strings.Split(tree, "\n") {
+		// <mode> SP <type> SP <object> TAB <path>
+		meta, path, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		paths = append(paths, path)
+		objects = append(objects, fields[2])
+	}
+	files := map[string][]byte{}
+	if len(objects) == 0 {
+		return files, nil
+	}
+
+	cmd := exec.Command("git", "-C", r.Path, "cat-file", "--batch")
+	cmd.Stdin = strings.NewReader(strings.Join(objects, "\n") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file --batch failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	for _, path := range paths {
+		// <object> SP <type> SP <size> LF <content> LF
+		header, rest, ok := bytes.Cut(output, []byte("\n"))
+		fields := strings.Fields(string(header))
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git cat-file output for %s: %q", path, header)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil || size+1 > len(rest) {
+			return nil, fmt.Errorf("unexpected git cat-file output for %s: %q", path, header)
+		}
+		files[path] = rest[:size]
+		output = rest[size+1:]
+	}
+	return files, nil
+}
+
+// LastCommit returns the hash of the newest commit on the checked out branch that changed any of the
+// given paths, or an empty string when none did
+func (r Repository) LastCommit(paths ...string) (string, error) {
+	return r.run(append([]string{"log", "-1", "--format=%H", "--"}, paths...)...)
+}
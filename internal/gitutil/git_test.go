@@ -0,0 +1,213 @@
+package gitutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func newTestRepository(t *testing.T) Repository {
+	t.Helper()
+	dir := t.TempDir()
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "ota@example.com")
+	}
+	repository := Repository{Path: dir}
+	if _, err := repository.run("init", "--initial-branch=main"); err != nil {
+		t.Fatalf("cannot initialize repository: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "committed.yaml"), "committed\n")
+	if err := repository.Commit("Initial commit", "committed.yaml"); err != nil {
+		t.Fatalf("cannot commit: %v", err)
+	}
+	return repository
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("cannot write %s: %v", path, err)
+	}
+}
+
+func TestChangedAndDiff(t *testing.T) {
+	repository := newTestRepository(t)
+	root, err := repository.run("rev-parse", "--show-toplevel")
+	if err != nil {
+		t.Fatalf("cannot find repository root: %v", err)
+	}
+
+	changed, err := repository.Changed()
+	if err != nil {
+		t.Fatalf("Changed failed: %v", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("expected a clean repository, got changes in %v", changed)
+	}
+
+	writeFile(t, filepath.Join(repository.Path, "committed.yaml"), "modified\n")
+	writeFile(t, filepath.Join(repository.Path, "new.yaml"), "new\n")
+
+	changed, err = repository.Changed()
+	if err != nil {
+		t.Fatalf("Changed failed: %v", err)
+	}
+	expected := []string{filepath.Join(root, "committed.yaml"), filepath.Join(root, "new.yaml")}
+	if diff := cmp.Diff(expected, changed); diff != "" {
+		t.Errorf("unexpected changed files (-want +got):\n%s", diff)
+	}
+
+	diff, err := repository.Diff("committed.yaml", "new.yaml")
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	for _, line := range []string{"-committed", "+modified", "+new"} {
+		if !strings.Contains(diff, "\n"+line+"\n") && !strings.HasSuffix(diff, "\n"+line) {
+			t.Errorf("expected the diff to contain %q, got:\n%s", line, diff)
+		}
+	}
+}
+
+func TestSwitchBranch(t *testing.T) {
+	repository := newTestRepository(t)
+
+	for _, branch := range []string{"NodesFailToDrain", "main", "NodesFailToDrain"} {
+		if err := repository.SwitchBranch(branch); err != nil {
+			t.Fatalf("SwitchBranch(%s) failed: %v", branch, err)
+		}
+		current, err := repository.CurrentBranch()
+		if err != nil {
+			t.Fatalf("CurrentBranch failed: %v", err)
+		}
+		if current != branch {
+			t.Errorf("expected branch %s to be checked out, got %s", branch, current)
+		}
+	}
+}
+
+func TestAdded(t *testing.T) {
+	repository := newTestRepository(t)
+	if err := os.Mkdir(filepath.Join(repository.Path, "blocked-edges"), 0755); err != nil {
+		t.Fatalf("cannot create directory: %v", err)
+	}
+	for i, name := range []string{"4.16.1-Risk.yaml", "4.16.2-Risk.yaml"} {
+		t.Setenv("GIT_COMMITTER_DATE", fmt.Sprintf("2024-06-0%dT10:00:00Z", i+1))
+		writeFile(t, filepath.Join(repository.Path, "blocked-edges", name), "risk\n")
+		if err := repository.Commit("Add "+name, filepath.Join("blocked-edges", name)); err != nil {
+			t.Fatalf("cannot commit: %v", err)
+		}
+	}
+	// Modifying a file does not change when it was added
+	t.Setenv("GIT_COMMITTER_DATE", "2024-06-09T10:00:00Z")
+	writeFile(t, filepath.Join(repository.Path, "blocked-edges", "4.16.1-Risk.yaml"), "modified\n")
+	if err := repository.Commit("Modify", filepath.Join("blocked-edges", "4.16.1-Risk.yaml")); err != nil {
+		t.Fatalf("cannot commit: %v", err)
+	}
+
+	added, err := repository.Added("blocked-edges/*-Risk.yaml")
+	if err != nil {
+		t.Fatalf("Added failed: %v", err)
+	}
+	expected := map[string]time.Time{
+		"blocked-edges/4.16.1-Risk.yaml": time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC),
+		"blocked-edges/4.16.2-Risk.yaml": time.Date(2024, 6, 2, 10, 0, 0, 0, time.UTC),
+	}
+	if diff := cmp.Diff(expected, added, cmpopts.EquateApproxTime(0)); diff != "" {
+		t.Errorf("unexpected addition times (-want +got):\n%s", diff)
+	}
+}
+
+func TestModified(t *testing.T) {
+	repository := newTestRepository(t)
+	if err := os.Mkdir(filepath.Join(repository.Path, "blocked-edges"), 0755); err != nil {
+		t.Fatalf("cannot create directory: %v", err)
+	}
+	commit := func(date, name, content string) {
+		t.Helper()
+		t.Setenv("GIT_COMMITTER_DATE", date)
+		writeFile(t, filepath.Join(repository.Path, "blocked-edges", name), content)
+		if err := repository.Commit("Change "+name, filepath.Join("blocked-edges", name)); err != nil {
+			t.Fatalf("cannot commit: %v", err)
+		}
+	}
+	commit("2024-06-01T10:00:00Z", "4.16.1-Risk.yaml", "risk\n")
+	commit("2024-06-02T10:00:00Z", "4.16.2-Risk.yaml", "risk\n")
+	commit("2024-06-03T10:00:00Z", "4.16.1-Risk.yaml", "modified before\n")
+	commit("2024-06-09T10:00:00Z", "4.16.2-Risk.yaml", "modified\n")
+	commit("2024-06-10T10:00:00Z", "4.16.3-Risk.yaml", "added\n")
+	commit("2024-06-11T10:00:00Z", "4.16.2-Risk.yaml", "modified again\n")
+
+	modified, err := repository.Modified("blocked-edges/*-Risk.yaml", time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Modified failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"blocked-edges/4.16.2-Risk.yaml"}, modified); diff != "" {
+		t.Errorf("unexpected modified files (-want +got):\n%s", diff)
+	}
+}
+
+func TestFilesAt(t *testing.T) {
+	repository := newTestRepository(t)
+	if err := os.Mkdir(filepath.Join(repository.Path, "blocked-edges"), 0755); err != nil {
+		t.Fatalf("cannot create directory: %v", err)
+	}
+	writeFile(t, filepath.Join(repository.Path, "blocked-edges", "4.16.1-Risk.yaml"), "first\n")
+	writeFile(t, filepath.Join(repository.Path, "blocked-edges", "4.16.2-Risk.yaml"), "")
+	if err := repository.Commit("Add edges", "blocked-edges"); err != nil {
+		t.Fatalf("cannot commit: %v", err)
+	}
+	// Uncommitted changes are not visible at the ref
+	writeFile(t, filepath.Join(repository.Path, "blocked-edges", "4.16.1-Risk.yaml"), "modified\n")
+	writeFile(t, filepath.Join(repository.Path, "blocked-edges", "4.16.3-Risk.yaml"), "untracked\n")
+
+	files, err := repository.FilesAt("HEAD", "blocked-edges")
+	if err != nil {
+		t.Fatalf("FilesAt failed: %v", err)
+	}
+	expected := map[string]string{
+		"blocked-edges/4.16.1-Risk.yaml": "first\n",
+		"blocked-edges/4.16.2-Risk.yaml": "",
+	}
+	got := map[string]string{}
+	for path, content := range files {
+		got[path] = string(content)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected files (-want +got):\n%s", diff)
+	}
+
+	if _, err := repository.FilesAt("no-such-ref", "blocked-edges"); err == nil {
+		t.Errorf("expected an error for a missing ref")
+	}
+}
+
+func TestLastCommit(t *testing.T) {
+	repository := newTestRepository(t)
+	initial, err := repository.run("rev-parse", "HEAD")
+	if err != nil {
+		t.Fatalf("cannot resolve HEAD: %v", err)
+	}
+	writeFile(t, filepath.Join(repository.Path, "other.yaml"), "other\n")
+	if err := repository.Commit("Add other", "other.yaml"); err != nil {
+		t.Fatalf("cannot commit: %v", err)
+	}
+
+	last, err := repository.LastCommit(filepath.Join(repository.Path, "committed.yaml"))
+	if err != nil {
+		t.Fatalf("LastCommit failed: %v", err)
+	}
+	if last != initial {
+		t.Errorf("expected commit %s, got %s", initial, last)
+	}
+
+	writeFile(t, filepath.Join(repository.Path, "untracked.yaml"), "untracked\n")
+	if last, err := repository.LastCommit("untracked.yaml"); err != nil || last != "" {
+		t.Errorf("expected no commit for an untracked file, got %q (error %v)", last, err)
+	}
+}
@@ -0,0 +1,175 @@
+package graph
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/petr-muller/ota/internal/gitutil"
+)
+
+const (
+	RiskAdded   = "added"
+	RiskRemoved = "removed"
+	RiskEdited  = "edited"
+)
+
+// FieldChange is a changed field of the blocked edges of a risk to a single version
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// EdgeChange holds the changed fields of the blocked edges of a risk to a single version
+type EdgeChange struct {
+	To     string        `json:"to"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// RiskChange describes how the blocked edges of a risk differ between two sets of blocked edges
+type RiskChange struct {
+	Name   string `json:"name"`
+	Change string `json:"change"`
+	// Added and Removed hold the versions whose edges were added or removed
+	Added   []string     `json:"added,omitempty"`
+	Removed []string     `json:"removed,omitempty"`
+	Edited  []EdgeChange `json:"edited,omitempty"`
+}
+
+// target aggregates the blocked edges of a risk to a single version. Graph repositories have a single
+// edge with a from regular expression for each version; graphs served by OSUS have an edge for every
+// exact source version.
+type target struct {
+	from          sets.Set[string]
+	url           string
+	message       string
+	matchingRules string
+	fixedIn       string
+}
+
+func (t target) fields() map[string]string {
+	return map[string]string{
+		"from":          strings.Join(sets.List(t.from), ", "),
+		"url":           t.url,
+		"message":       t.message,
+		"matchingRules": t.matchingRules,
+		"fixedIn":       t.fixedIn,
+	}
+}
+
+// fieldOrder is the order of the compared fields, matching the blocked edge files
+var fieldOrder = []string{"from", "fixedIn", "url", "message", "matchingRules"}
+
+// targets groups the edges by risk name and target version
+func targets(edges []ConditionallyBlockedEdge) map[string]map[string]*target {
+	byRisk := map[string]map[string]*target{}
+	for _, edge := range edges {
+		if byRisk[edge.Name] == nil {
+			byRisk[edge.Name] = map[string]*target{}
+		}
+		t, ok := byRisk[edge.Name][edge.To]
+		if !ok {
+			var rules []string
+			for _, rule := range edge.MatchingRules {
+				rules = append(rules, strings.TrimSpace(rule.Type+" "+rule.PromQL.Query))
+			}
+			t = &target{from: sets.New[string](), url: edge.URL, message: edge.Message, matchingRules: strings.Join(rules, "\n"), fixedIn: edge.FixedIn}
+			byRisk[edge.Name][edge.To] = t
+		}
+		t.from.Insert(edge.From)
+	}
+	return byRisk
+}
+
+// Diff compares two sets of blocked edges and returns the risks that were added, removed or whose
+// blocked edges changed, sorted by name
+func Diff(old, new []ConditionallyBlockedEdge) []RiskChange {
+	oldRisks, newRisks := targets(old), targets(new)
+	names := sets.KeySet(oldRisks).Union(sets.KeySet(newRisks))
+
+	var changes []RiskChange
+	for _, name := range sets.List(names) {
+		oldTargets, newTargets := oldRisks[name], newRisks[name]
+		change := RiskChange{Name: name}
+		switch {
+		case oldTargets == nil:
+			change.Change = RiskAdded
+		case newTargets == nil:
+			change.Change = RiskRemoved
+		default:
+			change.Change = RiskEdited
+		}
+
+		versions := sets.KeySet(oldTargets).Union(sets.KeySet(newTargets))
+		for _, to := range sortedVersions(versions) {
+			oldTarget, inOld := oldTargets[to]
+			newTarget, inNew := newTargets[to]
+			switch {
+			case !inOld:
+				change.Added = append(change.Added, to)
+			case !inNew:
+				change.Removed = append(change.Removed, to)
+			default:
+				oldFields, newFields := oldTarget.fields(), newTarget.fields()
+				edited := EdgeChange{To: to}
+				for _, field := range fieldOrder {
+					if oldFields[field] != newFields[field] {
+						edited.Fields = append(edited.Fields, FieldChange{Field: field, Old: oldFields[field], New: newFields[field]})
+					}
+				}
+				if len(edited.Fields) > 0 {
+					change.Edited = append(change.Edited, edited)
+				}
+			}
+		}
+		if change.Change == RiskEdited && len(change.Added) == 0 && len(change.Removed) == 0 && len(change.Edited) == 0 {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// sortedVersions sorts the versions semantically, so that 4.16.10 follows 4.16.9
+func sortedVersions(versions sets.Set[string]) []string {
+	sorted := sets.List(versions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, aErr := version.ParseSemantic(sorted[i])
+		b, bErr := version.ParseSemantic(sorted[j])
+		if aErr != nil || bErr != nil {
+			return sorted[i] < sorted[j]
+		}
+		return a.LessThan(b)
+	})
+	return sorted
+}
+
+// EdgesAt reads the blocked edges committed in the graph repository at the given git ref
+func EdgesAt(repositoryPath, ref string) ([]ConditionallyBlockedEdge, error) {
+	files, err := gitutil.Repository{Path: repositoryPath}.FilesAt(ref, blockedEdgesDirName)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		if path.Ext(p) == edgeFileExtension {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var edges []ConditionallyBlockedEdge
+	for _, p := range paths {
+		edge, err := UnmarshalEdge(files[p])
+		if err != nil {
+			return nil, fmt.Errorf("cannot unmarshal %s at %s: %w", p, ref, err)
+		}
+		edges = append(edges, edge)
+	}
+	return edges, nil
+}
@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiff(t *testing.T) {
+	edge := func(to, from, name string) ConditionallyBlockedEdge {
+		return ConditionallyBlockedEdge{
+			To:            to,
+			From:          from,
+			URL:           "https://issues.redhat.com/browse/OCPBUGS-1",
+			Name:          name,
+			Message:       "Nodes may fail to drain.",
+			MatchingRules: []PromQLRule{{Type: "Always"}},
+		}
+	}
+	edited := edge("4.16.2", `4\.15\..*`, "Edited")
+	edited.Message = "Nodes may fail to drain on AWS."
+	edited.FixedIn = "4.16.3"
+
+	old := []ConditionallyBlockedEdge{
+		edge("4.16.1", `4\.15\..*`, "Removed"),
+		edge("4.16.1", `4\.15\..*`, "Unchanged"),
+		edge("4.16.1", `4\.15\..*`, "Edited"),
+		edge("4.16.2", `4\.15\..*`, "Edited"),
+		edge("4.16.9", `4\.15\..*`, "Edited"),
+	}
+	new := []ConditionallyBlockedEdge{
+		edge("4.16.1", `4\.15\..*`, "Unchanged"),
+		edge("4.16.1", `4\.15\..*`, "Edited"),
+		edited,
+		edge("4.16.10", `4\.15\..*`, "Edited"),
+		edge("4.16.1", "4.15.1", "Added"),
+		edge("4.16.1", "4.15.2", "Added"),
+	}
+
+	expected := []RiskChange{
+		{Name: "Added", Change: RiskAdded, Added: []string{"4.16.1"}},
+		{
+			Name:    "Edited",
+			Change:  RiskEdited,
+			Added:   []string{"4.16.10"},
+			Removed: []string{"4.16.9"},
+			Edited: []EdgeChange{{To: "4.16.2", Fields: []FieldChange{
+				{Field: "fixedIn", Old: "", New: "4.16.3"},
+				{Field: "message", Old: "Nodes may fail to drain.", New: "Nodes may fail to drain on AWS."},
+			}}},
+		},
+		{Name: "Removed", Change: RiskRemoved, Removed: []string{"4.16.1"}},
+	}
+	if diff := cmp.Diff(expected, Diff(old, new)); diff != "" {
+		t.Errorf("unexpected changes (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiffServedSources(t *testing.T) {
+	served := func(from string) ConditionallyBlockedEdge {
+		return ConditionallyBlockedEdge{To: "4.16.1", From: from, Name: "Risk", MatchingRules: []PromQLRule{{Type: "PromQL", PromQL: PromQLQuery{Query: "group(up)"}}}}
+	}
+	old := []ConditionallyBlockedEdge{served("4.15.1"), served("4.15.2")}
+	new := []ConditionallyBlockedEdge{served("4.15.2"), served("4.15.1"), served("4.15.3")}
+
+	expected := []RiskChange{{
+		Name:   "Risk",
+		Change: RiskEdited,
+		Edited: []EdgeChange{{To: "4.16.1", Fields: []FieldChange{{Field: "from", Old: "4.15.1, 4.15.2", New: "4.15.1, 4.15.2, 4.15.3"}}}},
+	}}
+	if diff := cmp.Diff(expected, Diff(old, new)); diff != "" {
+		t.Errorf("unexpected changes (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,140 @@
+// Package graph implements reading and writing the blocked edges in a Cincinnati graph repository
+// (github.com/openshift/cincinnati-graph-data)
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// blockedEdgesDirName is the directory in the graph repository that holds the conditionally blocked edges
+	blockedEdgesDirName = "blocked-edges"
+	edgeFileExtension   = ".yaml"
+)
+
+type PromQLQuery struct {
+	Query string `yaml:"promql"`
+}
+
+type PromQLRule struct {
+	Type   string      `yaml:"type"`
+	PromQL PromQLQuery `yaml:"promql,omitempty"`
+}
+
+// ConditionallyBlockedEdge is a single blocked edge file. The field order matches the order used
+// in the graph repository.
+type ConditionallyBlockedEdge struct {
+	To            string       `yaml:"to"`
+	From          string       `yaml:"from"`
+	FixedIn       string       `yaml:"fixedIn,omitempty"`
+	URL           string       `yaml:"url"`
+	Name          string       `yaml:"name"`
+	Message       string       `yaml:"message"`
+	MatchingRules []PromQLRule `yaml:"matchingRules"`
+}
+
+// EdgesDirectory returns the path to the blocked edges directory in the graph repository
+func EdgesDirectory(repositoryPath string) string {
+	return filepath.Join(repositoryPath, blockedEdgesDirName)
+}
+
+// EdgeFileName returns the conventional name of the file blocking edges to version because of risk
+func EdgeFileName(version, risk string) string {
+	return fmt.Sprintf("%s-%s%s", version, risk, edgeFileExtension)
+}
+
+// EdgePath returns the path to the file blocking edges to version because of risk
+func EdgePath(repositoryPath, version, risk string) string {
+	return filepath.Join(EdgesDirectory(repositoryPath), EdgeFileName(version, risk))
+}
+
+// ParseEdgeFileName splits a conventional blocked edge file name into the version and the risk name.
+// Risk names never contain dashes while versions may (e.g. 4.16.0-rc.1), so the name is split on the
+// last dash.
+func ParseEdgeFileName(name string) (version, risk string, err error) {
+	base := filepath.Base(name)
+	if !strings.HasSuffix(base, edgeFileExtension) {
+		return "", "", fmt.Errorf("%s: blocked edge file must have %s extension", base, edgeFileExtension)
+	}
+	base = strings.TrimSuffix(base, edgeFileExtension)
+	idx := strings.LastIndex(base, "-")
+	if idx <= 0 || idx == len(base)-1 {
+		return "", "", fmt.Errorf("%s: blocked edge file name must be VERSION-RISK%s", name, edgeFileExtension)
+	}
+	return base[:idx], base[idx+1:], nil
+}
+
+// CheckFileName returns a problem when the file name does not follow the VERSION-RISK.yaml convention
+// for the version and risk of the edge it holds, or an empty string
+func CheckFileName(path string, edge ConditionallyBlockedEdge) string {
+	version, risk, err := ParseEdgeFileName(path)
+	if err != nil {
+		return err.Error()
+	}
+	if version != edge.To || risk != edge.Name {
+		return fmt.Sprintf("file name does not match the edge, expected %s", EdgeFileName(edge.To, edge.Name))
+	}
+	return ""
+}
+
+// UnmarshalEdge parses a blocked edge from its YAML representation
+func UnmarshalEdge(raw []byte) (ConditionallyBlockedEdge, error) {
+	var edge ConditionallyBlockedEdge
+	if err := yaml.Unmarshal(raw, &edge); err != nil {
+		return ConditionallyBlockedEdge{}, err
+	}
+	return edge, nil
+}
+
+// MarshalEdge serializes the blocked edge the same way the graph repository formats them: two-space
+// indent, keys in the ConditionallyBlockedEdge field order, fixedIn and promql omitted when empty
+func MarshalEdge(edge ConditionallyBlockedEdge) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(edge); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// LoadEdge reads the blocked edge from the file at path
+func LoadEdge(path string) (ConditionallyBlockedEdge, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return ConditionallyBlockedEdge{}, err
+	}
+	edge, err := UnmarshalEdge(raw)
+	if err != nil {
+		return ConditionallyBlockedEdge{}, fmt.Errorf("cannot unmarshal %s: %w", path, err)
+	}
+	return edge, nil
+}
+
+// SaveEdge writes the blocked edge into the file at path
+func SaveEdge(path string, edge ConditionallyBlockedEdge) error {
+	raw, err := MarshalEdge(edge)
+	if err != nil {
+		return fmt.Errorf("cannot marshal blocked edge: %w", err)
+	}
+	return os.WriteFile(path, raw, 0644)
+}
+
+// Filter selects blocked edges when walking the graph repository. Empty fields match all edges.
+type Filter struct {
+	Risk    string
+	Version string
+}
+
+func (f Filter) matches(edge ConditionallyBlockedEdge) bool {
+	return (f.Risk == "" || f.Risk == edge.Name) && (f.Version == "" || f.Version == edge.To)
+}
@@ -0,0 +1,175 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// graphRepositoryEdge is formatted like the files written by the graph tools: two-space indent, keys in
+// the order used by the graph repository, block scalars for multi-line strings
+const graphRepositoryEdge = `to: 4.16.1
+from: 4\.15\..*
+url: https://issues.redhat.com/browse/OCPBUGS-1
+name: NodesFailToDrain
+message: |-
+  Nodes may fail to drain on AWS clusters.
+  More text.
+matchingRules:
+  - type: PromQL
+    promql:
+      promql: |
+        group(cluster_infrastructure_provider{type="AWS"})
+        or
+        0 * group(cluster_infrastructure_provider)
+`
+
+func TestMarshalEdge(t *testing.T) {
+	testCases := []struct {
+		name     string
+		edge     ConditionallyBlockedEdge
+		expected string
+	}{
+		{
+			name: "edge without fixedIn",
+			edge: ConditionallyBlockedEdge{
+				To:      "4.16.1",
+				From:    `4\.15\..*`,
+				URL:     "https://issues.redhat.com/browse/OCPBUGS-1",
+				Name:    "NodesFailToDrain",
+				Message: "Nodes may fail to drain on AWS clusters.\nMore text.",
+				MatchingRules: []PromQLRule{{
+					Type:   "PromQL",
+					PromQL: PromQLQuery{Query: "group(cluster_infrastructure_provider{type=\"AWS\"})\nor\n0 * group(cluster_infrastructure_provider)\n"},
+				}},
+			},
+			expected: graphRepositoryEdge,
+		},
+		{
+			name: "fixedIn follows from, Always rule without promql",
+			edge: ConditionallyBlockedEdge{
+				To:            "4.16.1",
+				From:          `4\.15\..*`,
+				FixedIn:       "4.16.2",
+				URL:           "https://issues.redhat.com/browse/OCPBUGS-1",
+				Name:          "NodesFailToDrain",
+				Message:       "Nodes may fail to drain.",
+				MatchingRules: []PromQLRule{{Type: "Always"}},
+			},
+			expected: `to: 4.16.1
+from: 4\.15\..*
+fixedIn: 4.16.2
+url: https://issues.redhat.com/browse/OCPBUGS-1
+name: NodesFailToDrain
+message: Nodes may fail to drain.
+matchingRules:
+  - type: Always
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := MarshalEdge(tc.edge)
+			if err != nil {
+				t.Fatalf("MarshalEdge failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, string(raw)); diff != "" {
+				t.Errorf("unexpected serialization (-want +got):\n%s", diff)
+			}
+
+			edge, err := UnmarshalEdge(raw)
+			if err != nil {
+				t.Fatalf("UnmarshalEdge failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.edge, edge); diff != "" {
+				t.Errorf("edge changed in a round trip (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMarshalEdgeRoundTrip(t *testing.T) {
+	edge, err := UnmarshalEdge([]byte(graphRepositoryEdge))
+	if err != nil {
+		t.Fatalf("UnmarshalEdge failed: %v", err)
+	}
+	raw, err := MarshalEdge(edge)
+	if err != nil {
+		t.Fatalf("MarshalEdge failed: %v", err)
+	}
+	if diff := cmp.Diff(graphRepositoryEdge, string(raw)); diff != "" {
+		t.Errorf("file changed in a round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseEdgeFileName(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		expectedVersion string
+		expectedRisk    string
+		expectedError   bool
+	}{
+		{
+			name:            "4.16.1-NodesFailToDrain.yaml",
+			expectedVersion: "4.16.1",
+			expectedRisk:    "NodesFailToDrain",
+		},
+		{
+			name:            "blocked-edges/4.16.0-rc.1-NodesFailToDrain.yaml",
+			expectedVersion: "4.16.0-rc.1",
+			expectedRisk:    "NodesFailToDrain",
+		},
+		{
+			name:          "4.16.1-NodesFailToDrain.yml",
+			expectedError: true,
+		},
+		{
+			name:          "NodesFailToDrain.yaml",
+			expectedError: true,
+		},
+		{
+			name:          "4.16.1-.yaml",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			version, risk, err := ParseEdgeFileName(tc.name)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %t, got %v", tc.expectedError, err)
+			}
+			if version != tc.expectedVersion || risk != tc.expectedRisk {
+				t.Errorf("expected (%q, %q), got (%q, %q)", tc.expectedVersion, tc.expectedRisk, version, risk)
+			}
+		})
+	}
+
+	if version, risk, _ := ParseEdgeFileName(EdgeFileName("4.16.1", "NodesFailToDrain")); version != "4.16.1" || risk != "NodesFailToDrain" {
+		t.Errorf("ParseEdgeFileName does not invert EdgeFileName, got (%q, %q)", version, risk)
+	}
+}
+
+func TestCheckFileName(t *testing.T) {
+	edge := ConditionallyBlockedEdge{To: "4.16.1", Name: "NodesFailToDrain"}
+	testCases := []struct {
+		path string
+
+		expected string
+	}{
+		{path: "blocked-edges/4.16.1-NodesFailToDrain.yaml"},
+		{path: "blocked-edges/4.16.2-NodesFailToDrain.yaml", expected: "file name does not match the edge, expected 4.16.1-NodesFailToDrain.yaml"},
+		{path: "blocked-edges/4.16.1-NodesFailToBoot.yaml", expected: "file name does not match the edge, expected 4.16.1-NodesFailToDrain.yaml"},
+		{path: "blocked-edges/4.16.1-NodesFailToDrain.yml", expected: "4.16.1-NodesFailToDrain.yml: blocked edge file must have .yaml extension"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			if problem := CheckFileName(tc.path, edge); problem != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/petr-muller/ota/internal/editor"
+)
+
+// ErrEditAborted is returned by EditNameAndMessage when the user emptied the edited file
+var ErrEditAborted = errors.New("aborted by user")
+
+// EditableRisk holds the fields of a risk that users write in their editor
+type EditableRisk struct {
+	Name    string `yaml:"name"`
+	Message string `yaml:"message"`
+}
+
+// Validate returns an error when the name is not a valid risk name or the message is not fit to be
+// served: the errors of CheckMessage, which graph lint also fails on
+func (r EditableRisk) Validate() error {
+	if !ValidRiskName(r.Name) {
+		return fmt.Errorf("name %q must be a CamelCase identifier", r.Name)
+	}
+	if errs := MessageErrors(CheckMessage(r.Message)); len(errs) > 0 {
+		return errors.New(errs[0].String())
+	}
+	return nil
+}
+
+// EditNameAndMessage opens the risk name and message in the user's editor and returns the edited values.
+// The header lines are shown as comments above them. The editor is reopened with the validation error
+// until the content is valid; ErrEditAborted is returned when the user empties the file.
+func EditNameAndMessage(name, message string, header ...string) (string, string, error) {
+	comments := "# Edit the risk name and message. Empty the file to abort.\n"
+	for _, line := range header {
+		comments += "# " + line + "\n"
+	}
+
+	content, err := yaml.Marshal(EditableRisk{Name: name, Message: message})
+	if err != nil {
+		return "", "", fmt.Errorf("cannot marshal risk name and message: %w", err)
+	}
+	content = append([]byte(comments), content...)
+
+	for {
+		edited, err := editor.Edit(content, "ota-risk-*.yaml")
+		if err != nil {
+			return "", "", err
+		}
+
+		var result EditableRisk
+		if err := yaml.Unmarshal(edited, &result); err != nil {
+			return "", "", fmt.Errorf("cannot unmarshal edited risk name and message: %w", err)
+		}
+		if result == (EditableRisk{}) {
+			return "", "", ErrEditAborted
+		}
+		result.Message = strings.TrimSpace(result.Message)
+
+		if err := result.Validate(); err != nil {
+			logrus.WithError(err).Warn("Edited risk name and message are not valid, reopening the editor")
+			content = append([]byte(fmt.Sprintf("# ERROR: %s\n", err)), edited...)
+			continue
+		}
+		return result.Name, result.Message, nil
+	}
+}
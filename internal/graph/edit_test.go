@@ -0,0 +1,130 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditableRiskValidate(t *testing.T) {
+	testCases := []struct {
+		name string
+		risk EditableRisk
+
+		expectedError bool
+	}{
+		{
+			name: "valid",
+			risk: EditableRisk{Name: "NodesFailToDrain", Message: "Nodes on AWS may fail to drain."},
+		},
+		{
+			name:          "name is not CamelCase",
+			risk:          EditableRisk{Name: "nodes-fail-to-drain", Message: "Nodes on AWS may fail to drain."},
+			expectedError: true,
+		},
+		{
+			name:          "empty message",
+			risk:          EditableRisk{Name: "NodesFailToDrain"},
+			expectedError: true,
+		},
+		{
+			name:          "multi-line message",
+			risk:          EditableRisk{Name: "NodesFailToDrain", Message: "Nodes on AWS may fail to drain.\nMore text."},
+			expectedError: true,
+		},
+		{
+			name:          "message too long",
+			risk:          EditableRisk{Name: "NodesFailToDrain", Message: strings.Repeat("a", MaxMessageLength+1)},
+			expectedError: true,
+		},
+		{
+			name:          "message with Jira markup",
+			risk:          EditableRisk{Name: "NodesFailToDrain", Message: "Nodes on AWS may fail to drain, see {{oc adm drain}}."},
+			expectedError: true,
+		},
+		{
+			name: "message with style warnings only",
+			risk: EditableRisk{Name: "NodesFailToDrain", Message: "nodes fail to drain"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.risk.Validate(); (err != nil) != tc.expectedError {
+				t.Errorf("expected error: %t, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+// fakeEditor makes EditNameAndMessage use an editor that replaces the edited file with the given
+// contents, one for each time the editor is opened, and records what it was opened with
+func fakeEditor(t *testing.T, contents ...string) (opened func(i int) string) {
+	t.Helper()
+	dir := t.TempDir()
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\nn=$(ls " + dir + " | grep -c '^opened-')\ncp \"$1\" " + dir + "/opened-$n\n")
+	for i, content := range contents {
+		path := filepath.Join(dir, fmt.Sprintf("content-%d", i))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fmt.Fprintf(&script, "[ $n = %d ] && cp %s \"$1\"\n", i, path)
+	}
+	script.WriteString("exit 0\n")
+	editorPath := filepath.Join(dir, "editor.sh")
+	if err := os.WriteFile(editorPath, []byte(script.String()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", editorPath)
+	return func(i int) string {
+		raw, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("opened-%d", i)))
+		if err != nil {
+			t.Fatalf("editor was not opened %d times: %v", i+1, err)
+		}
+		return string(raw)
+	}
+}
+
+func TestEditNameAndMessage(t *testing.T) {
+	t.Run("edited", func(t *testing.T) {
+		opened := fakeEditor(t, "name: NodesFailToDrain\nmessage: |\n  Nodes on AWS may fail to drain.\n")
+		name, message, err := EditNameAndMessage("OldName", "Old message.", "Impact statement: OCPBUGS-1")
+		if err != nil {
+			t.Fatalf("EditNameAndMessage failed: %v", err)
+		}
+		if name != "NodesFailToDrain" || message != "Nodes on AWS may fail to drain." {
+			t.Errorf("unexpected name %q and message %q", name, message)
+		}
+		if first := opened(0); !strings.Contains(first, "# Impact statement: OCPBUGS-1\n") || !strings.Contains(first, "name: OldName\n") {
+			t.Errorf("editor was not opened with the header and the current values:\n%s", first)
+		}
+	})
+
+	t.Run("invalid content reopens the editor with the error", func(t *testing.T) {
+		opened := fakeEditor(t,
+			"name: nodes-fail\nmessage: Nodes may fail to drain.\n",
+			"name: NodesFailToDrain\nmessage: Nodes may fail to drain.\n",
+		)
+		name, _, err := EditNameAndMessage("OldName", "Old message.")
+		if err != nil {
+			t.Fatalf("EditNameAndMessage failed: %v", err)
+		}
+		if name != "NodesFailToDrain" {
+			t.Errorf("expected the name from the second edit, got %q", name)
+		}
+		if second := opened(1); !strings.HasPrefix(second, "# ERROR: name \"nodes-fail\" must be a CamelCase identifier\n") {
+			t.Errorf("editor was not reopened with the error:\n%s", second)
+		}
+	})
+
+	t.Run("emptied file aborts", func(t *testing.T) {
+		fakeEditor(t, "")
+		if _, _, err := EditNameAndMessage("OldName", "Old message."); !errors.Is(err, ErrEditAborted) {
+			t.Errorf("expected ErrEditAborted, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,292 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/petr-muller/ota/internal/config"
+)
+
+// Index holds all blocked edges of a graph repository, read once so that repeated lookups do not
+// re-read and re-unmarshal every file
+type Index struct {
+	// edges maps the paths of the blocked edge files to their content
+	edges map[string]ConditionallyBlockedEdge
+	paths []string
+
+	byRisk map[string][]string
+	byURL  map[string][]string
+}
+
+// maxCachedIndexes is the number of cached indexes kept in the ota data directory; older ones are
+// removed when a new index is cached
+const maxCachedIndexes = 5
+
+// EdgeErrors holds the errors of the blocked edge files that cannot be read, keyed by their paths
+type EdgeErrors map[string]error
+
+func (e EdgeErrors) Error() string {
+	paths := make([]string, 0, len(e))
+	for path := range e {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if len(paths) == 1 {
+		return e[paths[0]].Error()
+	}
+	return fmt.Sprintf("%d blocked edge files cannot be read, first: %v", len(paths), e[paths[0]])
+}
+
+// LoadIndex reads all blocked edges in the graph repository, parsing the files in parallel. When the
+// blocked edges directory has no uncommitted changes, the index is cached in the ota data directory,
+// keyed by the git HEAD of the repository. Commands should load the index once and pass it around.
+//
+// When some files cannot be read, LoadIndex returns EdgeErrors together with the index of the edges
+// that were read.
+func LoadIndex(repositoryPath string) (*Index, error) {
+	cachePath := indexCachePath(repositoryPath)
+	if cachePath != "" {
+		if index, err := loadCachedIndex(repositoryPath, cachePath); err == nil {
+			logrus.Debugf("Using cached blocked edges index %s", cachePath)
+			return index, nil
+		}
+	}
+
+	edges, err := readEdges(repositoryPath)
+	if err != nil {
+		var edgeErrors EdgeErrors
+		if errors.As(err, &edgeErrors) {
+			return newIndex(edges), err
+		}
+		return nil, err
+	}
+
+	if cachePath != "" {
+		if err := saveCachedIndex(repositoryPath, cachePath, edges); err != nil {
+			logrus.WithError(err).Warn("Cannot cache blocked edges index")
+		}
+		pruneCachedIndexes(filepath.Dir(cachePath))
+	}
+	return newIndex(edges), nil
+}
+
+func newIndex(edges map[string]ConditionallyBlockedEdge) *Index {
+	index := &Index{
+		edges:  edges,
+		byRisk: map[string][]string{},
+		byURL:  map[string][]string{},
+	}
+	for path, edge := range edges {
+		index.paths = append(index.paths, path)
+		index.byRisk[edge.Name] = append(index.byRisk[edge.Name], path)
+		index.byURL[edge.URL] = append(index.byURL[edge.URL], path)
+	}
+	sort.Strings(index.paths)
+	for _, paths := range index.byRisk {
+		sort.Strings(paths)
+	}
+	for _, paths := range index.byURL {
+		sort.Strings(paths)
+	}
+	return index
+}
+
+// readEdges reads and parses all blocked edge files with one worker per CPU. The files that cannot be
+// read are returned as EdgeErrors, together with all edges that were read.
+func readEdges(repositoryPath string) (map[string]ConditionallyBlockedEdge, error) {
+	edgesDirectory := EdgesDirectory(repositoryPath)
+	entries, err := os.ReadDir(edgesDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failure when walking items in graph repository directory %s: %w", edgesDirectory, err)
+	}
+
+	paths := make(chan string)
+	var lock sync.Mutex
+	edgeErrors := EdgeErrors{}
+	edges := map[string]ConditionallyBlockedEdge{}
+
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				edge, err := LoadEdge(path)
+				lock.Lock()
+				if err != nil {
+					edgeErrors[path] = err
+				} else {
+					edges[path] = edge
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			logrus.Tracef("Skipping (unexpected) directory %s", entry.Name())
+			continue
+		}
+		paths <- filepath.Join(edgesDirectory, entry.Name())
+	}
+	close(paths)
+	wg.Wait()
+
+	if len(edgeErrors) > 0 {
+		return edges, edgeErrors
+	}
+	return edges, nil
+}
+
+// indexCachePath returns the path of the cached index for the current git HEAD of the repository, or
+// an empty string when the index must not be cached (not a git repository, uncommitted changes)
+func indexCachePath(repositoryPath string) string {
+	head, err := exec.Command("git", "-C", repositoryPath, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	status, err := exec.Command("git", "-C", repositoryPath, "status", "--porcelain", "--", blockedEdgesDirName).Output()
+	if err != nil || len(status) > 0 {
+		return ""
+	}
+	dataDir, err := config.OtaDataDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dataDir, "graph-index", strings.TrimSpace(string(head))+".json")
+}
+
+// cachedIndex is the on-disk form of the index, with paths relative to the graph repository
+type cachedIndex map[string]ConditionallyBlockedEdge
+
+func loadCachedIndex(repositoryPath, cachePath string) (*Index, error) {
+	raw, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	var cached cachedIndex
+	if err := json.Unmarshal(raw, &cached); err != nil {
+		return nil, err
+	}
+	edges := map[string]ConditionallyBlockedEdge{}
+	for relative, edge := range cached {
+		edges[filepath.Join(repositoryPath, relative)] = edge
+	}
+	return newIndex(edges), nil
+}
+
+func saveCachedIndex(repositoryPath, cachePath string, edges map[string]ConditionallyBlockedEdge) error {
+	cached := cachedIndex{}
+	for path, edge := range edges {
+		relative, err := filepath.Rel(repositoryPath, path)
+		if err != nil {
+			return err
+		}
+		cached[relative] = edge
+	}
+	raw, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(cachePath, raw, 0644)
+}
+
+// pruneCachedIndexes removes all but the maxCachedIndexes most recently written indexes from the cache
+func pruneCachedIndexes(cacheDir string) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+	type cached struct {
+		path     string
+		modified time.Time
+	}
+	var indexes []cached
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		indexes = append(indexes, cached{path: filepath.Join(cacheDir, entry.Name()), modified: info.ModTime()})
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].modified.After(indexes[j].modified) })
+	for i := maxCachedIndexes; i < len(indexes); i++ {
+		if err := os.Remove(indexes[i].path); err != nil {
+			logrus.WithError(err).Debugf("Cannot remove cached blocked edges index %s", indexes[i].path)
+		}
+	}
+}
+
+// Walk calls fn for every blocked edge in the index that matches the filter, in the order of their paths.
+// Walking stops at the first error, which is returned.
+func (i *Index) Walk(filter Filter, fn func(path string, edge ConditionallyBlockedEdge) error) error {
+	paths := i.paths
+	if filter.Risk != "" {
+		paths = i.byRisk[filter.Risk]
+	}
+	for _, path := range paths {
+		edge := i.edges[path]
+		if !filter.matches(edge) {
+			continue
+		}
+		if err := fn(path, edge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ByURL returns all blocked edges that reference the given URL
+func (i *Index) ByURL(url string) []ConditionallyBlockedEdge {
+	var edges []ConditionallyBlockedEdge
+	for _, path := range i.byURL[url] {
+		edges = append(edges, i.edges[path])
+	}
+	return edges
+}
+
+// Paths returns the paths of all blocked edges in the index that match the filter
+func (i *Index) Paths(filter Filter) []string {
+	var paths []string
+	_ = i.Walk(filter, func(path string, _ ConditionallyBlockedEdge) error {
+		paths = append(paths, path)
+		return nil
+	})
+	return paths
+}
+
+// Spread copies the message, URL and matchingRules of the source edge into the blocked edges at the
+// given paths and writes them. Spreading stops at the first file that cannot be written.
+func (i *Index) Spread(source ConditionallyBlockedEdge, paths []string) error {
+	for _, path := range paths {
+		target, ok := i.edges[path]
+		if !ok {
+			return fmt.Errorf("%s: not a blocked edge in the index", path)
+		}
+		target.Message = source.Message
+		target.URL = source.URL
+		target.MatchingRules = source.MatchingRules
+		// TODO(muller): Handle `from` field, will be likely identical within minor
+
+		if err := SaveEdge(path, target); err != nil {
+			return fmt.Errorf("cannot write updated edge into target file %s: %w", path, err)
+		}
+		i.edges[path] = target
+	}
+	return nil
+}
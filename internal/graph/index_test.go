@@ -0,0 +1,111 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadIndexReportsUnreadableFiles(t *testing.T) {
+	repository := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	if err := os.MkdirAll(EdgesDirectory(repository), 0755); err != nil {
+		t.Fatal(err)
+	}
+	edge := ConditionallyBlockedEdge{To: "4.16.1", From: ".*", Name: "Risk", URL: "https://example.com", MatchingRules: []PromQLRule{{Type: "Always"}}}
+	if err := SaveEdge(EdgePath(repository, "4.16.1", "Risk"), edge); err != nil {
+		t.Fatal(err)
+	}
+	broken := EdgePath(repository, "4.16.2", "Broken")
+	if err := os.WriteFile(broken, []byte("to: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := LoadIndex(repository)
+	var edgeErrors EdgeErrors
+	if !errors.As(err, &edgeErrors) {
+		t.Fatalf("expected EdgeErrors, got %v", err)
+	}
+	if _, ok := edgeErrors[broken]; !ok || len(edgeErrors) != 1 {
+		t.Errorf("expected a single error for %s, got %v", broken, edgeErrors)
+	}
+
+	var walked []string
+	if err := index.Walk(Filter{}, func(_ string, edge ConditionallyBlockedEdge) error {
+		walked = append(walked, edge.Name)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if len(walked) != 1 || walked[0] != "Risk" {
+		t.Errorf("expected the readable edge to be indexed, got %v", walked)
+	}
+}
+
+func TestPruneCachedIndexes(t *testing.T) {
+	cacheDir := t.TempDir()
+	now := time.Now()
+	for i := range maxCachedIndexes + 2 {
+		path := filepath.Join(cacheDir, fmt.Sprintf("%d.json", i))
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// a higher number is an older index
+		modified := now.Add(-time.Duration(i) * time.Hour)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruneCachedIndexes(cacheDir)
+
+	for i := range maxCachedIndexes + 2 {
+		_, err := os.Stat(filepath.Join(cacheDir, fmt.Sprintf("%d.json", i)))
+		if kept := err == nil; kept != (i < maxCachedIndexes) {
+			t.Errorf("%d.json: expected kept=%t, got %t", i, i < maxCachedIndexes, kept)
+		}
+	}
+}
+
+func TestSpread(t *testing.T) {
+	repository := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	if err := os.MkdirAll(EdgesDirectory(repository), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"4.16.1", "4.16.2", "4.17.0"} {
+		edge := ConditionallyBlockedEdge{To: version, From: "4\\.15\\..*", Name: "Risk", URL: "https://example.com/old", Message: "Old.", MatchingRules: []PromQLRule{{Type: "Always"}}}
+		if err := SaveEdge(EdgePath(repository, version, "Risk"), edge); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index, err := LoadIndex(repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := ConditionallyBlockedEdge{To: "4.16.2", Name: "Risk", URL: "https://example.com/new", Message: "New.", MatchingRules: []PromQLRule{{Type: "PromQL", PromQL: PromQLQuery{Query: "vector(1)"}}}}
+	targets := []string{EdgePath(repository, "4.16.1", "Risk"), EdgePath(repository, "4.17.0", "Risk")}
+	if err := index.Spread(source, targets); err != nil {
+		t.Fatalf("Spread failed: %v", err)
+	}
+
+	for _, path := range targets {
+		edge, err := LoadEdge(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edge.Message != source.Message || edge.URL != source.URL || len(edge.MatchingRules) != 1 || edge.MatchingRules[0].Type != "PromQL" {
+			t.Errorf("%s: expected the changes to be spread, got %+v", path, edge)
+		}
+		if edge.From != "4\\.15\\..*" || filepath.Base(path) != EdgeFileName(edge.To, "Risk") {
+			t.Errorf("%s: expected from and to to be preserved, got %+v", path, edge)
+		}
+	}
+	if edge, _ := LoadEdge(EdgePath(repository, "4.16.2", "Risk")); edge.Message != "Old." {
+		t.Errorf("expected the edge outside of paths to be unchanged, got %+v", edge)
+	}
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MaxMessageLength is the ceiling for the length of a risk message: OSUS serves it verbatim and longer
+// messages are truncated or hard to read in `oc adm upgrade` output
+const MaxMessageLength = 500
+
+// MessageProblem is a style problem found in a risk message together with a suggestion how to fix it
+type MessageProblem struct {
+	Problem    string
+	Suggestion string
+	// Warning is set for the problems found by heuristics, which may be wrong about a valid message
+	Warning bool
+}
+
+func (p MessageProblem) String() string {
+	if p.Warning {
+		return fmt.Sprintf("warning: message: %s (%s)", p.Problem, p.Suggestion)
+	}
+	return fmt.Sprintf("message: %s (%s)", p.Problem, p.Suggestion)
+}
+
+// MessageErrors returns the problems that are not warnings
+func MessageErrors(problems []MessageProblem) []MessageProblem {
+	var errs []MessageProblem
+	for _, problem := range problems {
+		if !problem.Warning {
+			errs = append(errs, problem)
+		}
+	}
+	return errs
+}
+
+var (
+	jiraMarkup = []struct {
+		name   string
+		regexp *regexp.Regexp
+	}{
+		{"{{monospace}}", regexp.MustCompile(`\{\{.*?\}\}`)},
+		{"[text|link]", regexp.MustCompile(`\[[^\]|]+\|[^\]]+\]`)},
+		{"[~user] mention", regexp.MustCompile(`\[~[^\]]+\]`)},
+		{"hN. heading", regexp.MustCompile(`(^|\s)h[1-6]\.\s`)},
+		{"{code}/{noformat}/{quote} block", regexp.MustCompile(`\{(code|noformat|quote|panel)(:[^}]*)?\}`)},
+	}
+
+	// symptomRegexp matches wording that describes what happens to exposed clusters
+	symptomRegexp = regexp.MustCompile(`(?i)\b(may|might|can|could|will|cause[sd]?|fail\w*|break\w*|unavailab\w*|degrad\w*|los[est]\w*|crash\w*|stuck|hang\w*|disrupt\w*|unable|prevent\w*|block\w*|error\w*|outage|downtime|leak\w*|corrupt\w*)\b`)
+
+	// platformRegexp matches wording that describes which clusters are exposed
+	platformRegexp = regexp.MustCompile(`(?i)\b(aws|azure|gcp|google cloud|vsphere|openstack|bare[ -]?metal|ibm ?cloud|power ?vs|nutanix|ovirt|alibaba|libvirt|platforms?|all clusters|clusters? (with|using|running|that|where|on|in|configured)|(single|multi)[ -]node|sno|hypershift|hosted control planes?|ovn|sdn|fips|proxy|disconnected)\b`)
+)
+
+// CheckMessage returns the style problems of a risk message. Messages that are too long, span multiple
+// lines or contain Jira markup are errors: OSUS serves them verbatim. Whether the message describes the
+// symptom and the exposed clusters, and whether it is written as full sentences, is only guessed, so
+// such problems are warnings.
+func CheckMessage(message string) []MessageProblem {
+	var problems []MessageProblem
+
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return []MessageProblem{{Problem: "is empty", Suggestion: "describe the symptom and the exposed clusters in a sentence or two"}}
+	}
+
+	if length := len(message); length > MaxMessageLength {
+		problems = append(problems, MessageProblem{
+			Problem:    fmt.Sprintf("is %d characters long, the ceiling is %d", length, MaxMessageLength),
+			Suggestion: "keep the symptom and the exposed clusters and leave the details to the linked card",
+		})
+	}
+
+	if strings.Contains(message, "\n") {
+		problems = append(problems, MessageProblem{Problem: "spans multiple lines", Suggestion: "write it as a single paragraph"})
+	}
+
+	for _, markup := range jiraMarkup {
+		if match := markup.regexp.FindString(message); match != "" {
+			problems = append(problems, MessageProblem{
+				Problem:    fmt.Sprintf("contains Jira %s markup %q", markup.name, strings.TrimSpace(match)),
+				Suggestion: "use plain text, the message is not rendered as Jira markup",
+			})
+		}
+	}
+
+	if !symptomRegexp.MatchString(message) {
+		problems = append(problems, MessageProblem{
+			Problem:    "does not seem to describe a symptom",
+			Suggestion: "say what may happen to exposed clusters, e.g. '... may fail to ...'",
+			Warning:    true,
+		})
+	}
+
+	if !platformRegexp.MatchString(message) {
+		problems = append(problems, MessageProblem{
+			Problem:    "does not seem to name the affected platform or configuration",
+			Suggestion: "say which clusters are exposed, e.g. 'Clusters on AWS using ...'",
+			Warning:    true,
+		})
+	}
+
+	if first := []rune(message)[0]; !unicode.IsUpper(first) {
+		problems = append(problems, MessageProblem{Problem: "does not start with a capital letter", Suggestion: "write the message as full sentences", Warning: true})
+	}
+	if !strings.HasSuffix(message, ".") {
+		problems = append(problems, MessageProblem{Problem: "does not end with a period", Suggestion: "write the message as full sentences", Warning: true})
+	}
+
+	return problems
+}
@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckMessage(t *testing.T) {
+	testCases := []struct {
+		name    string
+		message string
+
+		expectedErrors   []string
+		expectedWarnings []string
+	}{
+		{
+			name:    "symptom and platform",
+			message: "Clusters using OpenShift SDN may experience pod network disruption during the update, when nodes reboot into the new release.",
+		},
+		{
+			name:    "platform named as a cloud",
+			message: "On AWS clusters, the cluster-version operator can get stuck updating the cloud-credential operator.",
+		},
+		{
+			name:    "configuration named as clusters with",
+			message: "Clusters with a custom MachineConfigPool may fail to drain nodes, stalling the update of the pool.",
+		},
+		{
+			name:    "single-node clusters",
+			message: "Single-node clusters may lose API availability for several minutes after the update.",
+		},
+		{
+			name:    "multiple sentences",
+			message: "Adding a new worker node will fail for clusters running on Azure. Existing nodes are not affected.",
+		},
+		{
+			name:             "no platform is only a warning",
+			message:          "The OAuth server may crash on start when a custom identity provider is configured.",
+			expectedWarnings: []string{"does not seem to name the affected platform or configuration"},
+		},
+		{
+			name:             "no symptom and lowercase start are only warnings",
+			message:          "upgrades on vSphere with the in-tree storage driver.",
+			expectedWarnings: []string{"does not seem to describe a symptom", "does not start with a capital letter"},
+		},
+		{
+			name:             "missing period is only a warning",
+			message:          "Clusters on GCP may fail to provision new machines",
+			expectedWarnings: []string{"does not end with a period"},
+		},
+		{
+			name:           "empty",
+			message:        " ",
+			expectedErrors: []string{"is empty"},
+		},
+		{
+			name:           "too long",
+			message:        "Clusters on AWS may fail to update. " + strings.Repeat("More details. ", 40),
+			expectedErrors: []string{"is 595 characters long, the ceiling is 500"},
+		},
+		{
+			name:           "multiple lines",
+			message:        "Clusters on AWS may fail to update.\nSee the linked card.",
+			expectedErrors: []string{"spans multiple lines"},
+		},
+		{
+			name:           "Jira markup",
+			message:        "Clusters on AWS may fail to update when {{spec.proxy}} is set, see [the card|https://issues.redhat.com/browse/OCPBUGS-1].",
+			expectedErrors: []string{`contains Jira {{monospace}} markup "{{spec.proxy}}"`, `contains Jira [text|link] markup "[the card|https://issues.redhat.com/browse/OCPBUGS-1]"`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var errors, warnings []string
+			for _, problem := range CheckMessage(tc.message) {
+				if problem.Warning {
+					warnings = append(warnings, problem.Problem)
+				} else {
+					errors = append(errors, problem.Problem)
+				}
+			}
+			if diff := cmp.Diff(tc.expectedErrors, errors); diff != "" {
+				t.Errorf("unexpected errors (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedWarnings, warnings); diff != "" {
+				t.Errorf("unexpected warnings (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMessageErrors(t *testing.T) {
+	problems := []MessageProblem{{Problem: "spans multiple lines"}, {Problem: "does not end with a period", Warning: true}}
+	if diff := cmp.Diff([]MessageProblem{{Problem: "spans multiple lines"}}, MessageErrors(problems)); diff != "" {
+		t.Errorf("unexpected errors (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,14 @@
+package graph
+
+import "regexp"
+
+// JiraBrowsePrefix is the prefix of the Jira card URLs that risks reference in their url field
+const JiraBrowsePrefix = "https://issues.redhat.com/browse/"
+
+// riskNameRegexp matches the CamelCase identifiers used as risk names
+var riskNameRegexp = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// ValidRiskName returns true if the name is a valid risk name (a CamelCase identifier)
+func ValidRiskName(name string) bool {
+	return riskNameRegexp.MatchString(name)
+}
@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// channelsDirName is the directory in the graph repository that holds the versions in each channel
+const channelsDirName = "channels"
+
+// ZStreams returns the z-stream versions from first to last, inclusive. Both versions must be in the
+// same minor.
+func ZStreams(first, last *version.Version) ([]string, error) {
+	if first.Major() != last.Major() || first.Minor() != last.Minor() {
+		return nil, fmt.Errorf("%s and %s are not in the same minor", first, last)
+	}
+	if last.LessThan(first) {
+		return nil, fmt.Errorf("%s is older than %s", last, first)
+	}
+	var versions []string
+	for z := first.Patch(); z <= last.Patch(); z++ {
+		versions = append(versions, fmt.Sprintf("%d.%d.%d", first.Major(), first.Minor(), z))
+	}
+	return versions, nil
+}
+
+// FromRegexp returns the from regular expression of the blocked edges of a risk introduced in the given
+// version: it matches all versions of the previous minor and the versions of the same minor older than
+// the introduced one, which are not exposed to the risk yet. The updates between the exposed versions
+// do not need to be blocked.
+func FromRegexp(introduced *version.Version) string {
+	previous := fmt.Sprintf(`%d\.%d\..*`, introduced.Major(), introduced.Minor()-1)
+	if introduced.Minor() == 0 {
+		previous = fmt.Sprintf(`%d\..*`, introduced.Major()-1)
+	}
+	if introduced.Patch() == 0 {
+		return previous
+	}
+	return fmt.Sprintf(`%s|%d\.%d\.%s`, previous, introduced.Major(), introduced.Minor(), numbersBelow(introduced.Patch()))
+}
+
+// numbersBelow returns a regular expression matching the decimal numbers lower than n (which must be
+// positive), like ([0-9]|1[0-4]) for 15
+func numbersBelow(n uint) string {
+	if n <= 10 {
+		return digitsBelow(n)
+	}
+	parts := []string{"[0-9]"}
+	tens := n / 10
+	// the decades from 10-19 to 90-99 are grouped into a single character class
+	if full := min(tens-1, 9); full > 0 {
+		parts = append(parts, digitRange(1, full)+"[0-9]")
+	}
+	for decade := uint(10); decade < tens; decade++ {
+		parts = append(parts, strconv.FormatUint(uint64(decade), 10)+"[0-9]")
+	}
+	if rest := n % 10; rest > 0 {
+		parts = append(parts, strconv.FormatUint(uint64(tens), 10)+digitsBelow(rest))
+	}
+	return "(" + strings.Join(parts, "|") + ")"
+}
+
+// digitsBelow returns a character class matching the digits lower than n, which must be 1-10
+func digitsBelow(n uint) string {
+	return digitRange(0, n-1)
+}
+
+func digitRange(lowest, highest uint) string {
+	if lowest == highest {
+		return strconv.FormatUint(uint64(lowest), 10)
+	}
+	return fmt.Sprintf("[%d-%d]", lowest, highest)
+}
+
+type channel struct {
+	Versions []string `yaml:"versions"`
+}
+
+// ChannelVersions returns the versions in the channel of the graph repository, like candidate-4.16
+func ChannelVersions(repositoryPath, name string) (sets.Set[string], error) {
+	path := filepath.Join(repositoryPath, channelsDirName, name+".yaml")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c channel
+	if err := yaml.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal %s: %w", path, err)
+	}
+	return sets.New[string](c.Versions...), nil
+}
@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestZStreams(t *testing.T) {
+	testCases := []struct {
+		name        string
+		first, last string
+
+		expected      []string
+		expectedError bool
+	}{
+		{
+			name:     "range",
+			first:    "4.16.8",
+			last:     "4.16.11",
+			expected: []string{"4.16.8", "4.16.9", "4.16.10", "4.16.11"},
+		},
+		{
+			name:     "single version",
+			first:    "4.16.0",
+			last:     "4.16.0",
+			expected: []string{"4.16.0"},
+		},
+		{
+			name:          "different minors",
+			first:         "4.15.20",
+			last:          "4.16.2",
+			expectedError: true,
+		},
+		{
+			name:          "reversed",
+			first:         "4.16.2",
+			last:          "4.16.1",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			versions, err := ZStreams(version.MustParseGeneric(tc.first), version.MustParseGeneric(tc.last))
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %t, got %v", tc.expectedError, err)
+			}
+			if diff := cmp.Diff(tc.expected, versions); diff != "" {
+				t.Errorf("versions differ from expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFromRegexp(t *testing.T) {
+	testCases := []struct {
+		introduced string
+		expected   string
+	}{
+		{introduced: "4.16.0", expected: `4\.15\..*`},
+		{introduced: "4.16.1", expected: `4\.15\..*|4\.16\.0`},
+		{introduced: "4.16.5", expected: `4\.15\..*|4\.16\.[0-4]`},
+		{introduced: "4.16.10", expected: `4\.15\..*|4\.16\.[0-9]`},
+		{introduced: "4.16.11", expected: `4\.15\..*|4\.16\.([0-9]|10)`},
+		{introduced: "4.16.27", expected: `4\.15\..*|4\.16\.([0-9]|1[0-9]|2[0-6])`},
+		{introduced: "4.16.40", expected: `4\.15\..*|4\.16\.([0-9]|[1-3][0-9])`},
+		{introduced: "5.0.0", expected: `4\..*`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.introduced, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, FromRegexp(version.MustParseGeneric(tc.introduced))); diff != "" {
+				t.Errorf("from regexp differs from expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFromRegexpMatches(t *testing.T) {
+	for _, introduced := range []uint{1, 9, 10, 11, 19, 20, 21, 99, 100, 101, 123} {
+		re := regexp.MustCompile("^(?:" + FromRegexp(version.MustParseGeneric(fmt.Sprintf("4.16.%d", introduced))) + ")$")
+		for z := uint(0); z < 150; z++ {
+			if matches := re.MatchString(fmt.Sprintf("4.16.%d", z)); matches != (z < introduced) {
+				t.Errorf("introduced in 4.16.%d: expected 4.16.%d to match: %t, got %t", introduced, z, z < introduced, matches)
+			}
+		}
+	}
+}
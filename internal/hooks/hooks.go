@@ -0,0 +1,156 @@
+// Package hooks runs user-configured automation around the key actions of the workflow commands.
+// Each hook is either a command that receives a JSON payload on its stdin, or a webhook that
+// receives the payload in a POST request body.
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/httputil"
+)
+
+const (
+	hooksFileName = "hooks.yaml"
+)
+
+// Event identifies the action around which hooks run
+type Event string
+
+const (
+	EventImpactStatementRequested Event = "impact-statement-requested"
+	EventImpactStatementProposed  Event = "impact-statement-proposed"
+	EventKnownIssueAnnounced      Event = "known-issue-announced"
+	EventLabelsCleared            Event = "labels-cleared"
+	EventRiskExtended             Event = "risk-extended"
+	EventRiskFixed                Event = "risk-fixed"
+)
+
+// Phase is either before (pre) or after (post) the action
+type Phase string
+
+const (
+	PhasePre  Phase = "pre"
+	PhasePost Phase = "post"
+)
+
+// Hook is a single configured hook
+type Hook struct {
+	Event   Event    `yaml:"event"`
+	Phase   Phase    `yaml:"phase"`
+	Command []string `yaml:"command,omitempty"`
+	URL     string   `yaml:"url,omitempty"`
+}
+
+// Payload is the JSON document passed to hooks
+type Payload struct {
+	Event Event             `json:"event"`
+	Phase Phase             `json:"phase"`
+	Time  time.Time         `json:"time"`
+	Data  map[string]string `json:"data"`
+}
+
+// Runner runs the configured hooks
+type Runner struct {
+	Hooks []Hook `yaml:"hooks"`
+}
+
+// Load reads the hooks configuration from the ota config directory. A missing configuration is
+// not an error and results in a Runner without any hooks.
+func Load() (*Runner, error) {
+	raw, path, err := config.ReadConfigFile(hooksFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Runner{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read hooks config %s: %w", path, err)
+	}
+
+	var r Runner
+	if err := yaml.Unmarshal(raw, &r); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal hooks config %s: %w", path, err)
+	}
+	for i, hook := range r.Hooks {
+		if (len(hook.Command) == 0) == (hook.URL == "") {
+			return nil, fmt.Errorf("hook %d in %s must have exactly one of command or url", i, path)
+		}
+		if hook.Phase != PhasePre && hook.Phase != PhasePost {
+			return nil, fmt.Errorf("hook %d in %s has invalid phase %q", i, path, hook.Phase)
+		}
+	}
+	return &r, nil
+}
+
+// Pre runs the hooks configured to run before the action. An error means that a hook failed and
+// that the action should not be performed.
+func (r *Runner) Pre(event Event, data map[string]string) error {
+	return r.run(event, PhasePre, data)
+}
+
+// Post runs the hooks configured to run after the action. The action already happened, so failures
+// are only logged.
+func (r *Runner) Post(event Event, data map[string]string) {
+	if err := r.run(event, PhasePost, data); err != nil {
+		logrus.WithError(err).Warnf("Post-%s hook failed", event)
+	}
+}
+
+func (r *Runner) run(event Event, phase Phase, data map[string]string) error {
+	var payload []byte
+	for _, hook := range r.Hooks {
+		if hook.Event != event || hook.Phase != phase {
+			continue
+		}
+
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(Payload{Event: event, Phase: phase, Time: time.Now(), Data: data}); err != nil {
+				return fmt.Errorf("cannot marshal hook payload: %w", err)
+			}
+		}
+
+		logrus.Infof("Running %s-%s hook", phase, event)
+		if len(hook.Command) > 0 {
+			if err := runCommand(hook.Command, payload); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := callWebhook(hook.URL, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runCommand(command []string, payload []byte) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("hook command %s failed: %w", command[0], err)
+	}
+	return nil
+}
+
+func callWebhook(url string, payload []byte) error {
+	resp, err := httputil.Client().Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("hook webhook %s failed: %w", url, err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("hook webhook %s returned %s", url, resp.Status)
+	}
+	return nil
+}
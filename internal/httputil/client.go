@@ -0,0 +1,82 @@
+// Package httputil provides the HTTP client shared by the tools for talking to non-Jira services
+package httputil
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	sharedClient     *http.Client
+	sharedClientOnce sync.Once
+)
+
+// Client returns an HTTP client with a shared, connection-pooling transport. Sequential requests to the
+// same host reuse established connections instead of paying for a TCP and TLS handshake every time.
+//
+// The Jira client is not covered: prow's Jira client builds its own (already pooled) retrying transport
+// and offers no way to inject a different one.
+func Client() *http.Client {
+	sharedClientOnce.Do(func() {
+		transport := &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 60 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		}
+		sharedClient = &http.Client{
+			Transport: &tracingTransport{upstream: transport},
+			Timeout:   5 * time.Minute,
+		}
+	})
+	return sharedClient
+}
+
+// tracingTransport logs connection reuse and timing of each request on debug level
+type tracingTransport struct {
+	upstream http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !logrus.IsLevelEnabled(logrus.DebugLevel) {
+		return t.upstream.RoundTrip(r)
+	}
+
+	var reused bool
+	var tlsStart, tlsDone time.Time
+	trace := &httptrace.ClientTrace{
+		GotConn:           func(info httptrace.GotConnInfo) { reused = info.Reused },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(_ tls.ConnectionState, _ error) { tlsDone = time.Now() },
+	}
+	start := time.Now()
+	resp, err := t.upstream.RoundTrip(r.WithContext(httptrace.WithClientTrace(r.Context(), trace)))
+
+	fields := logrus.Fields{
+		"host":     r.URL.Host,
+		"reused":   reused,
+		"duration": time.Since(start).Truncate(time.Millisecond),
+	}
+	if !tlsStart.IsZero() {
+		fields["tls"] = tlsDone.Sub(tlsStart).Truncate(time.Millisecond)
+	}
+	if resp != nil {
+		fields["proto"] = resp.Proto
+	}
+	logrus.WithFields(fields).Debugf("%s %s", r.Method, r.URL.Path)
+	return resp, err
+}
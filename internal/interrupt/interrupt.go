@@ -0,0 +1,25 @@
+// Package interrupt provides the context the tools use to abort their work when the user interrupts them
+package interrupt
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Context returns a context that is cancelled when the process receives SIGINT or SIGTERM. Tools pass
+// it to their network requests so that Ctrl+C aborts them promptly and the tool can still report what
+// it did before the interrupt. A second signal terminates the process right away.
+func Context() context.Context {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		logrus.Warn("Interrupted, aborting (interrupt again to exit immediately)")
+		// Restore the default handling so that the next signal terminates the process
+		stop()
+	}()
+	return ctx
+}
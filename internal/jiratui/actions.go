@@ -0,0 +1,208 @@
+package jiratui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/updateblockers"
+)
+
+// Action is a blocker workflow operation that can be run on the bug selected in a panel
+type Action struct {
+	Key  string
+	Name string
+
+	// question is shown to confirm the action on a bug
+	question string
+	// needsProject actions prompt for the project of the component instead of a yes/no confirmation
+	needsProject bool
+
+	run func(flow *blockerflow.Flow, candidate *blockerflow.Candidate, project string) (string, error)
+}
+
+var (
+	RequestImpactStatement = Action{
+		Key:          "i",
+		Name:         "request impact statement",
+		question:     "Project of the component to request the impact statement for %s from",
+		needsProject: true,
+		run: func(flow *blockerflow.Flow, candidate *blockerflow.Candidate, project string) (string, error) {
+			if err := flow.RequestImpactStatement(candidate, project, false); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Created impact statement request %s for %s", candidate.ImpactStatementRequest.Key, candidate.Bug.Key), nil
+		},
+	}
+
+	ProposeImpactStatement = Action{
+		Key:      "p",
+		Name:     "mark impact statement proposed",
+		question: "Move %s to " + updateblockers.LabelImpactStatementProposed + " and its impact statement request to review?",
+		run: func(flow *blockerflow.Flow, candidate *blockerflow.Candidate, _ string) (string, error) {
+			if candidate.ImpactStatementRequest == nil && len(candidate.ImpactStatementRequestCandidates) > 1 {
+				return "", fmt.Errorf("%s has multiple impact statement request candidates, use monitor-jira-move-to-proposed --impact-statement-card", candidate.Bug.Key)
+			}
+			if err := flow.MarkProposed(candidate, false); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Moved %s to %s", candidate.Bug.Key, updateblockers.LabelImpactStatementProposed), nil
+		},
+	}
+
+	ClearLabels = Action{
+		Key:      "c",
+		Name:     "clear labels",
+		question: "Clear all UpgradeBlocker related labels from %s?",
+		run: func(flow *blockerflow.Flow, candidate *blockerflow.Candidate, _ string) (string, error) {
+			if err := flow.ClearLabels(candidate); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Cleared UpgradeBlocker related labels from %s", candidate.Bug.Key), nil
+		},
+	}
+)
+
+// ActionDoneMsg reports the result of an action
+type ActionDoneMsg struct {
+	Action string
+	Bug    string
+	Result string
+	Err    error
+}
+
+// Actions runs blocker workflow actions on bugs after the user confirms them
+type Actions struct {
+	flow    *blockerflow.Flow
+	actions []Action
+
+	// pending is the action waiting for confirmation on bug
+	pending *Action
+	bug     string
+	project textinput.Model
+
+	status string
+}
+
+// NewActions returns a component offering the given actions. Without a flow, the actions are not available.
+func NewActions(flow *blockerflow.Flow, actions ...Action) Actions {
+	project := textinput.New()
+	project.Placeholder = "e.g. MCO"
+	return Actions{flow: flow, actions: actions, project: project}
+}
+
+// Active returns true while a confirmation is shown; all key presses should be passed to Update then
+func (a Actions) Active() bool {
+	return a.pending != nil
+}
+
+// Start asks for the confirmation of the action bound to the key for the bug. It returns false when
+// no action is bound to the key.
+func (a Actions) Start(key, bug string) (Actions, tea.Cmd, bool) {
+	for i := range a.actions {
+		if a.actions[i].Key != key {
+			continue
+		}
+		if a.flow == nil {
+			a.status = "Workflow actions are not available"
+			return a, nil, true
+		}
+		a.pending = &a.actions[i]
+		a.bug = bug
+		a.status = ""
+		if a.pending.needsProject {
+			a.project.Reset()
+			return a, a.project.Focus(), true
+		}
+		return a, nil, true
+	}
+	return a, nil, false
+}
+
+// Update handles key presses while a confirmation is shown and the results of the actions
+func (a Actions) Update(msg tea.Msg) (Actions, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ActionDoneMsg:
+		if msg.Err != nil {
+			a.status = fmt.Sprintf("Failed to %s for %s: %v", msg.Action, msg.Bug, msg.Err)
+		} else {
+			a.status = msg.Result
+		}
+		return a, nil
+	case tea.KeyMsg:
+		if a.pending == nil {
+			return a, nil
+		}
+		action, bug := *a.pending, a.bug
+		switch msg.String() {
+		case "esc":
+			a.pending = nil
+			return a, nil
+		case "enter":
+			if !action.needsProject {
+				return a, nil
+			}
+			project := strings.TrimSpace(a.project.Value())
+			if project == "" {
+				return a, nil
+			}
+			a.pending = nil
+			a.status = fmt.Sprintf("Running %s for %s...", action.Name, bug)
+			return a, a.run(action, bug, project)
+		case "y", "Y":
+			if !action.needsProject {
+				a.pending = nil
+				a.status = fmt.Sprintf("Running %s for %s...", action.Name, bug)
+				return a, a.run(action, bug, "")
+			}
+		case "n", "N":
+			if !action.needsProject {
+				a.pending = nil
+				return a, nil
+			}
+		}
+		if action.needsProject {
+			var cmd tea.Cmd
+			a.project, cmd = a.project.Update(msg)
+			return a, cmd
+		}
+	}
+	return a, nil
+}
+
+func (a Actions) run(action Action, bug, project string) tea.Cmd {
+	flow := a.flow
+	return func() tea.Msg {
+		msg := ActionDoneMsg{Action: action.Name, Bug: bug}
+		candidate, err := flow.Load(bug, "")
+		if err != nil {
+			msg.Err = err
+			return msg
+		}
+		msg.Result, msg.Err = action.run(flow, candidate, project)
+		return msg
+	}
+}
+
+// Help describes the keys bound to the actions
+func (a Actions) Help() string {
+	var help []string
+	for _, action := range a.actions {
+		help = append(help, fmt.Sprintf("'%s' to %s", action.Key, action.Name))
+	}
+	return strings.Join(help, ", ")
+}
+
+// View shows the pending confirmation or the result of the last action
+func (a Actions) View() string {
+	switch {
+	case a.pending == nil:
+		return a.status
+	case a.pending.needsProject:
+		return fmt.Sprintf(a.pending.question, a.bug) + ": " + a.project.View() + "\nPress 'enter' to confirm, 'esc' to cancel"
+	}
+	return fmt.Sprintf(a.pending.question, a.bug) + " (y/n)"
+}
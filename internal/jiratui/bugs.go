@@ -0,0 +1,323 @@
+package jiratui
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/petr-muller/ota/internal/platform"
+	"github.com/petr-muller/ota/internal/riskinspect"
+)
+
+// bugSort is a column the bug explorer sorts by
+type bugSort int
+
+const (
+	sortByKey bugSort = iota
+	sortByTarget
+	sortByStatus
+)
+
+var bugSortNames = []string{"key", "target version", "status"}
+
+// maxSummaryLength is the number of summary characters shown for each bug
+const maxSummaryLength = 70
+
+var (
+	fixStateStyles = map[riskinspect.FixState]lipgloss.Style{
+		riskinspect.FixStateUnfixed: lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
+		riskinspect.FixStatePending: lipgloss.NewStyle().Foreground(lipgloss.Color("11")),
+		riskinspect.FixStateFixed:   lipgloss.NewStyle().Foreground(lipgloss.Color("10")),
+		riskinspect.FixStateUnknown: lipgloss.NewStyle().Foreground(lipgloss.Color("8")),
+	}
+	fixStateOrder = map[riskinspect.FixState]int{
+		riskinspect.FixStateUnfixed: 0,
+		riskinspect.FixStatePending: 1,
+		riskinspect.FixStateFixed:   2,
+		riskinspect.FixStateUnknown: 3,
+	}
+	cursorStyle = lipgloss.NewStyle().Bold(true)
+	helpStyle   = lipgloss.NewStyle().Faint(true)
+)
+
+// bugRow is a bug shown in the explorer, nested under the bug it was cloned from
+type bugRow struct {
+	bug    riskinspect.Bug
+	depth  int
+	clones int
+}
+
+// bugExplorer shows the bugs reachable from the impact statement card of a risk as a tree of clone
+// chains and lets the user accept whether to extend the risk or declare it fixed
+type bugExplorer struct {
+	inspection *riskinspect.Inspection
+	jiraURL    string
+	newVersion string
+
+	recommendation string
+	reason         string
+
+	// clones maps the bugs to the bugs cloned from them
+	clones   map[string][]riskinspect.Bug
+	roots    []riskinspect.Bug
+	expanded map[string]bool
+	sortBy   bugSort
+
+	rows   []bugRow
+	cursor int
+
+	accepted  string
+	cancelled bool
+}
+
+func newBugExplorer(inspection *riskinspect.Inspection, jiraURL, newVersion string) bugExplorer {
+	e := bugExplorer{
+		inspection: inspection,
+		jiraURL:    jiraURL,
+		newVersion: newVersion,
+		clones:     map[string][]riskinspect.Bug{},
+		expanded:   map[string]bool{},
+	}
+	e.recommendation, e.reason = inspection.Recommend(newVersion)
+
+	inspected := map[string]bool{}
+	for _, bug := range inspection.Bugs {
+		inspected[bug.Key] = true
+	}
+	for _, bug := range inspection.Bugs {
+		if bug.Clones != "" && inspected[bug.Clones] && bug.Clones != bug.Key {
+			e.clones[bug.Clones] = append(e.clones[bug.Clones], bug)
+			continue
+		}
+		e.roots = append(e.roots, bug)
+	}
+	e.refreshRows()
+	return e
+}
+
+// less orders the bugs by the sort column, then by their keys
+func (e bugExplorer) less(a, b riskinspect.Bug) bool {
+	switch e.sortBy {
+	case sortByTarget:
+		if c := compareTargets(a.TargetVersion, b.TargetVersion); c != 0 {
+			return c < 0
+		}
+	case sortByStatus:
+		if a, b := fixStateOrder[a.FixState()], fixStateOrder[b.FixState()]; a != b {
+			return a < b
+		}
+	}
+	return compareKeys(a.Key, b.Key) < 0
+}
+
+// compareTargets compares target versions like 4.16.z component by component, numerically where
+// possible. Bugs without a target version go last.
+func compareTargets(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if c := compareNumeric(aParts[i], bParts[i]); c != 0 {
+			return c
+		}
+	}
+	return len(aParts) - len(bParts)
+}
+
+// compareKeys compares Jira keys by project, then numerically by their numbers
+func compareKeys(a, b string) int {
+	aProject, aNumber, _ := strings.Cut(a, "-")
+	bProject, bNumber, _ := strings.Cut(b, "-")
+	if aProject != bProject {
+		return strings.Compare(aProject, bProject)
+	}
+	return compareNumeric(aNumber, bNumber)
+}
+
+func compareNumeric(a, b string) int {
+	aNumber, aErr := strconv.Atoi(a)
+	bNumber, bErr := strconv.Atoi(b)
+	if aErr == nil && bErr == nil {
+		return aNumber - bNumber
+	}
+	return strings.Compare(a, b)
+}
+
+// refreshRows rebuilds the visible rows from the sorted roots and the clones of the expanded bugs,
+// keeping the cursor on the same bug
+func (e *bugExplorer) refreshRows() {
+	var selected string
+	if e.cursor < len(e.rows) {
+		selected = e.rows[e.cursor].bug.Key
+	}
+
+	e.rows = nil
+	var add func(bugs []riskinspect.Bug, depth int)
+	add = func(bugs []riskinspect.Bug, depth int) {
+		sorted := append([]riskinspect.Bug{}, bugs...)
+		sort.SliceStable(sorted, func(i, j int) bool { return e.less(sorted[i], sorted[j]) })
+		for _, bug := range sorted {
+			e.rows = append(e.rows, bugRow{bug: bug, depth: depth, clones: len(e.clones[bug.Key])})
+			// depth guards against clone cycles
+			if e.expanded[bug.Key] && depth < len(e.inspection.Bugs) {
+				add(e.clones[bug.Key], depth+1)
+			}
+		}
+	}
+	add(e.roots, 0)
+
+	e.cursor = 0
+	for i, row := range e.rows {
+		if row.bug.Key == selected {
+			e.cursor = i
+		}
+	}
+}
+
+func (e bugExplorer) Init() tea.Cmd {
+	return nil
+}
+
+func (e bugExplorer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return e, nil
+	}
+	switch key.String() {
+	case "up", "k":
+		e.cursor = max(e.cursor-1, 0)
+	case "down", "j":
+		e.cursor = min(e.cursor+1, len(e.rows)-1)
+	case "enter", " ", "right", "left", "l", "h":
+		if e.cursor < len(e.rows) && e.rows[e.cursor].clones > 0 {
+			bug := e.rows[e.cursor].bug.Key
+			e.expanded[bug] = !e.expanded[bug]
+			e.refreshRows()
+		}
+	case "s":
+		e.sortBy = (e.sortBy + 1) % bugSort(len(bugSortNames))
+		e.refreshRows()
+	case "o":
+		if e.cursor < len(e.rows) {
+			return e, openBug(e.jiraURL, e.rows[e.cursor].bug.Key)
+		}
+	case "a":
+		e.accepted = e.recommendation
+		return e, tea.Quit
+	case "e":
+		e.accepted = riskinspect.ActionExtend
+		return e, tea.Quit
+	case "f":
+		e.accepted = riskinspect.ActionFix
+		return e, tea.Quit
+	case "q", "esc", "ctrl+c":
+		e.cancelled = true
+		return e, tea.Quit
+	}
+	return e, nil
+}
+
+// openBug returns a command opening the bug in the browser, or copying its URL to the clipboard where
+// that is not possible
+func openBug(jiraURL, key string) tea.Cmd {
+	return func() tea.Msg {
+		bugURL, err := url.JoinPath(jiraURL, "browse", key)
+		if err != nil {
+			return nil
+		}
+		if err := platform.OpenURL(bugURL); errors.Is(err, platform.ErrUnsupported) {
+			_ = platform.CopyToClipboard(bugURL)
+		}
+		return nil
+	}
+}
+
+func (e bugExplorer) View() string {
+	if e.accepted != "" || e.cancelled {
+		return ""
+	}
+
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "%s\n", e.inspection.Summary())
+	_, _ = fmt.Fprintf(&b, "Recommendation for %s: %s (%s)\n\n", e.newVersion, e.recommendation, e.reason)
+
+	type line struct{ tree, direct, target, status, summary string }
+	lines := []line{{tree: "BUG", direct: "DIRECT", target: "TARGET", status: "STATUS", summary: "SUMMARY"}}
+	for _, row := range e.rows {
+		marker := "  "
+		if row.clones > 0 {
+			marker = "▸ "
+			if e.expanded[row.bug.Key] {
+				marker = "▾ "
+			}
+		}
+		direct := ""
+		if row.bug.Direct {
+			direct = "x"
+		}
+		summary := row.bug.Summary
+		if runes := []rune(summary); len(runes) > maxSummaryLength {
+			summary = string(runes[:maxSummaryLength-1]) + "…"
+		}
+		lines = append(lines, line{
+			tree:    strings.Repeat("  ", row.depth) + marker + row.bug.Key,
+			direct:  direct,
+			target:  row.bug.TargetVersion,
+			status:  row.bug.Status,
+			summary: summary,
+		})
+	}
+	var treeWidth, targetWidth, statusWidth int
+	for _, l := range lines {
+		treeWidth = max(treeWidth, len([]rune(l.tree)))
+		targetWidth = max(targetWidth, len(l.target))
+		statusWidth = max(statusWidth, len(l.status))
+	}
+
+	for i, l := range lines {
+		status := fmt.Sprintf("%-*s", statusWidth, l.status)
+		cursor := "  "
+		if i > 0 {
+			status = fixStateStyles[e.rows[i-1].bug.FixState()].Render(status)
+			if i-1 == e.cursor {
+				cursor = cursorStyle.Render("> ")
+			}
+		}
+		padding := strings.Repeat(" ", treeWidth-len([]rune(l.tree)))
+		_, _ = fmt.Fprintf(&b, "%s%s%s  %-6s  %-*s  %s  %s\n", cursor, l.tree, padding, l.direct, targetWidth, l.target, status, l.summary)
+	}
+
+	_, _ = fmt.Fprintf(&b, "\n%s\n", helpStyle.Render(fmt.Sprintf(
+		"↑/↓ move • enter expand clones • s sort (by %s) • o open • a accept %s • e extend • f fix • q quit",
+		bugSortNames[e.sortBy], e.recommendation)))
+	return b.String()
+}
+
+// ExploreBugs shows the bugs of the inspection in a terminal UI, together with the recommendation
+// whether to extend the risk to the new version or declare it fixed in it. It returns the action the
+// user accepted, or ErrCancelled when the user quit without accepting any.
+func ExploreBugs(inspection *riskinspect.Inspection, jiraURL, newVersion string) (string, error) {
+	if !Interactive() {
+		return "", ErrNotInteractive
+	}
+	final, err := Run(newBugExplorer(inspection, jiraURL, newVersion))
+	if err != nil {
+		return "", err
+	}
+	if e := final.(bugExplorer); !e.cancelled {
+		return e.accepted, nil
+	}
+	return "", ErrCancelled
+}
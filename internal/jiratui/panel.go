@@ -0,0 +1,454 @@
+// Package jiratui implements bubbletea components for browsing Jira queries
+package jiratui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/petr-muller/ota/internal/blockerflow"
+	"github.com/petr-muller/ota/internal/jirautil"
+	"github.com/petr-muller/ota/internal/platform"
+	"github.com/petr-muller/ota/internal/timefmt"
+)
+
+// Client is the subset of the Jira client the panels need
+type Client interface {
+	GetIssue(string) (*jira.Issue, error)
+	SearchWithContext(context.Context, string, *jira.SearchOptions) ([]jira.Issue, *jira.Response, error)
+	JiraURL() string
+}
+
+// Panel is a titled table of the results of a single Jira query. Results are fetched page by page
+// and the panel can be refreshed at any time. Messages for a panel carry its name, so a model with
+// several panels can route them with Handles.
+type Panel struct {
+	Name  string
+	Title string
+	Query string
+
+	fetched bool
+	loading bool
+	total   int
+	err     error
+	items   []jira.Issue
+	table   table.Model
+	spinner spinner.Model
+
+	// queryErr is set when the query cannot be used at all; such a panel is never fetched
+	queryErr error
+
+	// generation is increased on every refresh so that pages of a superseded fetch are dropped
+	generation int
+
+	// isrs holds the lazily fetched impact statement requests linked to the items, keyed by the item key.
+	// The ISR column is only shown when isrs is not nil.
+	isrs map[string]isrInfo
+	// bugProjects are the Jira projects whose linked cards are not considered to be ISRs
+	bugProjects []string
+
+	// outstanding is set for panels whose items are impact statement requests themselves; such panels
+	// show how long each request is waiting for an answer
+	outstanding bool
+	// overdueOnly hides the items whose impact statement request is not overdue
+	overdueOnly bool
+
+	timestamps timefmt.Formatter
+}
+
+// NewPanel returns a panel showing the results of query. With withIsrs, the panel fetches the impact
+// statement request linked to each item and shows its state in an additional column.
+func NewPanel(name, title, query string, withIsrs bool) Panel {
+	p := Panel{
+		Name:    name,
+		Title:   title,
+		Query:   query,
+		spinner: spinner.New(spinner.WithSpinner(spinner.Points)),
+		table:   table.New(),
+	}
+	if withIsrs {
+		p.isrs = map[string]isrInfo{}
+	}
+	return p
+}
+
+// isrInfo is the state of the impact statement request linked to a bug
+type isrInfo struct {
+	fetched bool
+	key     string
+	status  string
+	updated time.Time
+	created time.Time
+	done    bool
+	err     error
+}
+
+// overdue returns true when the impact statement request is still waiting for an answer after the SLA
+func (i isrInfo) overdue(now time.Time) bool {
+	return i.fetched && i.key != "" && !i.done && blockerflow.Overdue(i.created, now)
+}
+
+func (i isrInfo) format(timestamps timefmt.Formatter, now time.Time) string {
+	switch {
+	case !i.fetched:
+		return "..."
+	case i.err != nil:
+		return "error"
+	case i.key == "":
+		return "none"
+	}
+	formatted := fmt.Sprintf("%s %s (%s)", i.key, i.status, timestamps.Since(i.updated, now))
+	if !i.done {
+		formatted += ", " + blockerflow.FormatOutstanding(i.created, now)
+	}
+	return formatted
+}
+
+// isDone returns true when the issue is in a status of the Done category
+func isDone(issue jira.Issue) bool {
+	return issue.Fields.Status != nil && issue.Fields.Status.StatusCategory.Key == jira.StatusCategoryComplete
+}
+
+// PageMsg carries a single page of search results for a panel together with the total number of results
+type PageMsg struct {
+	panel      string
+	generation int
+	items      []jira.Issue
+	total      int
+	err        error
+}
+
+// IsrMsg carries the impact statement request linked to a bug in a panel
+type IsrMsg struct {
+	panel string
+	bug   string
+	isr   isrInfo
+}
+
+// Handles returns true if the message is addressed to this panel
+func (p Panel) Handles(msg tea.Msg) bool {
+	switch msg := msg.(type) {
+	case PageMsg:
+		return msg.panel == p.Name
+	case IsrMsg:
+		return msg.panel == p.Name
+	}
+	return false
+}
+
+// WithTimestamps returns a copy of the panel formatting ages with the given formatter
+func (p Panel) WithTimestamps(timestamps timefmt.Formatter) Panel {
+	p.timestamps = timestamps
+	p.refreshTable()
+	return p
+}
+
+// WithBugProjects returns a copy of the panel that looks for the ISRs outside of the given bug projects
+func (p Panel) WithBugProjects(projects []string) Panel {
+	p.bugProjects = projects
+	return p
+}
+
+// WithOutstanding returns a copy of the panel whose items are impact statement requests, showing
+// how long each of them is waiting for an answer
+func (p Panel) WithOutstanding() Panel {
+	p.outstanding = true
+	p.refreshTable()
+	return p
+}
+
+// WithOverdueOnly returns a copy of the panel that only shows the items whose impact statement request
+// is overdue. Panels that do not know about impact statement requests (neither WithOutstanding nor
+// created withIsrs) show all items.
+func (p Panel) WithOverdueOnly() Panel {
+	p.overdueOnly = true
+	p.refreshTable()
+	return p
+}
+
+// visible returns the items shown in the table
+func (p Panel) visible() []jira.Issue {
+	if !p.overdueOnly || (p.isrs == nil && !p.outstanding) {
+		return p.items
+	}
+	now := time.Now()
+	var visible []jira.Issue
+	for _, item := range p.items {
+		if p.isrs != nil && p.isrs[item.Key].overdue(now) {
+			visible = append(visible, item)
+		}
+		if p.outstanding && !isDone(item) && blockerflow.Overdue(time.Time(item.Fields.Created), now) {
+			visible = append(visible, item)
+		}
+	}
+	return visible
+}
+
+// WithQueryError returns a copy of the panel that shows the error instead of fetching results
+func (p Panel) WithQueryError(err error) Panel {
+	p.queryErr = err
+	p.fetched = true
+	p.err = err
+	return p
+}
+
+// Refresh returns a copy of the panel with all results dropped and a command fetching them again
+func (p Panel) Refresh(client Client) (Panel, tea.Cmd) {
+	if p.queryErr != nil {
+		return p, nil
+	}
+	p.generation++
+	p.fetched = false
+	p.loading = false
+	p.err = nil
+	p.items = nil
+	p.total = 0
+	if p.isrs != nil {
+		p.isrs = map[string]isrInfo{}
+	}
+	p.refreshTable()
+	return p, tea.Batch(p.fetchPage(0, client), p.spinner.Tick)
+}
+
+func (p Panel) fetchPage(startAt int, client Client) tea.Cmd {
+	name, generation, query := p.Name, p.generation, p.Query
+	return func() tea.Msg {
+		items, total, err := jirautil.SearchPage(context.Background(), client, query, startAt)
+		return PageMsg{panel: name, generation: generation, items: items, total: total, err: err}
+	}
+}
+
+// Update handles messages addressed to the panel (see Handles) and passes everything else to the table
+// (when focused) and the spinner
+func (p Panel) Update(msg tea.Msg, client Client) (Panel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case PageMsg:
+		if msg.panel != p.Name || msg.generation != p.generation {
+			return p, nil
+		}
+		p.fetched = true
+		p.err = msg.err
+		p.items = append(p.items, msg.items...)
+		p.total = max(msg.total, len(p.items))
+		p.loading = msg.err == nil && len(msg.items) > 0 && len(p.items) < p.total
+		cmds := p.fetchMissingIsrs(client)
+		p.refreshTable()
+		if p.loading {
+			cmds = append(cmds, p.fetchPage(len(p.items), client))
+		}
+		return p, tea.Batch(cmds...)
+	case IsrMsg:
+		if msg.panel != p.Name || p.isrs == nil {
+			return p, nil
+		}
+		p.isrs[msg.bug] = msg.isr
+		p.refreshTable()
+		return p, nil
+	}
+
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	if p.table.Focused() {
+		p.table, cmd = p.table.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	p.spinner, cmd = p.spinner.Update(msg)
+	cmds = append(cmds, cmd)
+	return p, tea.Batch(cmds...)
+}
+
+// Focus returns a copy of the panel that receives keyboard input
+func (p Panel) Focus() Panel {
+	p.table.Focus()
+	return p
+}
+
+// Blur returns a copy of the panel that does not receive keyboard input
+func (p Panel) Blur() Panel {
+	p.table.Blur()
+	return p
+}
+
+// Tick returns the command that starts the panel spinner
+func (p Panel) Tick() tea.Msg {
+	return p.spinner.Tick()
+}
+
+// Selected returns the issue under the cursor, or nil when the panel has no results
+func (p Panel) Selected() *jira.Issue {
+	visible := p.visible()
+	if !p.fetched || p.table.Cursor() < 0 || p.table.Cursor() >= len(visible) {
+		return nil
+	}
+	return &visible[p.table.Cursor()]
+}
+
+// Select returns a copy of the panel with the cursor on the issue with the given key, and whether the
+// panel contains such issue
+func (p Panel) Select(key string) (Panel, bool) {
+	for i, item := range p.visible() {
+		if item.Key == key {
+			p.table.SetCursor(i)
+			return p, true
+		}
+	}
+	return p, false
+}
+
+// Loaded returns true when all results of the query were fetched (or fetching them failed)
+func (p Panel) Loaded() bool {
+	return p.fetched && !p.loading
+}
+
+// OpenSelected returns a command opening the issue under the cursor in the browser. Where that is not
+// possible, the URL of the issue is copied to the clipboard instead.
+func (p Panel) OpenSelected(client Client) tea.Cmd {
+	issue := p.Selected()
+	if issue == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		itemUrl, err := url.JoinPath(client.JiraURL(), "browse", issue.Key)
+		if err != nil {
+			return nil
+		}
+		if err := platform.OpenURL(itemUrl); errors.Is(err, platform.ErrUnsupported) {
+			_ = platform.CopyToClipboard(itemUrl)
+		}
+		return nil
+	}
+}
+
+// Label returns the panel title with the number of results, e.g. for a tab bar
+func (p Panel) Label() string {
+	switch {
+	case p.err != nil:
+		return p.Title + " (error)"
+	case !p.fetched:
+		return p.Title + " (...)"
+	}
+	if p.overdueOnly && (p.isrs != nil || p.outstanding) {
+		return fmt.Sprintf("%s (%d overdue of %d)", p.Title, len(p.visible()), p.total)
+	}
+	return fmt.Sprintf("%s (%d)", p.Title, p.total)
+}
+
+func (p Panel) View() string {
+	view := p.Title + ":\n\n"
+	if !p.fetched {
+		return view + p.spinner.View()
+	}
+
+	view += p.table.View()
+	switch {
+	case p.err != nil:
+		view += fmt.Sprintf("\nFailed to fetch issues: %v", p.err)
+	case p.loading:
+		view += fmt.Sprintf("\n%s Loading issues (%d of %d)", p.spinner.View(), len(p.items), p.total)
+	}
+	return view
+}
+
+// refreshTable rebuilds the table rows and columns from the items
+func (p *Panel) refreshTable() {
+	now := time.Now()
+	titles := []string{"ID", "Summary", "Component", "Modified", "Affects"}
+	if p.isrs != nil {
+		titles = append(titles, "ISR")
+	}
+	if p.outstanding {
+		titles = append(titles, "Outstanding")
+	}
+	lengths := make([]int, len(titles))
+	for c, title := range titles {
+		lengths[c] = len(title)
+	}
+	var rows []table.Row
+	for _, item := range p.visible() {
+		var affects []string
+		for _, version := range item.Fields.AffectsVersions {
+			affects = append(affects, version.Name)
+		}
+		var component string
+		if len(item.Fields.Components) > 0 {
+			component = item.Fields.Components[0].Name
+		}
+		row := table.Row{
+			item.Key,
+			item.Fields.Summary,
+			component,
+			p.timestamps.Since(time.Time(item.Fields.Updated), now),
+			strings.Join(affects, "|"),
+		}
+		if p.isrs != nil {
+			row = append(row, p.isrs[item.Key].format(p.timestamps, now))
+		}
+		if p.outstanding {
+			row = append(row, blockerflow.FormatOutstanding(time.Time(item.Fields.Created), now))
+		}
+		for c := range lengths {
+			if length := len(row[c]); length > lengths[c] {
+				lengths[c] = min(length, 75)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	var columns []table.Column
+	for c, title := range titles {
+		columns = append(columns, table.Column{Width: lengths[c], Title: title})
+	}
+	p.table.SetColumns(columns)
+	p.table.SetRows(rows)
+	p.table.SetHeight(min(10, len(rows)+2))
+}
+
+// fetchMissingIsrs marks all items without a known ISR as being fetched and returns commands that fetch them
+func (p Panel) fetchMissingIsrs(client Client) []tea.Cmd {
+	if p.isrs == nil {
+		return nil
+	}
+	var cmds []tea.Cmd
+	for _, item := range p.items {
+		if _, ok := p.isrs[item.Key]; ok {
+			continue
+		}
+		p.isrs[item.Key] = isrInfo{}
+		cmds = append(cmds, fetchIsr(p.Name, item, p.bugProjects, client))
+	}
+	return cmds
+}
+
+// fetchIsr finds the impact statement request Spike linked to the given bug and fetches its current state
+func fetchIsr(panel string, bug jira.Issue, bugProjects []string, client Client) tea.Cmd {
+	return func() tea.Msg {
+		msg := IsrMsg{panel: panel, bug: bug.Key, isr: isrInfo{fetched: true}}
+		for _, link := range bug.Fields.IssueLinks {
+			for _, linked := range []*jira.Issue{link.OutwardIssue, link.InwardIssue} {
+				if !blockerflow.IsImpactStatementRequestCandidate(linked, bugProjects) {
+					continue
+				}
+				isr, err := client.GetIssue(linked.Key)
+				if err != nil {
+					msg.isr.err = err
+					return msg
+				}
+				msg.isr.key = isr.Key
+				msg.isr.status = isr.Fields.Status.Name
+				msg.isr.updated = time.Time(isr.Fields.Updated)
+				msg.isr.created = time.Time(isr.Fields.Created)
+				msg.isr.done = isDone(*isr)
+				return msg
+			}
+		}
+		return msg
+	}
+}
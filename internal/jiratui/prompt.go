@@ -0,0 +1,89 @@
+package jiratui
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+var (
+	// ErrNotInteractive is returned by Ask when stdin is not a terminal
+	ErrNotInteractive = errors.New("cannot ask for input: stdin is not a terminal")
+	// ErrCancelled is returned by Ask when the user cancelled the prompt
+	ErrCancelled = errors.New("cancelled")
+)
+
+// Interactive returns true when the user can be asked for input
+func Interactive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// Run runs the terminal UI program with logging silenced, because log lines written while the program
+// owns the terminal garble its output. Logging is restored when the program exits.
+func Run(model tea.Model) (tea.Model, error) {
+	out := logrus.StandardLogger().Out
+	logrus.SetOutput(io.Discard)
+	defer logrus.SetOutput(out)
+	return tea.NewProgram(model).Run()
+}
+
+// prompt asks for a single line of text outside of a terminal UI
+type prompt struct {
+	question  string
+	input     textinput.Model
+	done      bool
+	cancelled bool
+}
+
+func (p prompt) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (p prompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "enter":
+			p.done = true
+			return p, tea.Quit
+		case "esc", "ctrl+c":
+			p.cancelled = true
+			return p, tea.Quit
+		}
+	}
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	return p, cmd
+}
+
+func (p prompt) View() string {
+	if p.done || p.cancelled {
+		return p.question + " " + p.input.Value() + "\n"
+	}
+	return p.question + " " + p.input.View() + "\n(enter to confirm, esc to cancel)\n"
+}
+
+// Ask shows the question with an editable answer pre-filled with initial and returns the answer the
+// user confirmed. It returns ErrNotInteractive when stdin is not a terminal and ErrCancelled when
+// the user cancelled the prompt.
+func Ask(question, initial string) (string, error) {
+	if !Interactive() {
+		return "", ErrNotInteractive
+	}
+	input := textinput.New()
+	input.SetValue(initial)
+	input.Focus()
+
+	final, err := Run(prompt{question: question, input: input})
+	if err != nil {
+		return "", err
+	}
+	if p := final.(prompt); !p.cancelled {
+		return p.input.Value(), nil
+	}
+	return "", ErrCancelled
+}
@@ -0,0 +1,65 @@
+package jirautil
+
+import (
+	"github.com/andygrunwald/go-jira"
+	prowjira "sigs.k8s.io/prow/pkg/jira"
+)
+
+// Client is the prow Jira client extended with the calls the tools need but the prow client does not
+// provide. Code that must honor the read-only mode uses only these methods and never the raw go-jira
+// client returned by JiraClient(), so that ReadOnly can guard every call.
+type Client interface {
+	prowjira.Client
+
+	// GetSelf returns the user associated with the client
+	GetSelf() (*jira.User, error)
+	// GetIssueWithChangelog returns the issue together with its changelog
+	GetIssueWithChangelog(key string) (*jira.Issue, error)
+	// GetProjectMetadata returns the (cached) metadata of the project with the given key
+	GetProjectMetadata(key string) (*ProjectMetadata, error)
+	// DeleteComment deletes the comment with the given ID from the issue
+	DeleteComment(issueID, commentID string) error
+	// DeleteIssue deletes the issue with the given key
+	DeleteIssue(key string) error
+}
+
+// Extend returns the client extended with the additional calls implemented over its raw go-jira client
+func Extend(client prowjira.Client) Client {
+	return &extendedClient{Client: client}
+}
+
+type extendedClient struct {
+	prowjira.Client
+}
+
+func (c *extendedClient) GetSelf() (*jira.User, error) {
+	user, response, err := c.JiraClient().User.GetSelf()
+	if err != nil {
+		return nil, prowjira.HandleJiraError(response, err)
+	}
+	return user, nil
+}
+
+func (c *extendedClient) GetIssueWithChangelog(key string) (*jira.Issue, error) {
+	issue, response, err := c.JiraClient().Issue.Get(key, &jira.GetQueryOptions{Expand: "changelog"})
+	if err != nil {
+		return nil, prowjira.HandleJiraError(response, err)
+	}
+	return issue, nil
+}
+
+func (c *extendedClient) GetProjectMetadata(key string) (*ProjectMetadata, error) {
+	return GetProjectMetadata(c.JiraClient(), key)
+}
+
+func (c *extendedClient) DeleteComment(issueID, commentID string) error {
+	return c.JiraClient().Issue.DeleteComment(issueID, commentID)
+}
+
+func (c *extendedClient) DeleteIssue(key string) error {
+	response, err := c.JiraClient().Issue.Delete(key)
+	if err != nil {
+		return prowjira.HandleJiraError(response, err)
+	}
+	return nil
+}
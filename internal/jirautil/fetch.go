@@ -0,0 +1,122 @@
+package jirautil
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	prowjira "sigs.k8s.io/prow/pkg/jira"
+)
+
+// IssueGetter is the subset of the Jira client needed to obtain issues
+type IssueGetter interface {
+	GetIssue(key string) (*jira.Issue, error)
+}
+
+// Fetcher runs Jira requests with bounded concurrency. The requests Jira still rejects as rate limited
+// (HTTP 429) after the retries of the underlying HTTP client are retried again with exponential backoff.
+type Fetcher struct {
+	// Concurrency is the maximum number of requests in flight; values below 1 mean 1
+	Concurrency int
+	// Retries is the number of retries of a rate limited request
+	Retries int
+	// Backoff is the delay before the first retry, doubled with every further retry
+	Backoff time.Duration
+}
+
+// DefaultFetcher is the Fetcher used by the tools unless they need a different one
+var DefaultFetcher = Fetcher{Concurrency: 8, Retries: 5, Backoff: 2 * time.Second}
+
+// isRateLimited returns true if the error of a Jira request means the request was rate limited
+func isRateLimited(err error) bool {
+	return prowjira.JiraErrorStatusCode(err) == http.StatusTooManyRequests || strings.Contains(err.Error(), "Status code: 429")
+}
+
+// retry calls fn until it succeeds, fails with an error other than rate limiting, runs out of retries
+// or the context is cancelled
+func (f Fetcher) retry(ctx context.Context, fn func() error) error {
+	backoff := f.Backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= f.Retries || !isRateLimited(err) {
+			return err
+		}
+		logrus.WithError(err).Debugf("Jira request rate limited, retrying in %s", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Run calls fn for every index in [0, n), with at most Concurrency calls running at once, and returns
+// the error of the call with the lowest index that failed. Calls that did not start yet when a call
+// fails or the context is cancelled are skipped; the context error is returned in the latter case.
+func (f Fetcher) Run(ctx context.Context, n int, fn func(i int) error) error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, max(f.Concurrency, 1))
+	var failed sync.Once
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+start:
+	for i := range n {
+		select {
+		case <-done:
+			break start
+		case <-ctx.Done():
+			break start
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if errs[i] = f.retry(ctx, func() error { return fn(i) }); errs[i] != nil {
+				failed.Do(func() { close(done) })
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// GetIssues obtains the issues with the given keys, returned in the order of the keys
+func (f Fetcher) GetIssues(ctx context.Context, client IssueGetter, keys []string) ([]*jira.Issue, error) {
+	issues := make([]*jira.Issue, len(keys))
+	err := f.Run(ctx, len(keys), func(i int) error {
+		issue, err := client.GetIssue(keys[i])
+		issues[i] = issue
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// SearchAll returns all issues matching each of the queries, in the order of the queries
+func (f Fetcher) SearchAll(ctx context.Context, client Searcher, queries []string) ([][]jira.Issue, error) {
+	results := make([][]jira.Issue, len(queries))
+	err := f.Run(ctx, len(queries), func(i int) error {
+		issues, err := SearchAll(ctx, client, queries[i])
+		results[i] = issues
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
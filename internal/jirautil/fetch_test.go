@@ -0,0 +1,93 @@
+package jirautil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	prowjira "sigs.k8s.io/prow/pkg/jira"
+)
+
+type fakeGetter struct {
+	lock     sync.Mutex
+	inFlight int
+	peak     int
+	// limited is the number of requests rejected as rate limited before the requests succeed
+	limited atomic.Int32
+}
+
+func (g *fakeGetter) GetIssue(key string) (*jira.Issue, error) {
+	g.lock.Lock()
+	g.inFlight++
+	g.peak = max(g.peak, g.inFlight)
+	g.lock.Unlock()
+	defer func() {
+		g.lock.Lock()
+		g.inFlight--
+		g.lock.Unlock()
+	}()
+
+	time.Sleep(time.Millisecond)
+	if key == "BROKEN-1" {
+		return nil, errors.New("broken")
+	}
+	if g.limited.Add(-1) >= 0 {
+		return nil, &prowjira.JiraError{StatusCode: 429}
+	}
+	return &jira.Issue{Key: key}, nil
+}
+
+func TestFetcherGetIssues(t *testing.T) {
+	var keys []string
+	for i := range 20 {
+		keys = append(keys, fmt.Sprintf("OCPBUGS-%d", i))
+	}
+	getter := &fakeGetter{}
+	getter.limited.Store(3)
+
+	issues, err := Fetcher{Concurrency: 4, Retries: 3, Backoff: time.Millisecond}.GetIssues(context.Background(), getter, keys)
+	if err != nil {
+		t.Fatalf("GetIssues failed: %v", err)
+	}
+	for i, issue := range issues {
+		if issue.Key != keys[i] {
+			t.Errorf("expected issue %d to be %s, got %s", i, keys[i], issue.Key)
+		}
+	}
+	if getter.peak > 4 {
+		t.Errorf("expected at most 4 requests in flight, got %d", getter.peak)
+	}
+}
+
+func TestFetcherGetIssuesFails(t *testing.T) {
+	getter := &fakeGetter{}
+	if _, err := (Fetcher{Concurrency: 2}).GetIssues(context.Background(), getter, []string{"OCPBUGS-1", "BROKEN-1", "OCPBUGS-2"}); err == nil || err.Error() != "broken" {
+		t.Errorf("expected the error of the failed request, got %v", err)
+	}
+
+	getter.limited.Store(10)
+	if _, err := (Fetcher{Concurrency: 1, Retries: 2, Backoff: time.Millisecond}).GetIssues(context.Background(), getter, []string{"OCPBUGS-1"}); !isRateLimited(err) {
+		t.Errorf("expected a rate limited error after the retries ran out, got %v", err)
+	}
+}
+
+func TestFetcherGetIssuesCancelled(t *testing.T) {
+	getter := &fakeGetter{}
+	getter.limited.Store(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	started := time.Now()
+	_, err := (Fetcher{Concurrency: 1, Retries: 5, Backoff: time.Hour}).GetIssues(ctx, getter, []string{"OCPBUGS-1", "OCPBUGS-2"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected a cancelled fetch to return without backing off, took %s", elapsed)
+	}
+}
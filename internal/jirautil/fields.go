@@ -0,0 +1,60 @@
+package jirautil
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// Stolen from openshift-eng/jira-lifecycle-plugin
+const (
+	TargetVersionField    = "customfield_12319940"
+	TargetVersionFieldOld = "customfield_12323140"
+
+	// EpicLinkField holds the key of the epic the issue belongs to
+	EpicLinkField = "customfield_12311140"
+)
+
+// GetUnknownField will attempt to get the specified field from the Unknowns struct and unmarshal
+// the value into the provided function. If the field is not set, the first return value of this
+// function will return false.
+func GetUnknownField(field string, issue *jira.Issue, fn func() interface{}) (bool, error) {
+	obj := fn()
+	if issue.Fields == nil || issue.Fields.Unknowns == nil {
+		return false, nil
+	}
+	unknownField, ok := issue.Fields.Unknowns[field]
+	if !ok {
+		return false, nil
+	}
+	bytes, err := json.Marshal(unknownField)
+	if err != nil {
+		return true, fmt.Errorf("failed to process the custom field %s. Error : %v", field, err)
+	}
+	if err := json.Unmarshal(bytes, obj); err != nil {
+		return true, fmt.Errorf("failed to unmarshal the json to struct for %s. Error: %v", field, err)
+	}
+	return true, nil
+}
+
+// GetIssueTargetVersion returns the Target Version/s of the issue, read from the current or the old
+// custom field
+func GetIssueTargetVersion(issue *jira.Issue) ([]*jira.Version, error) {
+	var obj *[]*jira.Version
+	isSet, err := GetUnknownField(TargetVersionField, issue, func() interface{} {
+		obj = &[]*jira.Version{{}}
+		return obj
+	})
+	if isSet && obj != nil && *obj != nil {
+		return *obj, err
+	}
+	isSet, err = GetUnknownField(TargetVersionFieldOld, issue, func() interface{} {
+		obj = &[]*jira.Version{{}}
+		return obj
+	})
+	if !isSet {
+		return nil, err
+	}
+	return *obj, err
+}
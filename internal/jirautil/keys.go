@@ -0,0 +1,25 @@
+package jirautil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultBugProject is the Jira project of the OpenShift bugs
+const DefaultBugProject = "OCPBUGS"
+
+// InProjects returns true if the card with the given key belongs to one of the projects
+func InProjects(key string, projects []string) bool {
+	project, _, _ := strings.Cut(key, "-")
+	for _, candidate := range projects {
+		if strings.EqualFold(project, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// Key returns the key of the card with the given number in the project
+func Key(project string, number int) string {
+	return fmt.Sprintf("%s-%d", project, number)
+}
@@ -0,0 +1,208 @@
+package jirautil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/petr-muller/ota/internal/config"
+)
+
+// metadataMaxAge is how long cached project metadata is used before it is fetched again
+const metadataMaxAge = 24 * time.Hour
+
+// ProjectMetadata describes what can be created in a Jira project
+type ProjectMetadata struct {
+	Key            string   `json:"key"`
+	Name           string   `json:"name"`
+	IssueTypes     []string `json:"issueTypes"`
+	Components     []string `json:"components"`
+	SecurityLevels []string `json:"securityLevels"`
+	Priorities     []string `json:"priorities"`
+	Versions       []string `json:"versions"`
+	// Fields are the IDs of the fields that can be set when creating an issue, by issue type
+	Fields  map[string][]string `json:"fields"`
+	Fetched time.Time           `json:"fetched"`
+}
+
+// ErrNoSuchProject is returned when the project does not exist or is not visible to the user
+var ErrNoSuchProject = errors.New("no such project")
+
+// InvalidValueError is returned when the project does not accept a value of an issue field
+type InvalidValueError struct {
+	Project string
+	// Field is the name of the field in the message, like "issue type"
+	Field string
+	Value string
+	Valid []string
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("project %s has no %s %q (valid: %s)", e.Project, e.Field, e.Value, strings.Join(e.Valid, ", "))
+}
+
+// GetProjectMetadata returns the metadata of the project with the given key. Metadata fetched in the
+// last day is served from the cache in the ota data directory.
+func GetProjectMetadata(client *jira.Client, key string) (*ProjectMetadata, error) {
+	key = strings.ToUpper(key)
+	path, err := metadataPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, err := os.ReadFile(path); err == nil {
+		var cached ProjectMetadata
+		if err := json.Unmarshal(raw, &cached); err == nil && time.Since(cached.Fetched) < metadataMaxAge {
+			return &cached, nil
+		}
+	}
+
+	metadata, err := fetchProjectMetadata(client, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := saveMetadata(path, metadata); err != nil {
+		logrus.WithError(err).Warnf("Cannot cache metadata of project %s", key)
+	}
+	return metadata, nil
+}
+
+func metadataPath(key string) (string, error) {
+	dataDir, err := config.OtaDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "jira", "projects", key+".json"), nil
+}
+
+func saveMetadata(path string, metadata *ProjectMetadata) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0644)
+}
+
+func fetchProjectMetadata(client *jira.Client, key string) (*ProjectMetadata, error) {
+	logrus.Infof("Obtaining metadata of project %s", key)
+	project, resp, err := client.Project.Get(key)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%s: %w", key, ErrNoSuchProject)
+		}
+		return nil, fmt.Errorf("cannot get project %s: %w", key, err)
+	}
+
+	metadata := &ProjectMetadata{Key: project.Key, Name: project.Name, Fetched: time.Now()}
+	for _, issueType := range project.IssueTypes {
+		metadata.IssueTypes = append(metadata.IssueTypes, issueType.Name)
+	}
+	for _, component := range project.Components {
+		metadata.Components = append(metadata.Components, component.Name)
+	}
+	for _, version := range project.Versions {
+		metadata.Versions = append(metadata.Versions, version.Name)
+	}
+
+	// Security levels and priorities are only exposed through the create metadata, and security levels
+	// only to users who can set them
+	meta, _, err := client.Issue.GetCreateMetaWithOptions(&jira.GetQueryOptions{ProjectKeys: key, Expand: "projects.issuetypes.fields"})
+	if err != nil {
+		logrus.WithError(err).Warnf("Cannot obtain security levels and priorities of project %s", key)
+		return metadata, nil
+	}
+	levels, priorities := sets.New[string](), sets.New[string]()
+	metadata.Fields = map[string][]string{}
+	for _, metaProject := range meta.Projects {
+		for _, issueType := range metaProject.IssueTypes {
+			levels.Insert(allowedValues(issueType.Fields, "security")...)
+			priorities.Insert(allowedValues(issueType.Fields, "priority")...)
+			metadata.Fields[issueType.Name] = sets.List(sets.KeySet(issueType.Fields))
+		}
+	}
+	metadata.SecurityLevels = sets.List(levels)
+	metadata.Priorities = sets.List(priorities)
+	return metadata, nil
+}
+
+// allowedValues returns the names of the values allowed for the field in the create metadata fields
+func allowedValues(fields map[string]interface{}, field string) []string {
+	meta, ok := fields[field].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var names []string
+	allowed, _ := meta["allowedValues"].([]interface{})
+	for _, value := range allowed {
+		if named, ok := value.(map[string]interface{}); ok {
+			if name, ok := named["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// ValidateIssueType returns an InvalidValueError listing the valid issue types when the project has no issue type with the given name
+func (m *ProjectMetadata) ValidateIssueType(name string) error {
+	for _, issueType := range m.IssueTypes {
+		if strings.EqualFold(issueType, name) {
+			return nil
+		}
+	}
+	return &InvalidValueError{Project: m.Key, Field: "issue type", Value: name, Valid: m.IssueTypes}
+}
+
+// ValidateComponent returns an InvalidValueError listing the valid components when the project has no component with the given name
+func (m *ProjectMetadata) ValidateComponent(name string) error {
+	for _, component := range m.Components {
+		if component == name {
+			return nil
+		}
+	}
+	return &InvalidValueError{Project: m.Key, Field: "component", Value: name, Valid: m.Components}
+}
+
+// HasField returns true when the field with the given ID can be set when creating an issue of the type.
+// It returns false when the fields are not known, like in the metadata cached before they were recorded.
+func (m *ProjectMetadata) HasField(issueType, field string) bool {
+	for name, fields := range m.Fields {
+		if strings.EqualFold(name, issueType) {
+			return sets.New[string](fields...).Has(field)
+		}
+	}
+	return false
+}
+
+// HasVersion returns true when the project has the version with the given name
+func (m *ProjectMetadata) HasVersion(name string) bool {
+	return sets.New[string](m.Versions...).Has(name)
+}
+
+// ValidatePriority returns an InvalidValueError listing the valid priorities when the project does not
+// accept the priority with the given name. Any priority passes when the priorities are not known, like
+// in the metadata cached before they were recorded.
+func (m *ProjectMetadata) ValidatePriority(name string) error {
+	if len(m.Priorities) == 0 {
+		return nil
+	}
+	for _, priority := range m.Priorities {
+		if strings.EqualFold(priority, name) {
+			return nil
+		}
+	}
+	return &InvalidValueError{Project: m.Key, Field: "priority", Value: name, Valid: m.Priorities}
+}
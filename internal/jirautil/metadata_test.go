@@ -0,0 +1,64 @@
+package jirautil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAllowedValues(t *testing.T) {
+	fields := map[string]interface{}{
+		"priority": map[string]interface{}{
+			"allowedValues": []interface{}{
+				map[string]interface{}{"name": "Critical"},
+				map[string]interface{}{"id": "10"},
+				map[string]interface{}{"name": "Normal"},
+			},
+		},
+		"summary": map[string]interface{}{"required": true},
+	}
+
+	if diff := cmp.Diff([]string{"Critical", "Normal"}, allowedValues(fields, "priority")); diff != "" {
+		t.Errorf("unexpected priorities (-want +got):\n%s", diff)
+	}
+	if values := allowedValues(fields, "summary"); values != nil {
+		t.Errorf("expected no values of a field without allowed values, got %v", values)
+	}
+	if values := allowedValues(fields, "security"); values != nil {
+		t.Errorf("expected no values of a missing field, got %v", values)
+	}
+}
+
+func TestValidatePriority(t *testing.T) {
+	metadata := &ProjectMetadata{Key: "MCO", Priorities: []string{"Critical", "Normal"}}
+	if err := metadata.ValidatePriority("critical"); err != nil {
+		t.Errorf("expected priorities to match case-insensitively, got %v", err)
+	}
+
+	err := metadata.ValidatePriority("Blocker")
+	var invalid *InvalidValueError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected an InvalidValueError, got %v", err)
+	}
+	if diff := cmp.Diff(&InvalidValueError{Project: "MCO", Field: "priority", Value: "Blocker", Valid: []string{"Critical", "Normal"}}, invalid); diff != "" {
+		t.Errorf("unexpected error (-want +got):\n%s", diff)
+	}
+
+	if err := (&ProjectMetadata{Key: "MCO"}).ValidatePriority("Blocker"); err != nil {
+		t.Errorf("expected any priority to pass when the priorities are not known, got %v", err)
+	}
+}
+
+func TestHasField(t *testing.T) {
+	metadata := &ProjectMetadata{Key: "MCO", Fields: map[string][]string{"Spike": {"summary", TargetVersionField}}}
+	if !metadata.HasField("spike", TargetVersionField) {
+		t.Errorf("expected Spikes to have the Target Version field")
+	}
+	if metadata.HasField("Spike", "versions") {
+		t.Errorf("expected Spikes not to have the Affects Version/s field")
+	}
+	if metadata.HasField("Bug", "summary") {
+		t.Errorf("expected no fields of an unknown issue type")
+	}
+}
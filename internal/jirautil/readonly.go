@@ -0,0 +1,92 @@
+package jirautil
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	prowjira "sigs.k8s.io/prow/pkg/jira"
+)
+
+// ErrReadOnly is returned by all mutating methods of a read-only client
+var ErrReadOnly = errors.New("jira client is in read-only mode")
+
+// ReadOnly wraps the client so that all methods that would change anything in Jira fail with ErrReadOnly
+// without sending any request. The raw go-jira client returned by JiraClient() cannot be guarded, so
+// callers that change anything must go through the methods of Client.
+func ReadOnly(client Client) Client {
+	return &readOnlyClient{Client: client}
+}
+
+type readOnlyClient struct {
+	Client
+}
+
+func refuse(action string) error {
+	return fmt.Errorf("refusing to %s: %w", action, ErrReadOnly)
+}
+
+func (c *readOnlyClient) UpdateIssue(issue *jira.Issue) (*jira.Issue, error) {
+	return nil, refuse("update issue " + issue.Key)
+}
+
+func (c *readOnlyClient) CreateIssue(*jira.Issue) (*jira.Issue, error) {
+	return nil, refuse("create issue")
+}
+
+func (c *readOnlyClient) CreateIssueLink(*jira.IssueLink) error {
+	return refuse("create issue link")
+}
+
+func (c *readOnlyClient) CloneIssue(issue *jira.Issue) (*jira.Issue, error) {
+	return nil, refuse("clone issue " + issue.Key)
+}
+
+func (c *readOnlyClient) DoTransition(issueID, _ string) error {
+	return refuse("transition issue " + issueID)
+}
+
+func (c *readOnlyClient) UpdateStatus(issueID, _ string) error {
+	return refuse("update status of issue " + issueID)
+}
+
+func (c *readOnlyClient) AddRemoteLink(id string, _ *jira.RemoteLink) (*jira.RemoteLink, error) {
+	return nil, refuse("add remote link to issue " + id)
+}
+
+func (c *readOnlyClient) UpdateRemoteLink(id string, _ *jira.RemoteLink) error {
+	return refuse("update remote link of issue " + id)
+}
+
+func (c *readOnlyClient) DeleteLink(id string) error {
+	return refuse("delete link " + id)
+}
+
+func (c *readOnlyClient) DeleteRemoteLink(issueID string, _ int) error {
+	return refuse("delete remote link of issue " + issueID)
+}
+
+func (c *readOnlyClient) DeleteRemoteLinkViaURL(issueID, _ string) (bool, error) {
+	return false, refuse("delete remote link of issue " + issueID)
+}
+
+func (c *readOnlyClient) AddComment(issueID string, _ *jira.Comment) (*jira.Comment, error) {
+	return nil, refuse("comment on issue " + issueID)
+}
+
+func (c *readOnlyClient) DeleteComment(issueID, commentID string) error {
+	return refuse("delete comment " + commentID + " of issue " + issueID)
+}
+
+func (c *readOnlyClient) DeleteIssue(key string) error {
+	return refuse("delete issue " + key)
+}
+
+func (c *readOnlyClient) ForPlugin(plugin string) prowjira.Client {
+	return ReadOnly(Extend(c.Client.ForPlugin(plugin)))
+}
+
+func (c *readOnlyClient) WithFields(fields logrus.Fields) prowjira.Client {
+	return ReadOnly(Extend(c.Client.WithFields(fields)))
+}
@@ -0,0 +1,50 @@
+// Package jirautil contains helpers for working with the Jira API that the prow Jira client does not provide
+package jirautil
+
+import (
+	"context"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+const (
+	// PageSize is the number of issues requested in a single search request
+	PageSize = 50
+)
+
+// Searcher is the subset of the Jira client needed to search for issues
+type Searcher interface {
+	SearchWithContext(context.Context, string, *jira.SearchOptions) ([]jira.Issue, *jira.Response, error)
+}
+
+// SearchPage returns a single page of up to PageSize issues matching the query, starting at startAt,
+// together with the total number of matching issues
+func SearchPage(ctx context.Context, client Searcher, jql string, startAt int) ([]jira.Issue, int, error) {
+	options := &jira.SearchOptions{StartAt: startAt, MaxResults: PageSize}
+	items, response, err := client.SearchWithContext(ctx, jql, options)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := startAt + len(items)
+	if response != nil {
+		total = max(total, response.Total)
+	}
+	return items, total, nil
+}
+
+// SearchAll returns all issues matching the query, paging through the results. Searches with nil
+// options silently truncate at Jira's default page size.
+func SearchAll(ctx context.Context, client Searcher, jql string) ([]jira.Issue, error) {
+	var all []jira.Issue
+	for {
+		items, total, err := SearchPage(ctx, client, jql, len(all))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) == 0 || len(all) >= total {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,333 @@
+// Package notify delivers notifications from the tools to user-configured sinks: Slack (incoming
+// webhooks or the Web API), desktop notifications, email and generic webhooks. Routing rules decide
+// which sinks receive notifications from which tool and about which events.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/petr-muller/ota/internal/config"
+	"github.com/petr-muller/ota/internal/httputil"
+	"github.com/petr-muller/ota/internal/platform"
+)
+
+const (
+	notifyFileName = "notify.yaml"
+
+	// defaultSlackTemplate renders the Slack messages of sinks without a template
+	defaultSlackTemplate = "*{{ .Title }}*\n{{ .Body }}{{ with .URL }}\n{{ . }}{{ end }}"
+)
+
+// slackPostMessageURL is the Slack Web API method posting a message into a channel
+var slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// SinkType is the kind of destination a sink delivers notifications to
+type SinkType string
+
+const (
+	SinkSlack   SinkType = "slack"
+	SinkDesktop SinkType = "desktop"
+	SinkEmail   SinkType = "email"
+	SinkWebhook SinkType = "webhook"
+)
+
+// Sink is a single configured notification destination
+type Sink struct {
+	Name string   `yaml:"name"`
+	Type SinkType `yaml:"type"`
+
+	// URL is the Slack incoming webhook or the generic webhook URL
+	URL string `yaml:"url,omitempty"`
+
+	// TokenFile holds a Slack bot token; Slack sinks with a token post through the Web API instead of
+	// an incoming webhook and must set Channel
+	TokenFile string `yaml:"tokenFile,omitempty"`
+	// Channel is the Slack channel to post into, e.g. #forum-ota. Incoming webhooks post into their
+	// own channel when it is not set.
+	Channel string `yaml:"channel,omitempty"`
+	// Template is a text/template rendering the Slack message from the Message
+	Template string `yaml:"template,omitempty"`
+
+	// SMTP is the host:port of the SMTP server for email sinks
+	SMTP         string   `yaml:"smtp,omitempty"`
+	From         string   `yaml:"from,omitempty"`
+	To           []string `yaml:"to,omitempty"`
+	Username     string   `yaml:"username,omitempty"`
+	PasswordFile string   `yaml:"passwordFile,omitempty"`
+}
+
+// Route sends the notifications from the listed sources about the listed events (all sources or events
+// when empty) to the listed sinks
+type Route struct {
+	Sources []string `yaml:"sources,omitempty"`
+	Events  []string `yaml:"events,omitempty"`
+	Sinks   []string `yaml:"sinks"`
+}
+
+func (r Route) matches(msg Message) bool {
+	if len(r.Sources) > 0 && !sets.New[string](r.Sources...).Has(msg.Source) {
+		return false
+	}
+	return len(r.Events) == 0 || sets.New[string](r.Events...).Has(msg.Event)
+}
+
+// Message is a single notification
+type Message struct {
+	// Source identifies the tool sending the notification, e.g. "dashboard"
+	Source string `json:"source"`
+	// Event identifies what happened, e.g. "impact-statement-requested"; empty for general notifications
+	Event string `json:"event,omitempty"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url,omitempty"`
+	// Data holds the details of the event for the message templates, e.g. the bug key
+	Data map[string]string `json:"data,omitempty"`
+	// HTML marks a Body that is an HTML document; email sinks send it as such
+	HTML bool `json:"html,omitempty"`
+}
+
+// Notifier routes messages to the configured sinks
+type Notifier struct {
+	Sinks  []Sink  `yaml:"sinks"`
+	Routes []Route `yaml:"routes"`
+}
+
+// Load reads the notification configuration from the ota config directory. A missing configuration
+// is not an error and results in a Notifier that drops all messages.
+func Load() (*Notifier, error) {
+	raw, path, err := config.ReadConfigFile(notifyFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Notifier{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read notification config %s: %w", path, err)
+	}
+
+	var n Notifier
+	if err := yaml.Unmarshal(raw, &n); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal notification config %s: %w", path, err)
+	}
+	if err := n.validate(); err != nil {
+		return nil, fmt.Errorf("invalid notification config %s: %w", path, err)
+	}
+	return &n, nil
+}
+
+func (n *Notifier) validate() error {
+	names := sets.New[string]()
+	for _, sink := range n.Sinks {
+		if sink.Name == "" {
+			return fmt.Errorf("sink without a name")
+		}
+		if names.Has(sink.Name) {
+			return fmt.Errorf("duplicate sink %q", sink.Name)
+		}
+		names.Insert(sink.Name)
+
+		switch sink.Type {
+		case SinkSlack:
+			if (sink.URL == "") == (sink.TokenFile == "") {
+				return fmt.Errorf("sink %q: exactly one of url or tokenFile must be set", sink.Name)
+			}
+			if sink.TokenFile != "" && sink.Channel == "" {
+				return fmt.Errorf("sink %q: channel must be set with tokenFile", sink.Name)
+			}
+			if _, err := sink.template(); err != nil {
+				return fmt.Errorf("sink %q: %w", sink.Name, err)
+			}
+		case SinkWebhook:
+			if sink.URL == "" {
+				return fmt.Errorf("sink %q: url must be set", sink.Name)
+			}
+		case SinkEmail:
+			if sink.SMTP == "" || sink.From == "" || len(sink.To) == 0 {
+				return fmt.Errorf("sink %q: smtp, from and to must be set", sink.Name)
+			}
+		case SinkDesktop:
+		default:
+			return fmt.Errorf("sink %q: unknown type %q", sink.Name, sink.Type)
+		}
+	}
+
+	for _, route := range n.Routes {
+		for _, sink := range route.Sinks {
+			if !names.Has(sink) {
+				return fmt.Errorf("route references unknown sink %q", sink)
+			}
+		}
+	}
+	return nil
+}
+
+// WithChannel returns a copy of the notifier whose Slack sinks post into the given channel
+func (n *Notifier) WithChannel(channel string) *Notifier {
+	overridden := *n
+	overridden.Sinks = nil
+	for _, sink := range n.Sinks {
+		if sink.Type == SinkSlack {
+			sink.Channel = channel
+		}
+		overridden.Sinks = append(overridden.Sinks, sink)
+	}
+	return &overridden
+}
+
+// Notify delivers the message to all sinks routed from its source. Delivery failures are logged and
+// the remaining sinks are still tried; the returned error joins all failures.
+func (n *Notifier) Notify(msg Message) error {
+	routed := sets.New[string]()
+	for _, route := range n.Routes {
+		if route.matches(msg) {
+			routed.Insert(route.Sinks...)
+		}
+	}
+
+	var errs []error
+	for _, sink := range n.Sinks {
+		if !routed.Has(sink.Name) {
+			continue
+		}
+		logrus.Debugf("Sending %s notification to %s", msg.Source, sink.Name)
+		if err := sink.send(msg); err != nil {
+			logrus.WithError(err).Warnf("Cannot send notification to %s", sink.Name)
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s Sink) send(msg Message) error {
+	switch s.Type {
+	case SinkSlack:
+		return s.slack(msg)
+	case SinkWebhook:
+		return post(s.URL, msg)
+	case SinkDesktop:
+		return platform.Notify(msg.Title, msg.Body)
+	case SinkEmail:
+		return s.mail(msg)
+	}
+	return fmt.Errorf("unknown sink type %q", s.Type)
+}
+
+func (s Sink) template() (*template.Template, error) {
+	text := s.Template
+	if text == "" {
+		text = defaultSlackTemplate
+	}
+	tmpl, err := template.New(s.Name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// slack posts the message rendered with the sink template either through the incoming webhook or,
+// with a token, through the Web API
+func (s Sink) slack(msg Message) error {
+	tmpl, err := s.template()
+	if err != nil {
+		return err
+	}
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, msg); err != nil {
+		return fmt.Errorf("cannot render message: %w", err)
+	}
+
+	payload := map[string]string{"text": text.String()}
+	if s.Channel != "" {
+		payload["channel"] = s.Channel
+	}
+	if s.TokenFile == "" {
+		return post(s.URL, payload)
+	}
+
+	token, err := os.ReadFile(s.TokenFile)
+	if err != nil {
+		return fmt.Errorf("cannot read Slack token: %w", err)
+	}
+	return postSlackAPI(strings.TrimSpace(string(token)), payload)
+}
+
+// postSlackAPI calls chat.postMessage; the Web API reports failures in the response body
+func postSlackAPI(token string, payload map[string]string) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot marshal notification: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, slackPostMessageURL, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := httputil.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", slackPostMessageURL, resp.Status)
+	}
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("cannot decode Slack response: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack rejected the message: %s", result.Error)
+	}
+	return nil
+}
+
+func post(url string, payload any) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot marshal notification: %w", err)
+	}
+	resp, err := httputil.Client().Post(url, "application/json", bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+func (s Sink) mail(msg Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		password, err := os.ReadFile(s.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("cannot read SMTP password: %w", err)
+		}
+		host := strings.Split(s.SMTP, ":")[0]
+		auth = smtp.PlainAuth("", s.Username, strings.TrimSpace(string(password)), host)
+	}
+
+	body, headers := msg.Body, ""
+	if msg.HTML {
+		headers = "MIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n"
+	} else if msg.URL != "" {
+		body += "\n\n" + msg.URL
+	}
+	content := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n%s\r\n%s\r\n", s.From, strings.Join(s.To, ", "), msg.Title, headers, body)
+	return smtp.SendMail(s.SMTP, auth, s.From, s.To, []byte(content))
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNotifySlack(t *testing.T) {
+	msg := Message{
+		Source: "workflow",
+		Event:  "impact-statement-requested",
+		Title:  "Impact statement requested for OCPBUGS-1",
+		Body:   "OCPBUGS-1: Nodes fail to drain",
+		URL:    "https://issues.redhat.com/browse/OCPBUGS-1",
+		Data:   map[string]string{"bug": "OCPBUGS-1", "impactStatementRequest": "MCO-2"},
+	}
+
+	testCases := []struct {
+		name     string
+		sink     Sink
+		response string
+
+		expected      map[string]string
+		expectedToken string
+		expectedError bool
+	}{
+		{
+			name:     "incoming webhook with the default template",
+			sink:     Sink{Name: "slack", Type: SinkSlack},
+			expected: map[string]string{"text": "*Impact statement requested for OCPBUGS-1*\nOCPBUGS-1: Nodes fail to drain\nhttps://issues.redhat.com/browse/OCPBUGS-1"},
+		},
+		{
+			name:     "incoming webhook with a channel and template",
+			sink:     Sink{Name: "slack", Type: SinkSlack, Channel: "#forum-ota", Template: "{{ .Data.bug }} needs {{ .Data.impactStatementRequest }} answered"},
+			expected: map[string]string{"text": "OCPBUGS-1 needs MCO-2 answered", "channel": "#forum-ota"},
+		},
+		{
+			name:          "web API",
+			sink:          Sink{Name: "slack", Type: SinkSlack, TokenFile: "token", Channel: "#forum-ota", Template: "{{ .Title }}"},
+			response:      `{"ok": true}`,
+			expected:      map[string]string{"text": "Impact statement requested for OCPBUGS-1", "channel": "#forum-ota"},
+			expectedToken: "Bearer xoxb-token",
+		},
+		{
+			name:          "web API failure",
+			sink:          Sink{Name: "slack", Type: SinkSlack, TokenFile: "token", Channel: "#nope", Template: "{{ .Title }}"},
+			response:      `{"ok": false, "error": "channel_not_found"}`,
+			expected:      map[string]string{"text": "Impact statement requested for OCPBUGS-1", "channel": "#nope"},
+			expectedToken: "Bearer xoxb-token",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if auth := r.Header.Get("Authorization"); auth != tc.expectedToken {
+					t.Errorf("unexpected Authorization header %q", auth)
+				}
+				var got map[string]string
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("cannot decode request: %v", err)
+				}
+				if diff := cmp.Diff(tc.expected, got); diff != "" {
+					t.Errorf("unexpected payload (-want +got):\n%s", diff)
+				}
+				_, _ = w.Write([]byte(tc.response))
+			}))
+			defer server.Close()
+
+			sink := tc.sink
+			if sink.TokenFile != "" {
+				sink.TokenFile = filepath.Join(t.TempDir(), sink.TokenFile)
+				if err := os.WriteFile(sink.TokenFile, []byte("xoxb-token\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				original := slackPostMessageURL
+				slackPostMessageURL = server.URL
+				defer func() { slackPostMessageURL = original }()
+			} else {
+				sink.URL = server.URL
+			}
+
+			n := &Notifier{Sinks: []Sink{sink}, Routes: []Route{{Events: []string{msg.Event}, Sinks: []string{sink.Name}}}}
+			if err := n.validate(); err != nil {
+				t.Fatalf("invalid notifier: %v", err)
+			}
+			err := n.Notify(msg)
+			if (err != nil) != tc.expectedError {
+				t.Errorf("expected error: %t, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRouteMatches(t *testing.T) {
+	testCases := []struct {
+		name  string
+		route Route
+
+		expected bool
+	}{
+		{name: "all sources and events", route: Route{}, expected: true},
+		{name: "matching source", route: Route{Sources: []string{"workflow"}}, expected: true},
+		{name: "other source", route: Route{Sources: []string{"dashboard"}}, expected: false},
+		{name: "matching event", route: Route{Sources: []string{"workflow"}, Events: []string{"known-issue-announced", "impact-statement-proposed"}}, expected: true},
+		{name: "other event", route: Route{Events: []string{"known-issue-announced"}}, expected: false},
+	}
+
+	msg := Message{Source: "workflow", Event: "impact-statement-proposed"}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.route.matches(msg); got != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
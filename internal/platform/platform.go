@@ -0,0 +1,58 @@
+// Package platform hides the differences between operating systems for the integrations that
+// the tools need from the desktop environment: opening URLs in a browser, putting text into
+// the clipboard and showing desktop notifications.
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrUnsupported is returned when the integration is not available on the current platform,
+// either because the OS is not supported at all or because the necessary helper program is
+// not installed. Callers are expected to degrade gracefully when they see it.
+var ErrUnsupported = errors.New("not supported on this platform")
+
+// OpenURL opens the given URL in the user's default browser. It does not wait for the browser
+// to exit.
+func OpenURL(url string) error {
+	cmd, err := resolve(openCommand(url))
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", url, err)
+	}
+	return cmd.Start()
+}
+
+// CopyToClipboard puts the given text into the system clipboard
+func CopyToClipboard(text string) error {
+	cmd, err := resolve(clipboardCommand())
+	if err != nil {
+		return fmt.Errorf("cannot copy to clipboard: %w", err)
+	}
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+// Notify shows a desktop notification with the given title and message
+func Notify(title, message string) error {
+	cmd, err := resolve(notifyCommand(title, message))
+	if err != nil {
+		return fmt.Errorf("cannot show notification: %w", err)
+	}
+	return cmd.Run()
+}
+
+// resolve returns the first command from candidates whose program is available, or ErrUnsupported
+// when there is none
+func resolve(candidates []*exec.Cmd) (*exec.Cmd, error) {
+	for _, cmd := range candidates {
+		if path, err := exec.LookPath(cmd.Args[0]); err == nil {
+			cmd.Path = path
+			cmd.Err = nil
+			return cmd, nil
+		}
+	}
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,20 @@
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+func openCommand(url string) []*exec.Cmd {
+	return []*exec.Cmd{exec.Command("open", url)}
+}
+
+func clipboardCommand() []*exec.Cmd {
+	return []*exec.Cmd{exec.Command("pbcopy")}
+}
+
+func notifyCommand(title, message string) []*exec.Cmd {
+	script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(message), strconv.Quote(title))
+	return []*exec.Cmd{exec.Command("osascript", "-e", script)}
+}
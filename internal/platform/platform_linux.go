@@ -0,0 +1,25 @@
+package platform
+
+import (
+	"os"
+	"os/exec"
+)
+
+func openCommand(url string) []*exec.Cmd {
+	return []*exec.Cmd{exec.Command("xdg-open", url)}
+}
+
+func clipboardCommand() []*exec.Cmd {
+	var candidates []*exec.Cmd
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, exec.Command("wl-copy"))
+	}
+	return append(candidates,
+		exec.Command("xclip", "-selection", "clipboard"),
+		exec.Command("xsel", "--clipboard", "--input"),
+	)
+}
+
+func notifyCommand(title, message string) []*exec.Cmd {
+	return []*exec.Cmd{exec.Command("notify-send", "--app-name=ota", title, message)}
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !windows
+
+package platform
+
+import "os/exec"
+
+func openCommand(string) []*exec.Cmd {
+	return nil
+}
+
+func clipboardCommand() []*exec.Cmd {
+	return nil
+}
+
+func notifyCommand(string, string) []*exec.Cmd {
+	return nil
+}
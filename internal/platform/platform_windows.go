@@ -0,0 +1,31 @@
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func openCommand(url string) []*exec.Cmd {
+	return []*exec.Cmd{exec.Command("rundll32", "url.dll,FileProtocolHandler", url)}
+}
+
+func clipboardCommand() []*exec.Cmd {
+	return []*exec.Cmd{exec.Command("clip")}
+}
+
+// notifyScript shows a balloon tip from a temporary tray icon, which works on a stock Windows
+// installation without any additional PowerShell modules
+const notifyScript = `Add-Type -AssemblyName System.Windows.Forms
+$n = New-Object System.Windows.Forms.NotifyIcon
+$n.Icon = [System.Drawing.SystemIcons]::Information
+$n.Visible = $true
+$n.ShowBalloonTip(10000, '%s', '%s', [System.Windows.Forms.ToolTipIcon]::None)
+Start-Sleep -Seconds 5
+$n.Dispose()`
+
+func notifyCommand(title, message string) []*exec.Cmd {
+	quote := strings.NewReplacer("'", "''")
+	script := fmt.Sprintf(notifyScript, quote.Replace(title), quote.Replace(message))
+	return []*exec.Cmd{exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)}
+}
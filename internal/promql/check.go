@@ -0,0 +1,190 @@
+// Package promql validates and evaluates the PromQL expressions used in matchingRules of conditionally blocked edges
+package promql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+
+	"github.com/petr-muller/ota/internal/graph"
+)
+
+// Diagnostic is a problem found in a PromQL expression. Line and Column are 1-based positions
+// inside the expression. When the expression was found in a YAML document, YAMLLine is the line
+// in the document where the expression starts. When it was found in a parsed blocked edge, Rule
+// is the 1-based index of its rule in matchingRules.
+type Diagnostic struct {
+	YAMLLine int
+	Rule     int
+	Line     int
+	Column   int
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	message := d.Message
+	if d.Line > 0 {
+		message = fmt.Sprintf("promql %d:%d: %s", d.Line, d.Column, d.Message)
+	}
+	switch {
+	case d.YAMLLine > 0:
+		return fmt.Sprintf("line %d: %s", d.YAMLLine, message)
+	case d.Rule > 0:
+		return fmt.Sprintf("matchingRules[%d]: %s", d.Rule-1, message)
+	}
+	return message
+}
+
+// Check parses the expression and returns diagnostics for all syntax errors found in it
+func Check(expr string) []Diagnostic {
+	_, err := parser.ParseExpr(expr)
+	if err == nil {
+		return nil
+	}
+
+	var parseErrors parser.ParseErrors
+	if !errors.As(err, &parseErrors) {
+		return []Diagnostic{{Line: 1, Column: 1, Message: err.Error()}}
+	}
+
+	var diagnostics []Diagnostic
+	for _, parseErr := range parseErrors {
+		line, column := position(expr, int(parseErr.PositionRange.Start))
+		diagnostics = append(diagnostics, Diagnostic{Line: line, Column: column, Message: parseErr.Err.Error()})
+	}
+	return diagnostics
+}
+
+// position converts a byte offset in s to a 1-based line and column
+func position(s string, offset int) (int, int) {
+	offset = max(0, min(offset, len(s)))
+	before := s[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndex(before, "\n")
+	return line, column
+}
+
+// offset converts a 1-based line and column in s to a byte offset, the inverse of position
+func offset(s string, line, column int) int {
+	start := 0
+	for ; line > 1; line-- {
+		newline := strings.Index(s[start:], "\n")
+		if newline < 0 {
+			break
+		}
+		start += newline + 1
+	}
+	return min(start+column-1, len(s))
+}
+
+// documentLine returns the 1-based line of the YAML document (split into lines) that holds the byte
+// offset of the scalar value. The lines of the value do not always match the lines of the document:
+// block scalar content starts on the line after the | or > indicator, and folded (>) and multi-line
+// flow scalars join their lines with spaces. The value is therefore matched line by line against the
+// document; when that fails (like for escape sequences in double-quoted strings), the line where the
+// matching stopped is returned.
+func documentLine(lines []string, node *yaml.Node, offset int) int {
+	first := node.Line
+	if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		first++
+	}
+	quoted := node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0
+
+	line, cursor := first, 0
+	for n := first; n <= len(lines); n++ {
+		text := lines[n-1]
+		if n == node.Line {
+			// flow scalars start after the key on the line of the node
+			text = text[min(node.Column-1, len(text)):]
+		}
+		text = strings.TrimSpace(text)
+		if quoted {
+			text = strings.Trim(text, `"'`)
+		}
+		if text == "" {
+			continue
+		}
+		start := strings.Index(node.Value[cursor:], text)
+		if start < 0 {
+			break
+		}
+		line, cursor = n, cursor+start+len(text)
+		// the newline or space joining the line with the next one still belongs to the line
+		if offset <= cursor {
+			return n
+		}
+	}
+	return line
+}
+
+// CheckEdge finds all promql expressions in matchingRules of the given blocked edge YAML document
+// and returns diagnostics for all syntax errors found in them
+func CheckEdge(raw []byte) ([]Diagnostic, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+
+	rules := mappingValue(document.Content[0], "matchingRules")
+	if rules == nil || rules.Kind != yaml.SequenceNode {
+		return nil, nil
+	}
+
+	lines := strings.Split(string(raw), "\n")
+	var diagnostics []Diagnostic
+	for _, rule := range rules.Content {
+		if ruleType := mappingValue(rule, "type"); ruleType == nil || ruleType.Value != "PromQL" {
+			continue
+		}
+		query := mappingValue(mappingValue(rule, "promql"), "promql")
+		if query == nil {
+			diagnostics = append(diagnostics, Diagnostic{YAMLLine: rule.Line, Message: "PromQL rule without a promql expression"})
+			continue
+		}
+
+		for _, diagnostic := range Check(query.Value) {
+			diagnostic.YAMLLine = documentLine(lines, query, offset(query.Value, diagnostic.Line, diagnostic.Column))
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics, nil
+}
+
+// CheckRules returns diagnostics for all syntax errors in the promql expressions of the PromQL rules
+// of a parsed blocked edge
+func CheckRules(rules []graph.PromQLRule) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i, rule := range rules {
+		if rule.Type != "PromQL" {
+			continue
+		}
+		if rule.PromQL.Query == "" {
+			diagnostics = append(diagnostics, Diagnostic{Rule: i + 1, Message: "PromQL rule without a promql expression"})
+			continue
+		}
+		for _, diagnostic := range Check(rule.PromQL.Query) {
+			diagnostic.Rule = i + 1
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics
+}
+
+// mappingValue returns the value node for the given key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
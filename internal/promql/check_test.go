@@ -0,0 +1,224 @@
+package promql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/petr-muller/ota/internal/graph"
+)
+
+func TestPosition(t *testing.T) {
+	expr := "group(up)\nor\n0 * group(foo)"
+	testCases := []struct {
+		offset int
+
+		line, column int
+	}{
+		{offset: 0, line: 1, column: 1},
+		{offset: 9, line: 1, column: 10},
+		{offset: 10, line: 2, column: 1},
+		{offset: 17, line: 3, column: 5},
+		{offset: len(expr), line: 3, column: 15},
+		{offset: len(expr) + 10, line: 3, column: 15},
+	}
+	for _, tc := range testCases {
+		line, column := position(expr, tc.offset)
+		if line != tc.line || column != tc.column {
+			t.Errorf("offset %d: expected %d:%d, got %d:%d", tc.offset, tc.line, tc.column, line, column)
+		}
+		if back := offset(expr, line, column); back != min(tc.offset, len(expr)) {
+			t.Errorf("%d:%d: expected offset %d, got %d", line, column, min(tc.offset, len(expr)), back)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		name string
+		expr string
+
+		expected []Diagnostic
+	}{
+		{
+			name: "valid",
+			expr: `group(cluster_infrastructure_provider{_id="",type="AWS"}) or 0 * group(cluster_version{_id=""})`,
+		},
+		{
+			name:     "error at the end",
+			expr:     "sum(up) +",
+			expected: []Diagnostic{{Line: 1, Column: 10, Message: "unexpected end of input"}},
+		},
+		{
+			name:     "error on a later line",
+			expr:     "group(up)\nor\n0 * group(foo{a=})",
+			expected: []Diagnostic{{Line: 3, Column: 17, Message: `unexpected "}" in label matching, expected string`}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, Check(tc.expr)); diff != "" {
+				t.Errorf("unexpected diagnostics (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// edgeHeader precedes the matchingRules in the blocked edges of TestCheckEdge, so their first rule
+// starts on line 7
+const edgeHeader = `to: 4.16.1
+from: 4\.15\..*
+url: https://issues.redhat.com/browse/OCPBUGS-1
+name: Risk
+message: Clusters on AWS may fail to update.
+matchingRules:
+`
+
+func TestCheckEdge(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rules string
+
+		expected []string
+	}{
+		{
+			name: "valid literal block",
+			rules: `- type: PromQL
+  promql:
+    promql: |
+      group(up)
+      or
+      0 * group(foo)
+`,
+		},
+		{
+			name: "literal block",
+			rules: `- type: PromQL
+  promql:
+    promql: |
+      group(up)
+      or
+      0 * group(foo{a=})
+`,
+			expected: []string{`line 12: promql 3:17: unexpected "}" in label matching, expected string`},
+		},
+		{
+			name: "literal block with strip chomping and an error on its first line",
+			rules: `- type: PromQL
+  promql:
+    promql: |-
+      group(up{a=})
+      or
+      0 * group(foo)
+`,
+			expected: []string{`line 10: promql 1:12: unexpected "}" in label matching, expected string`},
+		},
+		{
+			name: "literal block with blank and more indented lines",
+			rules: `- type: PromQL
+  promql:
+    promql: |
+      group(up)
+
+      or
+        0 * group(foo{a=})
+`,
+			expected: []string{`line 13: promql 4:19: unexpected "}" in label matching, expected string`},
+		},
+		{
+			name: "folded block joins the lines",
+			rules: `- type: PromQL
+  promql:
+    promql: >
+      group(up)
+      or
+      0 * group(foo{a=})
+`,
+			expected: []string{`line 12: promql 1:30: unexpected "}" in label matching, expected string`},
+		},
+		{
+			name: "inline string",
+			rules: `- type: PromQL
+  promql:
+    promql: group(up{a=})
+`,
+			expected: []string{`line 9: promql 1:12: unexpected "}" in label matching, expected string`},
+		},
+		{
+			name: "double-quoted string",
+			rules: `- type: PromQL
+  promql:
+    promql: "group(up{a=\"\"}) or group(foo{a=})"
+`,
+			expected: []string{`line 9: promql 1:32: unexpected "}" in label matching, expected string`},
+		},
+		{
+			name: "multi-line plain string",
+			rules: `- type: PromQL
+  promql:
+    promql: group(up)
+      or 0 * group(foo{a=})
+`,
+			expected: []string{`line 10: promql 1:30: unexpected "}" in label matching, expected string`},
+		},
+		{
+			name: "error in the second rule",
+			rules: `- type: Always
+- type: PromQL
+  promql:
+    promql: |-
+      sum(up) +
+`,
+			expected: []string{"line 11: promql 1:10: unexpected end of input"},
+		},
+		{
+			name: "rule without a promql expression",
+			rules: `- type: PromQL
+- type: PromQL
+  promql: {}
+`,
+			expected: []string{"line 7: PromQL rule without a promql expression", "line 8: PromQL rule without a promql expression"},
+		},
+		{
+			name: "other rule types are not checked",
+			rules: `- type: Always
+  promql:
+    promql: group(up{a=})
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diagnostics, err := CheckEdge([]byte(edgeHeader + tc.rules))
+			if err != nil {
+				t.Fatalf("CheckEdge failed: %v", err)
+			}
+			var formatted []string
+			for _, diagnostic := range diagnostics {
+				formatted = append(formatted, diagnostic.String())
+			}
+			if diff := cmp.Diff(tc.expected, formatted); diff != "" {
+				t.Errorf("unexpected diagnostics (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	rules := []graph.PromQLRule{
+		{Type: "Always"},
+		{Type: "PromQL"},
+		{Type: "PromQL", PromQL: graph.PromQLQuery{Query: "group(up)\nor\nsum(up) +"}},
+	}
+	expected := []string{
+		"matchingRules[1]: PromQL rule without a promql expression",
+		"matchingRules[2]: promql 3:10: unexpected end of input",
+	}
+	var formatted []string
+	for _, diagnostic := range CheckRules(rules) {
+		formatted = append(formatted, diagnostic.String())
+	}
+	if diff := cmp.Diff(expected, formatted); diff != "" {
+		t.Errorf("unexpected diagnostics (-want +got):\n%s", diff)
+	}
+}
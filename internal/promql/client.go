@@ -0,0 +1,154 @@
+package promql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/petr-muller/ota/internal/httputil"
+)
+
+// Client evaluates PromQL expressions with the HTTP API of a Prometheus-compatible endpoint, like
+// Thanos Querier in a cluster or a Telemetry proxy
+type Client struct {
+	URL string
+	// Token is sent as a bearer token when not empty
+	Token string
+}
+
+// NewClient returns a client querying the Prometheus HTTP API at the given URL
+func NewClient(endpoint, token string) *Client {
+	return &Client{URL: endpoint, Token: token}
+}
+
+// Sample is a single series of an instant vector
+type Sample struct {
+	Labels map[string]string
+	Value  string
+}
+
+// String formats the sample like Prometheus does: metric{label="value", ...} => value
+func (s Sample) String() string {
+	name := s.Labels["__name__"]
+	var labels []string
+	for label, value := range s.Labels {
+		if label != "__name__" {
+			labels = append(labels, fmt.Sprintf("%s=%q", label, value))
+		}
+	}
+	sort.Strings(labels)
+	return fmt.Sprintf("%s{%s} => %s", name, strings.Join(labels, ", "), s.Value)
+}
+
+// Result is the result of an instant query
+type Result struct {
+	// Type is the type of the result, like vector or scalar
+	Type string
+	// Samples hold the series of a vector result
+	Samples  []Sample
+	Warnings []string
+}
+
+type apiResponse struct {
+	Status    string   `json:"status"`
+	ErrorType string   `json:"errorType"`
+	Error     string   `json:"error"`
+	Warnings  []string `json:"warnings"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type apiSample struct {
+	Metric map[string]string `json:"metric"`
+	// Value is a [timestamp, "value"] pair
+	Value []interface{} `json:"value"`
+}
+
+// Query evaluates the expression as an instant query at the current time. Evaluation failures, like
+// a bad expression or a query timeout, are returned as errors with the message of the endpoint.
+func (c *Client) Query(ctx context.Context, expr string) (*Result, error) {
+	u, err := url.JoinPath(c.URL, "api", "v1", "query")
+	if err != nil {
+		return nil, fmt.Errorf("invalid Prometheus URL %s: %w", c.URL, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(url.Values{"query": {expr}}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := httputil.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query %s: %w", c.URL, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var response apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("%s returned %s with an undecodable body: %w", c.URL, resp.Status, err)
+	}
+	if response.Status != "success" {
+		return nil, fmt.Errorf("query failed with %s (%s): %s", resp.Status, response.ErrorType, response.Error)
+	}
+
+	result := &Result{Type: response.Data.ResultType, Warnings: response.Warnings}
+	if result.Type != "vector" {
+		return result, nil
+	}
+	var samples []apiSample
+	if err := json.Unmarshal(response.Data.Result, &samples); err != nil {
+		return nil, fmt.Errorf("cannot decode the vector result: %w", err)
+	}
+	for _, sample := range samples {
+		var value string
+		if len(sample.Value) == 2 {
+			value, _ = sample.Value[1].(string)
+		}
+		result.Samples = append(result.Samples, Sample{Labels: sample.Metric, Value: value})
+	}
+	return result, nil
+}
+
+// CheckResult returns the problems the cluster-version operator would have with the result of a
+// matchingRules query: it needs a vector with a single sample that is either 1 (the cluster is exposed)
+// or 0 (the cluster is not exposed). Every sample must also carry the expected labels.
+func CheckResult(result *Result, expectedLabels []string) []string {
+	if result.Type != "vector" {
+		return []string{fmt.Sprintf("the query returns a %s, not an instant vector", result.Type)}
+	}
+
+	var problems []string
+	if len(result.Samples) != 1 {
+		problems = append(problems, fmt.Sprintf("the query returns %d samples, the cluster-version operator needs exactly one", len(result.Samples)))
+	}
+	for _, sample := range result.Samples {
+		if sample.Value != "0" && sample.Value != "1" {
+			problems = append(problems, fmt.Sprintf("sample %s is neither 0 nor 1", sample))
+		}
+		for _, label := range expectedLabels {
+			if _, ok := sample.Labels[label]; !ok {
+				problems = append(problems, fmt.Sprintf("sample %s has no %s label", sample, label))
+			}
+		}
+	}
+	return problems
+}
+
+// ForCluster returns the expression restricted to the cluster with the given ID. The matchingRules
+// select the series without an _id label, which are the series of the cluster evaluating them; in
+// Telemetry, where every series carries the _id of its cluster, they must select the cluster's series.
+func ForCluster(expr, clusterID string) string {
+	return strings.ReplaceAll(expr, `_id=""`, fmt.Sprintf("_id=%q", clusterID))
+}
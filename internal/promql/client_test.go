@@ -0,0 +1,116 @@
+package promql
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("unexpected Authorization header %q", auth)
+		}
+		switch query := r.FormValue("query"); query {
+		case "up":
+			_, _ = w.Write([]byte(`{"status":"success","warnings":["partial response"],"data":{"resultType":"vector","result":[{"metric":{"__name__":"up","job":"a"},"value":[1700000000,"1"]}]}}`))
+		case "1":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1700000000,"1"]}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "secret")
+
+	result, err := client.Query(context.Background(), "up")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	expected := &Result{Type: "vector", Samples: []Sample{{Labels: map[string]string{"__name__": "up", "job": "a"}, Value: "1"}}, Warnings: []string{"partial response"}}
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("unexpected result (-want +got):\n%s", diff)
+	}
+
+	if result, err := client.Query(context.Background(), "1"); err != nil || result.Type != "scalar" {
+		t.Errorf("expected a scalar result, got %v (err=%v)", result, err)
+	}
+
+	if _, err := client.Query(context.Background(), "up{"); err == nil || !strings.Contains(err.Error(), "bad_data") {
+		t.Errorf("expected the endpoint error, got %v", err)
+	}
+}
+
+func TestCheckResult(t *testing.T) {
+	sample := func(value string, labels ...string) Sample {
+		s := Sample{Labels: map[string]string{}, Value: value}
+		for _, label := range labels {
+			s.Labels[label] = "x"
+		}
+		return s
+	}
+
+	testCases := []struct {
+		name           string
+		result         *Result
+		expectedLabels []string
+
+		expected []string
+	}{
+		{
+			name:   "exposed cluster",
+			result: &Result{Type: "vector", Samples: []Sample{sample("1", "type")}},
+		},
+		{
+			name:           "missing label",
+			result:         &Result{Type: "vector", Samples: []Sample{sample("0")}},
+			expectedLabels: []string{"type"},
+			expected:       []string{`sample {} => 0 has no type label`},
+		},
+		{
+			name:     "scalar",
+			result:   &Result{Type: "scalar"},
+			expected: []string{"the query returns a scalar, not an instant vector"},
+		},
+		{
+			name:   "no data",
+			result: &Result{Type: "vector"},
+			expected: []string{
+				"the query returns 0 samples, the cluster-version operator needs exactly one",
+			},
+		},
+		{
+			name:   "too many samples",
+			result: &Result{Type: "vector", Samples: []Sample{sample("1"), sample("2")}},
+			expected: []string{
+				"the query returns 2 samples, the cluster-version operator needs exactly one",
+				"sample {} => 2 is neither 0 nor 1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, CheckResult(tc.result, tc.expectedLabels)); diff != "" {
+				t.Errorf("unexpected problems (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestForCluster(t *testing.T) {
+	expr := `group(a{_id="",type="AWS"}) or 0 * group(b{_id=""})`
+	expected := `group(a{_id="c1",type="AWS"}) or 0 * group(b{_id="c1"})`
+	if got := ForCluster(expr, "c1"); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,213 @@
+package promql
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/util/annotations"
+	"gopkg.in/yaml.v3"
+)
+
+// insightsMetricsPath is the file in an Insights Operator archive holding the metrics of the cluster
+// in the Prometheus text format
+const insightsMetricsPath = "config/metrics"
+
+// Profile is a snapshot of the metrics of a cluster that matchingRules can be evaluated against
+type Profile struct {
+	Name string `yaml:"name"`
+	// Metrics hold the series in the Prometheus text format
+	Metrics string `yaml:"metrics"`
+
+	series []series
+}
+
+// series is a single series of the profile with its value
+type series struct {
+	labels labels.Labels
+	value  float64
+}
+
+// LoadProfile reads a cluster profile: either a YAML document with the name and the metrics of the
+// cluster, or an Insights Operator archive (.tar.gz) whose metrics are used. The name defaults to the
+// file name.
+func LoadProfile(profilePath string) (*Profile, error) {
+	raw, err := os.ReadFile(profilePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read cluster profile: %w", err)
+	}
+
+	var profile Profile
+	if bytes.HasPrefix(raw, []byte{0x1f, 0x8b}) {
+		if profile.Metrics, err = insightsMetrics(raw); err != nil {
+			return nil, fmt.Errorf("cannot read Insights archive %s: %w", profilePath, err)
+		}
+	} else if err := yaml.Unmarshal(raw, &profile); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal cluster profile %s: %w", profilePath, err)
+	}
+	if profile.Name == "" {
+		profile.Name = filepath.Base(profilePath)
+	}
+
+	if profile.series, err = parseSeries(profile.Metrics); err != nil {
+		return nil, fmt.Errorf("cannot parse metrics of cluster profile %s: %w", profilePath, err)
+	}
+	return &profile, nil
+}
+
+// insightsMetrics returns the metrics file from the gzipped Insights archive
+func insightsMetrics(raw []byte) (string, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return "", err
+	}
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("archive has no %s", insightsMetricsPath)
+		}
+		if err != nil {
+			return "", err
+		}
+		if path.Clean(header.Name) == insightsMetricsPath {
+			metrics, err := io.ReadAll(archive)
+			return string(metrics), err
+		}
+	}
+}
+
+// parseSeries converts the gauge, counter and untyped metrics in the Prometheus text format into series
+func parseSeries(metrics string) ([]series, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bufio.NewReader(strings.NewReader(metrics)))
+	if err != nil {
+		return nil, err
+	}
+
+	var all []series
+	for name, family := range families {
+		for _, metric := range family.GetMetric() {
+			var value float64
+			switch {
+			case metric.Gauge != nil:
+				value = metric.GetGauge().GetValue()
+			case metric.Counter != nil:
+				value = metric.GetCounter().GetValue()
+			case metric.Untyped != nil:
+				value = metric.GetUntyped().GetValue()
+			default:
+				continue
+			}
+			builder := labels.NewScratchBuilder(len(metric.GetLabel()) + 1)
+			builder.Add(labels.MetricName, name)
+			for _, label := range metric.GetLabel() {
+				builder.Add(label.GetName(), label.GetValue())
+			}
+			builder.Sort()
+			all = append(all, series{labels: builder.Labels(), value: value})
+		}
+	}
+	return all, nil
+}
+
+// Evaluate evaluates the expression over the profile metrics like the cluster would: the snapshot is
+// taken as the current state of the cluster
+func (p *Profile) Evaluate(ctx context.Context, expr string) (*Result, error) {
+	now := time.Now()
+	queryable := &storage.MockQueryable{MockQuerier: &storage.MockQuerier{
+		SelectMockFunction: func(_ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+			return p.selectSeries(now, matchers)
+		},
+	}}
+
+	engine := promql.NewEngine(promql.EngineOpts{MaxSamples: 1_000_000, Timeout: time.Minute, LookbackDelta: 5 * time.Minute})
+	query, err := engine.NewInstantQuery(ctx, queryable, nil, expr, now)
+	if err != nil {
+		return nil, err
+	}
+	defer query.Close()
+
+	evaluated := query.Exec(ctx)
+	if evaluated.Err != nil {
+		return nil, evaluated.Err
+	}
+	result := &Result{Type: string(evaluated.Value.Type())}
+	warnings, infos := evaluated.Warnings.AsStrings(expr, 0, 0)
+	for _, annotation := range append(warnings, infos...) {
+		result.Warnings = append(result.Warnings, annotation)
+	}
+	if vector, ok := evaluated.Value.(promql.Vector); ok {
+		for _, sample := range vector {
+			result.Samples = append(result.Samples, Sample{Labels: sample.Metric.Map(), Value: strconv.FormatFloat(sample.F, 'f', -1, 64)})
+		}
+	}
+	return result, nil
+}
+
+// selectSeries returns the profile series matching all matchers, with their sample at the given time
+func (p *Profile) selectSeries(at time.Time, matchers []*labels.Matcher) storage.SeriesSet {
+	var selected []storage.Series
+	for _, s := range p.series {
+		if matchesAll(s.labels, matchers) {
+			selected = append(selected, storage.NewListSeries(s.labels, []chunks.Sample{floatSample{t: at.UnixMilli(), f: s.value}}))
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return labels.Compare(selected[i].Labels(), selected[j].Labels()) < 0 })
+	return &seriesSet{series: selected, index: -1}
+}
+
+func matchesAll(series labels.Labels, matchers []*labels.Matcher) bool {
+	for _, matcher := range matchers {
+		if !matcher.Matches(series.Get(matcher.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// floatSample is a float sample of a series
+type floatSample struct {
+	t int64
+	f float64
+}
+
+func (s floatSample) T() int64                      { return s.t }
+func (s floatSample) F() float64                    { return s.f }
+func (s floatSample) H() *histogram.Histogram       { return nil }
+func (s floatSample) FH() *histogram.FloatHistogram { return nil }
+func (s floatSample) Type() chunkenc.ValueType      { return chunkenc.ValFloat }
+
+// seriesSet iterates over the selected series
+type seriesSet struct {
+	series []storage.Series
+	index  int
+}
+
+func (s *seriesSet) Next() bool {
+	s.index++
+	return s.index < len(s.series)
+}
+
+func (s *seriesSet) At() storage.Series                { return s.series[s.index] }
+func (s *seriesSet) Err() error                        { return nil }
+func (s *seriesSet) Warnings() annotations.Annotations { return nil }
@@ -0,0 +1,111 @@
+package promql
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const awsMetrics = `# TYPE cluster_infrastructure_provider gauge
+cluster_infrastructure_provider{region="us-east-1",type="AWS"} 1
+# TYPE cluster_version gauge
+cluster_version{type="current",version="4.15.3"} 1700000000
+`
+
+func TestProfileEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	profilePath := filepath.Join(dir, "aws.yaml")
+	if err := os.WriteFile(profilePath, []byte("name: AWS cluster\nmetrics: |\n  "+indent(awsMetrics)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := LoadProfile(profilePath)
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %v", err)
+	}
+	if profile.Name != "AWS cluster" {
+		t.Errorf("expected the name from the profile, got %q", profile.Name)
+	}
+
+	testCases := []struct {
+		name string
+		expr string
+
+		expected *Result
+	}{
+		{
+			name:     "matching platform",
+			expr:     Combine([]string{`group(cluster_infrastructure_provider{_id="",type="AWS"})`}),
+			expected: &Result{Type: "vector", Samples: []Sample{{Labels: map[string]string{}, Value: "1"}}},
+		},
+		{
+			name:     "other platform",
+			expr:     Combine([]string{`group(cluster_infrastructure_provider{_id="",type="GCP"})`}),
+			expected: &Result{Type: "vector", Samples: []Sample{{Labels: map[string]string{}, Value: "0"}}},
+		},
+		{
+			name:     "selected series",
+			expr:     `cluster_infrastructure_provider{type=~"AWS|GCP"}`,
+			expected: &Result{Type: "vector", Samples: []Sample{{Labels: map[string]string{"__name__": "cluster_infrastructure_provider", "region": "us-east-1", "type": "AWS"}, Value: "1"}}},
+		},
+		{
+			name:     "no data",
+			expr:     `group(cluster_feature_set{_id=""})`,
+			expected: &Result{Type: "vector"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := profile.Evaluate(context.Background(), tc.expr)
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadProfileFromInsightsArchive(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{"config/version": "{}", "config/metrics": awsMetrics} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	profilePath := filepath.Join(t.TempDir(), "insights.tar.gz")
+	if err := os.WriteFile(profilePath, archive.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := LoadProfile(profilePath)
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %v", err)
+	}
+	if profile.Name != "insights.tar.gz" || len(profile.series) != 2 {
+		t.Errorf("expected the two series of insights.tar.gz, got %d series of %s", len(profile.series), profile.Name)
+	}
+}
+
+// indent indents the lines after the first one for a YAML block scalar
+func indent(s string) string {
+	return string(bytes.ReplaceAll([]byte(s), []byte("\n"), []byte("\n  ")))
+}
@@ -0,0 +1,180 @@
+package promql
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/petr-muller/ota/internal/config"
+)
+
+const (
+	// libraryFileName is a file in the OTA config directory that replaces the default library of
+	// matchingRules snippets
+	libraryFileName = "promql-library.yaml"
+
+	// fallback makes the combined suggestion evaluate to 0 (not exposed) instead of no data for the
+	// clusters that match none of the snippets
+	fallback = `0 * group(cluster_version{_id=""})`
+)
+
+// Snippet is a curated PromQL expression matching the clusters with a property, like a platform,
+// proposed when an impact statement mentions any of its hints
+type Snippet struct {
+	Name string `yaml:"name"`
+	// Hints are case-insensitive regular expressions matched against the impact statement
+	Hints []string `yaml:"hints"`
+	// PromQL returns a result for the exposed clusters and no data for the others
+	PromQL string `yaml:"promql"`
+
+	hints []*regexp.Regexp
+}
+
+// Library is a list of curated matchingRules snippets
+type Library struct {
+	Snippets []Snippet `yaml:"snippets"`
+}
+
+// Suggestion is a snippet proposed for an impact statement, together with the text that mentioned it
+type Suggestion struct {
+	Snippet  Snippet
+	Evidence string
+}
+
+// LoadLibrary reads the snippet library from the OTA config directory, or returns the default library
+// when there is none
+func LoadLibrary() (*Library, error) {
+	raw, path, err := config.ReadConfigFile(libraryFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		raw, path, err = []byte(defaultLibrary), "default library", nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read PromQL library %s: %w", path, err)
+	}
+	return parseLibrary(raw, path)
+}
+
+func parseLibrary(raw []byte, path string) (*Library, error) {
+	var library Library
+	if err := yaml.Unmarshal(raw, &library); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal PromQL library %s: %w", path, err)
+	}
+	for i := range library.Snippets {
+		snippet := &library.Snippets[i]
+		if diagnostics := Check(snippet.PromQL); len(diagnostics) > 0 {
+			return nil, fmt.Errorf("invalid PromQL library %s: snippet %s: %s", path, snippet.Name, diagnostics[0])
+		}
+		for _, hint := range snippet.Hints {
+			re, err := regexp.Compile("(?i)" + hint)
+			if err != nil {
+				return nil, fmt.Errorf("invalid PromQL library %s: snippet %s: %w", path, snippet.Name, err)
+			}
+			snippet.hints = append(snippet.hints, re)
+		}
+	}
+	return &library, nil
+}
+
+// Suggest returns the snippets whose hints are mentioned in the text, in the library order
+func (l *Library) Suggest(text string) []Suggestion {
+	var suggestions []Suggestion
+	for _, snippet := range l.Snippets {
+		for _, hint := range snippet.hints {
+			if location := hint.FindStringIndex(text); location != nil {
+				suggestions = append(suggestions, Suggestion{Snippet: snippet, Evidence: evidence(text, location)})
+				break
+			}
+		}
+	}
+	return suggestions
+}
+
+// evidence returns the line of the text around the match
+func evidence(text string, location []int) string {
+	start := strings.LastIndex(text[:location[0]], "\n") + 1
+	end := len(text)
+	if newline := strings.Index(text[location[1]:], "\n"); newline >= 0 {
+		end = location[1] + newline
+	}
+	return strings.TrimSpace(text[start:end])
+}
+
+// monospaceRegexp matches the Jira markup of inline monospace text, also in the {{{}...{}}} form Jira
+// uses when the text starts or ends with a brace, and of code blocks
+var monospaceRegexp = regexp.MustCompile(`(?s)\{\{\{\}(.+?)\{\}\}\}|\{\{(.+?)\}\}|\{(?:code|noformat)(?::[^}]*)?\}(.+?)\{(?:code|noformat)\}`)
+
+// Queries returns the valid PromQL expressions quoted in the Jira markup of the text, like the
+// vulnerability checks the impact statement template asks for. Anything without a selector or a
+// function call, like a plain word, is not considered PromQL.
+func Queries(text string) []string {
+	var queries []string
+	for _, match := range monospaceRegexp.FindAllStringSubmatch(text, -1) {
+		query := strings.TrimSpace(match[1] + match[2] + match[3])
+		if !strings.ContainsAny(query, "{(") || len(Check(query)) > 0 {
+			continue
+		}
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+// Combine joins the expressions into a single matchingRules query that returns a result for the
+// clusters matching any of them and 0 for the other clusters
+func Combine(queries []string) string {
+	var parts []string
+	for _, query := range queries {
+		parts = append(parts, "  "+strings.ReplaceAll(strings.TrimSpace(query), "\n", "\n  "))
+	}
+	return fmt.Sprintf("(\n%s\n)\nor\n%s\n", strings.Join(parts, "\n  or\n"), fallback)
+}
+
+// defaultLibrary holds snippets over the metrics available in Telemetry. Cluster properties that are
+// not listed here can be added in promql-library.yaml in the config directory.
+const defaultLibrary = `snippets:
+- name: AWS
+  hints: ['\bAWS\b', '\bAmazon\b']
+  promql: group(cluster_infrastructure_provider{_id="",type="AWS"})
+- name: Azure
+  hints: ['\bAzure\b', '\bARO\b']
+  promql: group(cluster_infrastructure_provider{_id="",type="Azure"})
+- name: GCP
+  hints: ['\bGCP\b', '\bGoogle Cloud\b']
+  promql: group(cluster_infrastructure_provider{_id="",type="GCP"})
+- name: vSphere
+  hints: ['\bvSphere\b', '\bVMware\b']
+  promql: group(cluster_infrastructure_provider{_id="",type="VSphere"})
+- name: OpenStack
+  hints: ['\bOpenStack\b', '\bOSP\b']
+  promql: group(cluster_infrastructure_provider{_id="",type="OpenStack"})
+- name: bare metal
+  hints: ['\bbare[- ]?metal\b', '\bmetal3\b']
+  promql: group(cluster_infrastructure_provider{_id="",type="BareMetal"})
+- name: IBM Cloud
+  hints: ['\bIBM ?Cloud\b']
+  promql: group(cluster_infrastructure_provider{_id="",type="IBMCloud"})
+- name: Nutanix
+  hints: ['\bNutanix\b']
+  promql: group(cluster_infrastructure_provider{_id="",type="Nutanix"})
+- name: PowerVS
+  hints: ['\bPower ?VS\b']
+  promql: group(cluster_infrastructure_provider{_id="",type="PowerVS"})
+- name: platform-agnostic
+  hints: ['\bplatform[: =]+"?none\b', '\bagnostic\b']
+  promql: group(cluster_infrastructure_provider{_id="",type="None"})
+- name: proxy
+  hints: ['\bprox(y|ies)\b']
+  promql: group(cluster_proxy_enabled{_id="",type=~"https?"})
+- name: trusted CA bundle
+  hints: ['\btrusted ?CA\b', '\bCA bundle\b']
+  promql: group(cluster_proxy_enabled{_id="",type="trusted_ca"})
+- name: Hive-installed
+  hints: ['\bHive\b', '\bROSA\b', '\bOSD\b', '\bOpenShift Dedicated\b']
+  promql: group(cluster_installer{_id="",invoker="hive"})
+- name: TechPreviewNoUpgrade
+  hints: ['\bTechPreviewNoUpgrade\b', '\btech(nology)? ?preview\b']
+  promql: group(cluster_feature_set{_id="",name="TechPreviewNoUpgrade"})
+`
@@ -0,0 +1,90 @@
+package promql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDefaultLibrary(t *testing.T) {
+	library, err := parseLibrary([]byte(defaultLibrary), "default library")
+	if err != nil {
+		t.Fatalf("default library is invalid: %v", err)
+	}
+	for _, snippet := range library.Snippets {
+		if len(snippet.hints) == 0 {
+			t.Errorf("snippet %s has no hints", snippet.Name)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	library, err := parseLibrary([]byte(defaultLibrary), "default library")
+	if err != nil {
+		t.Fatalf("default library is invalid: %v", err)
+	}
+
+	statement := `h2. Which types of clusters?
+Only clusters on AWS and vSphere are affected,
+and only when an egress PROXY is configured.
+Amazonian names do not count.`
+
+	var got []string
+	for _, suggestion := range library.Suggest(statement) {
+		got = append(got, suggestion.Snippet.Name+": "+suggestion.Evidence)
+	}
+	expected := []string{
+		"AWS: Only clusters on AWS and vSphere are affected,",
+		"vSphere: Only clusters on AWS and vSphere are affected,",
+		"proxy: and only when an egress PROXY is configured.",
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected suggestions (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseLibraryRejectsInvalidSnippets(t *testing.T) {
+	for name, raw := range map[string]string{
+		"invalid PromQL": "snippets:\n- name: broken\n  hints: [broken]\n  promql: group(foo{\n",
+		"invalid hint":   "snippets:\n- name: broken\n  hints: ['(']\n  promql: group(foo)\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseLibrary([]byte(raw), "test"); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestQueries(t *testing.T) {
+	statement := `Check with {{oc adm upgrade}} or the following PromQL {{{}count(foo{bar="baz"}) > 0{}}}.
+Also {{up}} is not a check and {{count(}} is broken.
+{code:none}
+group(cluster_infrastructure_provider{type="AWS"})
+{code}`
+
+	expected := []string{`count(foo{bar="baz"}) > 0`, `group(cluster_infrastructure_provider{type="AWS"})`}
+	if diff := cmp.Diff(expected, Queries(statement)); diff != "" {
+		t.Errorf("unexpected queries (-want +got):\n%s", diff)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	combined := Combine([]string{`group(a{_id=""})`, "group(\n  b{_id=\"\"}\n)"})
+	expected := `(
+  group(a{_id=""})
+  or
+  group(
+    b{_id=""}
+  )
+)
+or
+0 * group(cluster_version{_id=""})
+`
+	if diff := cmp.Diff(expected, combined); diff != "" {
+		t.Errorf("unexpected combined query (-want +got):\n%s", diff)
+	}
+	if diagnostics := Check(combined); len(diagnostics) > 0 {
+		t.Errorf("combined query is invalid: %v", diagnostics)
+	}
+}
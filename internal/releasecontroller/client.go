@@ -0,0 +1,83 @@
+// Package releasecontroller implements a client for the OpenShift release controller, which builds
+// and accepts the release payloads
+package releasecontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/petr-muller/ota/internal/httputil"
+)
+
+// DefaultURL is the release controller building the amd64 OCP payloads
+const DefaultURL = "https://amd64.ocp.releases.ci.openshift.org"
+
+// PhaseAccepted is the phase of the payloads that passed the blocking jobs
+const PhaseAccepted = "Accepted"
+
+// Tag is a single release payload in a release stream
+type Tag struct {
+	Name     string `json:"name"`
+	Phase    string `json:"phase"`
+	PullSpec string `json:"pullSpec"`
+}
+
+// Stream is a release stream, e.g. 4-stable, with its payloads from the newest
+type Stream struct {
+	Name string `json:"name"`
+	Tags []Tag  `json:"tags"`
+}
+
+// Client queries the release controller API
+type Client struct {
+	URL string
+}
+
+// NewClient returns a client querying the release controller at the given URL
+func NewClient(releaseControllerURL string) *Client {
+	return &Client{URL: releaseControllerURL}
+}
+
+// Stream fetches the payloads of the given release stream
+func (c *Client) Stream(ctx context.Context, stream string) (*Stream, error) {
+	u, err := url.JoinPath(c.URL, "api", "v1", "releasestream", stream, "tags")
+	if err != nil {
+		return nil, fmt.Errorf("invalid release controller URL %s: %w", c.URL, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httputil.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query release controller: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release controller returned %s for stream %s", resp.Status, stream)
+	}
+
+	var s Stream
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return nil, fmt.Errorf("cannot decode release stream: %w", err)
+	}
+	return &s, nil
+}
+
+// Accepted returns the names of the accepted payloads in the stream
+func (s *Stream) Accepted() []string {
+	var accepted []string
+	for _, tag := range s.Tags {
+		if tag.Phase == PhaseAccepted {
+			accepted = append(accepted, tag.Name)
+		}
+	}
+	return accepted
+}
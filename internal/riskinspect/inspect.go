@@ -0,0 +1,143 @@
+// Package riskinspect follows the links from the impact statement card of a risk to the bugs behind
+// it, to help decide whether the risk should be extended to a new release or declared fixed
+package riskinspect
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/petr-muller/ota/internal/jirautil"
+)
+
+// clonersLinkType is the type of the links between a cloned bug (outward "clones") and its original
+const clonersLinkType = "Cloners"
+
+// Client is the subset of the Jira client the inspection needs
+type Client = jirautil.IssueGetter
+
+// Bug is a bug card reachable from the impact statement card
+type Bug struct {
+	Key           string
+	Summary       string
+	Status        string
+	TargetVersion string
+	// Direct is true when the impact statement card itself blocks on the bug
+	Direct bool
+	// Clones is the key of the bug this bug was cloned from, when the bug is a clone
+	Clones string
+
+	Issue *jira.Issue
+}
+
+// Inspection is the impact statement card of a risk with all bugs reachable from it through links
+// between bugs
+type Inspection struct {
+	ImpactStatement *jira.Issue
+	// Bugs are sorted by their keys
+	Bugs []Bug
+}
+
+// Summary returns the key and summary of the impact statement card
+func (i *Inspection) Summary() string {
+	return fmt.Sprintf("%s: %s", i.ImpactStatement.Key, i.ImpactStatement.Fields.Summary)
+}
+
+// Inspect obtains the impact statement card and follows its links (and the links of the linked bugs)
+// to all bug cards, as decided by isBug. The bugs newly found at each level of links are obtained
+// concurrently.
+func Inspect(ctx context.Context, client Client, isBug func(key string) bool, impactStatementCard string) (*Inspection, error) {
+	logrus.Infof("Obtaining (likely) impact statement card %s and process its linked bugs", impactStatementCard)
+	impactStatement, err := client.GetIssue(impactStatementCard)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := sets.New(impactStatementCard)
+	bugs := map[string]*jira.Issue{}
+	directBlocks := sets.New[string]()
+	if isBug(impactStatementCard) {
+		bugs[impactStatementCard] = impactStatement
+	}
+
+	// Follow the links level by level, obtaining all newly linked bugs of a level concurrently
+	level := []*jira.Issue{impactStatement}
+	for len(level) > 0 {
+		var linkedKeys []string
+		for _, card := range level {
+			for _, link := range card.Fields.IssueLinks {
+				for _, linked := range []struct {
+					issue    *jira.Issue
+					relation string
+				}{{link.OutwardIssue, link.Type.Outward}, {link.InwardIssue, link.Type.Inward}} {
+					if linked.issue == nil {
+						continue
+					}
+					if !isBug(linked.issue.Key) {
+						logrus.Tracef("%s: not following a non-bug link '%s %s'", card.Key, linked.relation, linked.issue.Key)
+						continue
+					}
+					if card.Key == impactStatement.Key && linked.relation == "blocks" {
+						directBlocks.Insert(linked.issue.Key)
+					}
+					if seen.Has(linked.issue.Key) {
+						continue
+					}
+					seen.Insert(linked.issue.Key)
+					linkedKeys = append(linkedKeys, linked.issue.Key)
+				}
+			}
+		}
+
+		if level, err = jirautil.DefaultFetcher.GetIssues(ctx, client, linkedKeys); err != nil {
+			return nil, err
+		}
+		for _, bug := range level {
+			logrus.Tracef("%s: Found a bug card", bug.Key)
+			bugs[bug.Key] = bug
+		}
+	}
+
+	logrus.Infof("Found %d bug cards", len(bugs))
+	inspection := &Inspection{ImpactStatement: impactStatement}
+	for key, issue := range bugs {
+		bug := Bug{Key: key, Summary: issue.Fields.Summary, Direct: directBlocks.Has(key), Issue: issue}
+		if issue.Fields.Status != nil {
+			bug.Status = issue.Fields.Status.Name
+		}
+		for _, link := range issue.Fields.IssueLinks {
+			if link.Type.Name == clonersLinkType && link.OutwardIssue != nil {
+				bug.Clones = link.OutwardIssue.Key
+			}
+		}
+		if items, err := jirautil.GetIssueTargetVersion(issue); err == nil && len(items) > 0 {
+			bug.TargetVersion = items[0].Name
+			if len(items) > 1 {
+				logrus.Warningf("%s: Found multiple target versions: %v", key, items)
+			}
+		}
+		inspection.Bugs = append(inspection.Bugs, bug)
+	}
+	sort.Slice(inspection.Bugs, func(i, j int) bool { return inspection.Bugs[i].Key < inspection.Bugs[j].Key })
+	return inspection, nil
+}
+
+// Print writes the bugs as a table; bugs the impact statement card directly blocks on are marked with x
+func (i *Inspection) Print(w io.Writer) {
+	tabw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = tabw.Write([]byte("BUG\tDIRECT\tTARGET\tSTATUS\tSUMMARY\n"))
+	for _, bug := range i.Bugs {
+		direct := ""
+		if bug.Direct {
+			direct = "x"
+		}
+		_, _ = tabw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\n", bug.Key, direct, bug.TargetVersion, bug.Status, bug.Summary)))
+	}
+	_ = tabw.Flush()
+}
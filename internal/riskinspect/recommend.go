@@ -0,0 +1,100 @@
+package riskinspect
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FixState is how far the fix of a bug progressed, as inferred from its status
+type FixState string
+
+const (
+	// FixStateUnfixed bugs are not fixed yet (up to POST)
+	FixStateUnfixed FixState = "unfixed"
+	// FixStatePending bugs have a merged fix that was not verified yet (MODIFIED, ON_QA)
+	FixStatePending FixState = "pending"
+	// FixStateFixed bugs have a verified or released fix
+	FixStateFixed FixState = "fixed"
+	// FixStateUnknown bugs are in a status that does not say whether they were fixed, like closed
+	// without a fix
+	FixStateUnknown FixState = "unknown"
+)
+
+const (
+	ActionExtend = "extend"
+	ActionFix    = "fix"
+)
+
+// fixedResolutions are the resolutions of closed bugs that were fixed
+var fixedResolutions = map[string]bool{"": true, "Done": true, "Done-Errata": true, "Errata": true, "Current Release": true}
+
+// FixState returns the fix state of the bug
+func (b Bug) FixState() FixState {
+	switch strings.ToUpper(b.Status) {
+	case "NEW", "ASSIGNED", "POST", "TO DO", "IN PROGRESS", "CODE REVIEW":
+		return FixStateUnfixed
+	case "MODIFIED", "ON_QA":
+		return FixStatePending
+	case "VERIFIED", "RELEASE PENDING":
+		return FixStateFixed
+	case "CLOSED", "DONE":
+		resolution := ""
+		if b.Issue != nil && b.Issue.Fields != nil && b.Issue.Fields.Resolution != nil {
+			resolution = b.Issue.Fields.Resolution.Name
+		}
+		if fixedResolutions[resolution] {
+			return FixStateFixed
+		}
+	}
+	return FixStateUnknown
+}
+
+// minorRegexp matches the minor version in target versions like 4.16.z, 4.16.0 or 4.16
+var minorRegexp = regexp.MustCompile(`^(\d+\.\d+)(\.|$)`)
+
+// Minor returns the minor version the bug targets (like 4.16), or an empty string when the bug has no
+// target version
+func (b Bug) Minor() string {
+	match := minorRegexp.FindStringSubmatch(b.TargetVersion)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// Recommend returns whether the risk should be extended to the new version or declared fixed in it,
+// together with the reason. Only the bugs targeting the minor of the new version are considered: the
+// risk is fixed when all of them are fixed. Bugs with a fix that was not verified yet do not count as
+// fixed, because whether their fix made it into the new version cannot be told from Jira alone.
+func (i *Inspection) Recommend(newVersion string) (string, string) {
+	minor := minorRegexp.FindStringSubmatch(newVersion)
+	if minor == nil {
+		return ActionExtend, fmt.Sprintf("%s is not a release version, cannot tell which bugs target it", newVersion)
+	}
+
+	var unfixed, pending, fixed []string
+	for _, bug := range i.Bugs {
+		if bug.Minor() != minor[1] {
+			continue
+		}
+		switch bug.FixState() {
+		case FixStateUnfixed:
+			unfixed = append(unfixed, bug.Key)
+		case FixStatePending:
+			pending = append(pending, bug.Key)
+		case FixStateFixed:
+			fixed = append(fixed, bug.Key)
+		}
+	}
+
+	switch {
+	case len(unfixed) > 0:
+		return ActionExtend, fmt.Sprintf("%s targeting %s not fixed yet", strings.Join(unfixed, ", "), minor[1])
+	case len(pending) > 0:
+		return ActionExtend, fmt.Sprintf("fix of %s targeting %s not verified yet", strings.Join(pending, ", "), minor[1])
+	case len(fixed) > 0:
+		return ActionFix, fmt.Sprintf("%s targeting %s fixed", strings.Join(fixed, ", "), minor[1])
+	}
+	return ActionExtend, fmt.Sprintf("no bug with a known fix state targets %s", minor[1])
+}
@@ -0,0 +1,82 @@
+package riskinspect
+
+import (
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+func TestFixState(t *testing.T) {
+	closed := func(resolution string) *jira.Issue {
+		return &jira.Issue{Fields: &jira.IssueFields{Resolution: &jira.Resolution{Name: resolution}}}
+	}
+	testCases := []struct {
+		name string
+		bug  Bug
+
+		expected FixState
+	}{
+		{name: "new", bug: Bug{Status: "New"}, expected: FixStateUnfixed},
+		{name: "post", bug: Bug{Status: "POST"}, expected: FixStateUnfixed},
+		{name: "on qa", bug: Bug{Status: "ON_QA"}, expected: FixStatePending},
+		{name: "verified", bug: Bug{Status: "Verified"}, expected: FixStateFixed},
+		{name: "closed as done", bug: Bug{Status: "Closed", Issue: closed("Done-Errata")}, expected: FixStateFixed},
+		{name: "closed as duplicate", bug: Bug{Status: "Closed", Issue: closed("Duplicate")}, expected: FixStateUnknown},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if state := tc.bug.FixState(); state != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, state)
+			}
+		})
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	testCases := []struct {
+		name string
+		bugs []Bug
+
+		expected string
+	}{
+		{
+			name: "unfixed bug in the minor",
+			bugs: []Bug{
+				{Key: "OCPBUGS-1", Status: "Verified", TargetVersion: "4.17.0"},
+				{Key: "OCPBUGS-2", Status: "ASSIGNED", TargetVersion: "4.16.z"},
+			},
+			expected: ActionExtend,
+		},
+		{
+			name: "fix not verified in the minor",
+			bugs: []Bug{
+				{Key: "OCPBUGS-2", Status: "ON_QA", TargetVersion: "4.16.z"},
+				{Key: "OCPBUGS-3", Status: "Verified", TargetVersion: "4.16.z"},
+			},
+			expected: ActionExtend,
+		},
+		{
+			name: "all bugs in the minor fixed",
+			bugs: []Bug{
+				{Key: "OCPBUGS-1", Status: "New", TargetVersion: "4.15.z"},
+				{Key: "OCPBUGS-2", Status: "Verified", TargetVersion: "4.16.z"},
+			},
+			expected: ActionFix,
+		},
+		{
+			name: "no bug in the minor",
+			bugs: []Bug{
+				{Key: "OCPBUGS-1", Status: "Verified", TargetVersion: "4.17.0"},
+			},
+			expected: ActionExtend,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inspection := Inspection{Bugs: tc.bugs}
+			if action, reason := inspection.Recommend("4.16.5"); action != tc.expected {
+				t.Errorf("expected %s, got %s (%s)", tc.expected, action, reason)
+			}
+		})
+	}
+}
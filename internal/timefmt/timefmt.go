@@ -0,0 +1,86 @@
+// Package timefmt formats timestamps and durations consistently across the tools' tabular output
+package timefmt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/petr-muller/ota/internal/config"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+
+	absoluteLayout = "2006-01-02 15:04 MST"
+)
+
+// Duration formats the duration with the two most significant units, e.g. "3d 2h", "2w" or "45m"
+func Duration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+
+	units := []struct {
+		size   time.Duration
+		suffix string
+	}{{week, "w"}, {day, "d"}, {time.Hour, "h"}, {time.Minute, "m"}}
+
+	for i, unit := range units {
+		if d < unit.size {
+			continue
+		}
+		major := d / unit.size
+		if i == len(units)-1 {
+			return fmt.Sprintf("%d%s", major, unit.suffix)
+		}
+		minorUnit := units[i+1]
+		if minor := (d % unit.size) / minorUnit.size; minor > 0 {
+			return fmt.Sprintf("%d%s %d%s", major, unit.suffix, minor, minorUnit.suffix)
+		}
+		return fmt.Sprintf("%d%s", major, unit.suffix)
+	}
+	return "<1m"
+}
+
+// Formatter formats points in time either as humanized ages or as absolute timestamps. The zero
+// value formats humanized ages.
+type Formatter struct {
+	absolute bool
+	location *time.Location
+}
+
+// NewFormatter returns a formatter following the display preferences
+func NewFormatter(display config.Display) (Formatter, error) {
+	f := Formatter{absolute: display.AbsoluteTimestamps, location: time.Local}
+	if display.Timezone != "" {
+		location, err := time.LoadLocation(display.Timezone)
+		if err != nil {
+			return Formatter{}, fmt.Errorf("invalid timezone %q: %w", display.Timezone, err)
+		}
+		f.location = location
+	}
+	return f, nil
+}
+
+// Load returns a formatter following the display preferences in the OTA config directory
+func Load() (Formatter, error) {
+	display, err := config.LoadDisplay()
+	if err != nil {
+		return Formatter{}, err
+	}
+	return NewFormatter(display)
+}
+
+// Since formats the time t relative to now: the age of t, or t itself when absolute timestamps are
+// preferred
+func (f Formatter) Since(t, now time.Time) string {
+	if f.absolute {
+		location := f.location
+		if location == nil {
+			location = time.Local
+		}
+		return t.In(location).Format(absoluteLayout)
+	}
+	return Duration(now.Sub(t))
+}
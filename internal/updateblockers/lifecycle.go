@@ -0,0 +1,63 @@
+package updateblockers
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Stage is the position of a bug in the upgrade blocker lifecycle, derived from its labels:
+//
+//	candidate → impact statement requested → impact statement proposed → known issue announced
+//
+// Labels can be cleared from any stage, which returns the bug to StageNone.
+type Stage string
+
+const (
+	StageNone                     Stage = "none"
+	StageCandidate                Stage = "candidate"
+	StageImpactStatementRequested Stage = "impact-statement-requested"
+	StageImpactStatementProposed  Stage = "impact-statement-proposed"
+	StageKnownIssueAnnounced      Stage = "known-issue-announced"
+)
+
+// allowedTransitions lists the stages each stage can move to, besides clearing all labels
+var allowedTransitions = map[Stage]sets.Set[Stage]{
+	StageNone:                     sets.New(StageCandidate),
+	StageCandidate:                sets.New(StageImpactStatementRequested),
+	StageImpactStatementRequested: sets.New(StageImpactStatementProposed, StageKnownIssueAnnounced),
+	StageImpactStatementProposed:  sets.New(StageKnownIssueAnnounced),
+	StageKnownIssueAnnounced:      sets.New[Stage](),
+}
+
+// StageOf returns the lifecycle stage of a bug with the given labels. When the labels of several
+// stages are present, the most advanced stage wins.
+func StageOf(labels []string) Stage {
+	present := sets.New(labels...)
+	switch {
+	case present.Has(LabelKnownIssueAnnounced):
+		return StageKnownIssueAnnounced
+	case present.Has(LabelImpactStatementProposed):
+		return StageImpactStatementProposed
+	case present.Has(LabelImpactStatementRequested):
+		return StageImpactStatementRequested
+	case present.Has(LabelBlocker):
+		return StageCandidate
+	}
+	return StageNone
+}
+
+// ValidateTransition returns an error when a bug in the from stage should not be moved to the to stage
+func ValidateTransition(from, to Stage) error {
+	if to == StageNone {
+		return nil
+	}
+	next, ok := allowedTransitions[from]
+	if !ok {
+		return fmt.Errorf("unknown lifecycle stage %q", from)
+	}
+	if !next.Has(to) {
+		return fmt.Errorf("cannot move a bug from the %s stage to the %s stage", from, to)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package updateblockers
+
+import "testing"
+
+func TestStageOf(t *testing.T) {
+	testCases := []struct {
+		labels   []string
+		expected Stage
+	}{
+		{labels: nil, expected: StageNone},
+		{labels: []string{"Other"}, expected: StageNone},
+		{labels: []string{LabelBlocker}, expected: StageCandidate},
+		{labels: []string{LabelBlocker, LabelImpactStatementRequested}, expected: StageImpactStatementRequested},
+		{labels: []string{LabelImpactStatementRequested, LabelImpactStatementProposed}, expected: StageImpactStatementProposed},
+		{labels: []string{LabelBlocker, LabelKnownIssueAnnounced, LabelImpactStatementProposed}, expected: StageKnownIssueAnnounced},
+	}
+	for _, tc := range testCases {
+		if stage := StageOf(tc.labels); stage != tc.expected {
+			t.Errorf("StageOf(%v): expected %s, got %s", tc.labels, tc.expected, stage)
+		}
+	}
+}
+
+func TestValidateTransition(t *testing.T) {
+	testCases := []struct {
+		from, to      Stage
+		expectedError bool
+	}{
+		{from: StageCandidate, to: StageImpactStatementRequested},
+		{from: StageImpactStatementRequested, to: StageImpactStatementProposed},
+		{from: StageImpactStatementRequested, to: StageKnownIssueAnnounced},
+		{from: StageImpactStatementProposed, to: StageKnownIssueAnnounced},
+		{from: StageKnownIssueAnnounced, to: StageNone},
+		{from: StageNone, to: StageImpactStatementProposed, expectedError: true},
+		{from: StageCandidate, to: StageImpactStatementProposed, expectedError: true},
+		{from: StageImpactStatementProposed, to: StageImpactStatementRequested, expectedError: true},
+		{from: StageKnownIssueAnnounced, to: StageImpactStatementProposed, expectedError: true},
+		{from: Stage("bogus"), to: StageCandidate, expectedError: true},
+	}
+	for _, tc := range testCases {
+		if err := ValidateTransition(tc.from, tc.to); (err != nil) != tc.expectedError {
+			t.Errorf("ValidateTransition(%s, %s): expected error: %t, got %v", tc.from, tc.to, tc.expectedError, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package updateblockers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"gopkg.in/yaml.v3"
+
+	"github.com/petr-muller/ota/internal/config"
+)
+
+// State is a logical workflow state of an impact statement request card. Projects use different
+// workflows, so each state may correspond to differently named statuses in different projects.
+type State string
+
+const (
+	StateInProgress State = "in-progress"
+	StateReview     State = "review"
+	StateClosed     State = "closed"
+)
+
+const (
+	transitionsFileName = "transitions.yaml"
+)
+
+// defaultStatuses lists the status names that are tried, in order, when resolving a logical state
+var defaultStatuses = map[State][]string{
+	StateInProgress: {"In Progress", "ASSIGNED"},
+	StateReview:     {"Code Review", "Review"},
+	StateClosed:     {"Closed", "Done"},
+}
+
+// TransitionClient is the subset of the Jira client needed to transition issues
+type TransitionClient interface {
+	GetTransitions(issueID string) ([]jira.Transition, error)
+	DoTransition(issueID, transitionID string) error
+}
+
+// Transitioner moves issues to a logical State, resolving the state to the status used by the
+// workflow of the issue's project
+type Transitioner struct {
+	// Overrides maps a project key to the status name that should be used for a logical state in that project
+	Overrides map[string]map[State]string `yaml:"projects"`
+}
+
+// LoadTransitioner loads the per-project status overrides from the ota config directory. A missing
+// file is not an error and results in a Transitioner that only uses the default status names.
+func LoadTransitioner() (*Transitioner, error) {
+	raw, path, err := config.ReadConfigFile(transitionsFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Transitioner{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read transitions config %s: %w", path, err)
+	}
+
+	var t Transitioner
+	if err := yaml.Unmarshal(raw, &t); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal transitions config %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// candidates returns the status names to try for the given project and state
+func (t *Transitioner) candidates(project string, state State) []string {
+	if status, ok := t.Overrides[project][state]; ok {
+		return []string{status}
+	}
+	return defaultStatuses[state]
+}
+
+// Transition moves the issue to the status that corresponds to the given logical state in its
+// project, and returns the name of that status
+func (t *Transitioner) Transition(client TransitionClient, issue *jira.Issue, state State) (string, error) {
+	project := strings.SplitN(issue.Key, "-", 2)[0]
+	transitions, err := client.GetTransitions(issue.Key)
+	if err != nil {
+		return "", fmt.Errorf("cannot get transitions for %s: %w", issue.Key, err)
+	}
+
+	for _, status := range t.candidates(project, state) {
+		for _, transition := range transitions {
+			if strings.EqualFold(transition.To.Name, status) {
+				if err := client.DoTransition(issue.Key, transition.ID); err != nil {
+					return "", fmt.Errorf("cannot transition %s to %s: %w", issue.Key, transition.To.Name, err)
+				}
+				return transition.To.Name, nil
+			}
+		}
+	}
+
+	var available []string
+	for _, transition := range transitions {
+		available = append(available, transition.To.Name)
+	}
+	return "", fmt.Errorf("%s: no transition to a %s status (tried %s), available: %s", issue.Key, state,
+		strings.Join(t.candidates(project, state), ","), strings.Join(available, ","))
+}